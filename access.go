@@ -0,0 +1,64 @@
+package apibin
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+)
+
+var accessKey contextKey = "apibin/access"
+
+// AccessModel describes the IP allow/deny list decision for a request.
+type AccessModel struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason" enum:"allow-list,not-denied,deny-list,not-allowed" doc:"Why the request was allowed or denied"`
+	CIDR    string `json:"cidr,omitempty" doc:"The matching allow or deny list entry, if any"`
+}
+
+// checkAccess decides whether an IP may make requests. The deny list takes
+// precedence, and when an allow list is set the IP must be on it.
+func checkAccess(allow, deny []*net.IPNet, ip string) *AccessModel {
+	if n := matchIP(deny, ip); n != nil {
+		return &AccessModel{Reason: "deny-list", CIDR: n.String()}
+	}
+	if len(allow) == 0 {
+		return &AccessModel{Allowed: true, Reason: "not-denied"}
+	}
+	if n := matchIP(allow, ip); n != nil {
+		return &AccessModel{Allowed: true, Reason: "allow-list", CIDR: n.String()}
+	}
+	return &AccessModel{Reason: "not-allowed"}
+}
+
+// GetAccess returns the access decision for a request context, or nil if no
+// allow or deny lists are configured.
+func GetAccess(ctx context.Context) *AccessModel {
+	access, _ := ctx.Value(accessKey).(*AccessModel)
+	return access
+}
+
+// IPAccess rejects requests from client IPs which are on the deny list or
+// missing from the allow list with a 403 error. It must run after
+// `ForwardedHeaders` so that trusted proxies are taken into account. The API
+// is passed by reference since the middleware is created before it.
+func IPAccess(api *huma.API, allow, deny []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := GetClientInfo(r.Context()).IP
+			access := checkAccess(allow, deny, ip)
+			if !access.Allowed {
+				ctx := humachi.NewContext(nil, r, w)
+				msg := "client IP " + ip + " is not on the allow list"
+				if access.Reason == "deny-list" {
+					msg = "client IP " + ip + " is on the deny list"
+				}
+				huma.WriteErr(*api, ctx, http.StatusForbidden, msg)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accessKey, access)))
+		})
+	}
+}
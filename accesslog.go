@@ -0,0 +1,89 @@
+package apibin
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	accessLogCommon   = "common"
+	accessLogCombined = "combined"
+)
+
+// openAccessLog opens the access log destination, where `-` means stdout.
+// Files are appended to so existing log tooling can keep tailing them.
+func openAccessLog(path string) (io.Writer, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// dashIfEmpty returns `-`, which is used for missing values in the Apache log
+// formats, if the value is empty.
+func dashIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// AccessLog writes a line for each request in the Apache common or combined
+// log format, which is understood by most existing log tooling.
+func AccessLog(w io.Writer, format string) func(http.Handler) http.Handler {
+	// Loggers serialize writes, so lines from concurrent requests never
+	// interleave.
+	logger := log.New(w, "", 0)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				host := GetClientInfo(r.Context()).IP
+				if host == "" {
+					host = stripPort(r.RemoteAddr)
+				}
+
+				user, _, _ := r.BasicAuth()
+
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				size := "-"
+				if ww.BytesWritten() > 0 {
+					size = strconv.Itoa(ww.BytesWritten())
+				}
+
+				line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+					host,
+					dashIfEmpty(user),
+					start.Format("02/Jan/2006:15:04:05 -0700"),
+					r.Method,
+					r.URL.RequestURI(),
+					r.Proto,
+					status,
+					size,
+				)
+
+				if format == accessLogCombined {
+					line += fmt.Sprintf(" %q %q", dashIfEmpty(r.Referer()), dashIfEmpty(r.UserAgent()))
+				}
+
+				logger.Println(line)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
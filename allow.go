@@ -0,0 +1,171 @@
+package apibin
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/negotiation"
+	"github.com/go-chi/chi/v5"
+)
+
+// routeMethods are checked in order when building an `Allow` header.
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// routePath returns the path used to route a request, which is relative to
+// any mounted sub-router.
+func routePath(rctx *chi.Context, r *http.Request) string {
+	if rctx.RoutePath != "" {
+		return rctx.RoutePath
+	}
+	if r.URL.RawPath != "" {
+		return r.URL.RawPath
+	}
+	return r.URL.Path
+}
+
+// allowedMethods returns the methods routed for a path, along with the
+// matching route pattern. HEAD is allowed wherever GET is, see
+// `HeadRequests`. OPTIONS is included if any other method is allowed.
+func allowedMethods(routes chi.Routes, path string) ([]string, string) {
+	methods := []string{}
+	pattern := ""
+	for _, method := range routeMethods {
+		tctx := chi.NewRouteContext()
+		matched := routes.Match(tctx, method, path)
+		if method == http.MethodHead && len(methods) > 0 && methods[0] == http.MethodGet {
+			matched = true
+		}
+		if matched {
+			methods = append(methods, method)
+			if pattern == "" {
+				pattern = tctx.RoutePattern()
+			}
+		}
+	}
+	if len(methods) > 0 {
+		methods = append(methods, http.MethodOptions)
+	}
+	return methods, pattern
+}
+
+// findOperation returns the documented operation for a route pattern and
+// method from any of the APIs, or nil if there is none.
+func findOperation(apis []huma.API, pattern, method string) *huma.Operation {
+	for _, api := range apis {
+		item := api.OpenAPI().Paths[pattern]
+		if item == nil {
+			continue
+		}
+		var op *huma.Operation
+		switch method {
+		case http.MethodGet:
+			op = item.Get
+		case http.MethodHead:
+			op = item.Head
+		case http.MethodPost:
+			op = item.Post
+		case http.MethodPut:
+			op = item.Put
+		case http.MethodPatch:
+			op = item.Patch
+		case http.MethodDelete:
+			op = item.Delete
+		}
+		if op != nil {
+			return op
+		}
+	}
+	return nil
+}
+
+// requestContentTypes returns the request body content types accepted by an
+// operation.
+func requestContentTypes(op *huma.Operation) []string {
+	types := []string{}
+	if op != nil && op.RequestBody != nil {
+		for ct := range op.RequestBody.Content {
+			types = append(types, ct)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// AllowedMethod describes a method available on a resource.
+type AllowedMethod struct {
+	Method      string   `json:"method"`
+	OperationID string   `json:"operation_id,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Accept      []string `json:"accept,omitempty" doc:"Accepted request body content types"`
+}
+
+// AllowModel describes the methods available on a resource.
+type AllowModel struct {
+	Path    string          `json:"path" doc:"Route pattern matching the request path"`
+	Methods []AllowedMethod `json:"methods"`
+}
+
+// AllowedMethods answers OPTIONS requests for any route without its own
+// OPTIONS handler with an `Allow` header listing the routed methods, and
+// `Accept-Patch` where PATCH is supported. Clients which explicitly accept a
+// structured format also get a description of each method, otherwise the
+// response has no body. The APIs are passed by reference since the middleware
+// is created before them.
+func AllowedMethods(apis *[]huma.API) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.RouteContext(r.Context())
+			if r.Method != http.MethodOptions || rctx == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			path := routePath(rctx, r)
+			if rctx.Routes.Match(chi.NewRouteContext(), http.MethodOptions, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			methods, pattern := allowedMethods(rctx.Routes, path)
+			if len(methods) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			model := AllowModel{Path: pattern, Methods: []AllowedMethod{}}
+			for _, method := range methods {
+				m := AllowedMethod{Method: method}
+				if op := findOperation(*apis, pattern, method); op != nil {
+					m.OperationID = op.OperationID
+					m.Description = op.Description
+					m.Accept = requestContentTypes(op)
+				}
+				if method == http.MethodPatch && len(m.Accept) > 0 {
+					w.Header().Set("Accept-Patch", strings.Join(m.Accept, ", "))
+				}
+				model.Methods = append(model.Methods, m)
+			}
+
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			w.Header().Add("Vary", "Accept")
+
+			// Wildcards are ignored so only clients asking for a description get
+			// one, which keeps plain capability checks body-free.
+			ct := negotiation.SelectQValueFast(r.Header.Get("Accept"), []string{"application/json", "application/cbor", "application/yaml"})
+			if ct == "" || len(*apis) == 0 {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", ct)
+			w.WriteHeader(http.StatusOK)
+			(*apis)[0].Marshal(w, ct, model)
+		})
+	}
+}
@@ -0,0 +1,989 @@
+package apibin
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/danielgtaylor/huma/v2/autopatch"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"gopkg.in/yaml.v2"
+)
+
+var docs = strings.Replace(`[![HUMA Powered](https://img.shields.io/badge/Powered%20By-Huma-ff5f87)](https://huma.rocks/) [![Works With Restish](https://img.shields.io/badge/Works%20With-Restish-ff5f87)](https://rest.sh/) [![GitHub](https://img.shields.io/github/license/danielgtaylor/apibin)](https://github.com/danielgtaylor/apibin)
+
+Provides a simple, modern, example API that offers these features:
+
+- HTTP, HTTPS (TLS), and [HTTP/2](https://http2.github.io/)
+- [OpenAPI 3](https://www.openapis.org/) & [JSON Schema](https://json-schema.org/)
+- Client-driven content negotiation
+	- ^gzip^ & ^br^ content encoding for large responses
+		- Configurable levels and minimum size, overridable per request via ^X-Apibin-Compress-Level^
+	- ^JSON^, ^YAML^, & ^CBOR^ formats
+	- Server-side filtering via ^?filter=^ [shorthand queries](https://github.com/danielgtaylor/shorthand#querying)
+	- ^406^ & ^415^ negotiation failures with advisory headers at ^/negotiation/strict^
+	- Step-by-step negotiation diagnostics for ^Accept^ headers at ^/negotiation/debug^
+- Conditional requests via ^ETag^ or ^LastModified^
+	- Weak or strong validators via ^?etag=weak|strong^
+	- ETags for the echo at ^/^ & the resume at ^/example^ hashed with ^xxh3^, ^sha256^, or ^md5^ via ^--etag-algorithm^ or ^?etag_algorithm=^, indicated in ^X-Apibin-ETag-Algorithm^. The hash is of the resource rather than the bytes sent, so it doesn't change with the format or compression and won't match e.g. the MD5 of the body
+- A browser-friendly landing page at ^/^ with links to each endpoint group
+- Echo back request info to help debugging
+	- Also available via [gRPC-Web](https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md) & [Connect](https://connectrpc.com/) at ^/apibin.v1.EchoService/Echo^
+	- Request bodies compressed with ^gzip^, ^br^, ^deflate^, or ^zstd^ are decompressed for all operations, up to the maximum body size
+	- The request line & headers as ^message/http^ via ^/trace-echo^, like ^TRACE^ with credentials & hop-by-hop headers redacted
+	- Mock responses rendered from a template via ^/template-echo^ or ^/status/{code}?template=...^, with placeholders like ^{{method}}^, ^{{header "X-Foo"}}^, ^{{now}}^, & ^{{rand 100}}^
+- Digests of posted bodies via ^POST /hash^ using ^sha256^, ^sha512^, ^md5^, or ^xxh3^
+	- HMAC signing & verification via ^POST /hmac^ & ^POST /hmac/verify^
+- JWT decoding with claim checks & optional JWKS verification via ^POST /jwt/decode^
+- CSRF double-submit cookie protection demo at ^/csrf/token^ & ^/csrf/submit^
+- Cookie-based login sessions with configurable cookie attributes at ^/session/...^
+- Cookies with any combination of attributes via ^/cookies/set-advanced^, echoed back at ^/cookies^
+- A mock OAuth 2.0 server with OpenID Connect discovery at ^/.well-known/openid-configuration^
+	- Published signing keys at ^/.well-known/jwks.json^ with optional key rotation via ^--jwks-rotate-interval^
+- Security header presets with per-header overrides at ^/security-headers^
+- A Content Security Policy test page with a violation report collector at ^/csp/page^
+- IP allow & deny lists for restricted instances via ^--allow-cidr^ & ^--deny-cidr^
+- Endpoint groups (the lowercase tags, e.g. ^books^ or ^images^) can be mounted selectively via ^--enable^ & ^--disable^, with the OpenAPI describing only what is mounted. The ^authors^ & ^webhooks^ groups are built on the books, and ^/stats^ includes the number stored, so disable them too to hide all book data
+- A read-only mode for public instances via ^--read-only^, which rejects any method other than ^GET^, ^HEAD^, & ^OPTIONS^ with a ^403^
+- Optional API key auth via ^--api-keys^ with daily per-key quotas, ^X-RateLimit-*^ headers, ^429^ responses once exceeded, and current usage at ^/quota^
+- Go profiling endpoints at ^/debug/pprof/^ and open connections & in-flight requests at ^/debug/connections^ via ^--debug^, optionally protected by ^--debug-token^
+- Traffic recording to an [HTTP Archive](http://www.softwareishard.com/blog/har-12-spec/) via ^--record har:path^, with bodies capped by ^--record-body-limit^ and credentials redacted
+- Basic auth which hides failures behind a ^404^ at ^/hidden-basic-auth/{user}/{pass}^
+- Redirect chains with selectable ^301^, ^302^, ^303^, ^307^, or ^308^ status codes at ^/redirect/{n}^
+	- Path-relative & fully-qualified ^Location^ variants at ^/relative-redirect/{n}^ & ^/absolute-redirect/{n}^
+	- Redirect loops for testing loop detection at ^/redirect/loop^
+	- Cross-origin & downgrade redirects with a credential leak check at ^/redirect/cross^
+- Cached responses to test proxy & client-side caching
+	- Any valid ^Cache-Control^ directives via ^/cache/control?directives=...^
+	- HTTP/1.0 style ^Expires^ headers, optionally with a skewed ^Date^, via ^?expires=^ & ^?date-skew=^
+	- A server clock which appears off for any request via ^?clock-skew=-300s^ or ^--clock-skew^, shifting ^Date^ & freshness timestamps
+	- A simulated CDN with ^Age^, ^Cache-Status^, & ^X-Cache^ hits & misses per key at ^/cache/cdn^
+	- Unconditional ^304 Not Modified^ responses at ^/cache/not-modified^
+- Example structured data
+	- Shows off ^object^, ^array^, ^string^, ^date^, ^binary^, ^integer^, ^number^, ^boolean^, etc.
+	- A [JSON Resume](https://jsonresume.org/) with nested objects at ^/example^, supporting conditional requests and validated conditional writes via ^PUT^ to a per-client ^X-Apibin-Sandbox^
+	- The same bytes as base64, base64url, base32, & hex with matching ^contentEncoding^ & ^format^ at ^/types/binary^
+	- Dictionaries of integers & objects plus a free-form ^additionalProperties: true^ object at ^/types/maps^, with validation via ^PUT^
+	- Large deterministic arrays of up to a million small objects as JSON, NDJSON, or CBOR at ^/types/array?count=^
+- A sample CRUD API for books & reviews with simulated server-side updates
+	- Related authors at ^/authors^, linked to their books
+	- Live change feed via server-sent events at ^/books/events^, with optional ^heartbeat^ comments, ^retry^ field, & server disconnects via ^drop_after^
+	- Signed webhook deliveries with retries via ^/books/webhooks^
+	- Bulk JSON & CSV export/import with dry runs via ^/books/export^ & ^/books/import^
+	- Batch get of multiple books with a list of missing IDs via ^POST /books/batch-get^
+	- Spreadsheet-style reports with ^Content-Disposition^ downloads at ^/reports/books.csv^ & ^/reports/books.xlsx^
+	- Versioned under ^/v1^ & ^/v2^ with breaking changes and separate OpenAPI documents
+- WebSocket chat rooms at ^/ws/rooms/{room}^ with fan-out to every client, periodic server announcements, and ^POST /ws/rooms/{room}/messages^ to send from REST
+- Image responses ^JPEG^, ^WEBP^, ^GIF^, ^PNG^ & ^HEIC^
+	- Partial downloads via ^Range^ & ^If-Range^ requests, plus conditional requests using strong ETags & ^Last-Modified^, including seeded random images
+	- Seeded random images of any size at ^/images/random^
+	- Paginated listing at ^/images^ with ^?limit=^, ^first^/^prev^/^next^ links & ^X-Total-Count^
+	- QR codes as ^PNG^ or ^SVG^ at ^/qr^
+- Generators for seedable fake people, addresses, companies & lorem ipsum text, plus random tokens, at ^/generate/...^
+	- Streaming JSON arrays of up to a million records with periodic flushes at ^/generate/{kind}/stream^
+- Random binary responses using chunked or fixed-length transfer framing
+	- Predictable ^/range/{n}^ content with single & ^multipart/byteranges^ responses for ^Range^ requests, also supported by ^/images/{type}^
+- Deliberate lock contention via ^POST /concurrency/{ms}^ with wait statistics at ^/concurrency^
+- Client cancellation checks at ^/cancel?after=10s^, logging and counting at ^/cancel/stats^ whether the client disconnected before the work completed
+- Gateway timeouts at ^/timeout?after=5s&status=504^, either before the headers with a problem body or after the headers by dropping the connection via ^?stage=body^
+- Flaky responses for testing retries at ^/flaky?failures=3&status=503&key=abc^, failing the first requests for each key before succeeding
+- Scripted outages for testing circuit breakers at ^/breaker/{name}^, toggled or scheduled through ^healthy^, ^degraded^, ^down^, & ^recovering^ via ^PUT /breaker/{name}/state^
+- Any status code at ^/status/{code}^, with ^451 Unavailable For Legal Reasons^ including a ^blocked-by^ link & problem body as in [RFC 7725](https://www.rfc-editor.org/rfc/rfc7725)
+- Runtime statistics at ^/stats^ including goroutines, memory, garbage collection, stored books, and request counts per route
+- [RFC7807](https://datatracker.ietf.org/doc/html/rfc7807) structured errors
+- Legacy SOAP 1.1 book lookup at ^/soap^ with a WSDL at ^/soap?wsdl^
+- Static assets at ^/static/^ with strong ETags, conditional requests, and byte ranges
+	- Precompressed ^gzip^ & ^br^ variants built at startup, as well as for ^/example^, ^/docs^, & ^/books/export^ which are compressed once per distinct body
+- ^HEAD^ for every ^GET^ operation with identical headers, including ^Content-Length^
+- ^OPTIONS^ on every resource with accurate ^Allow^ & ^Accept-Patch^ headers, plus a description of each method for clients accepting JSON, CBOR, or YAML
+
+This project is open source: [https://github.com/danielgtaylor/apibin](https://github.com/danielgtaylor/apibin)
+
+You can run it localy via Docker:
+
+^^^sh
+# Start the server
+$ docker run -p 8888:8888 ghcr.io/danielgtaylor/apibin:latest
+
+# Make a request
+$ restish :8888/types
+^^^
+`, "^", "`", -1)
+
+type CachedModel struct {
+	Generated time.Time `json:"generated" doc:"Time when this response was generated"`
+	Until     time.Time `json:"until" doc:"When the cache will be invalidated"`
+}
+
+type ImageItem struct {
+	Name   string `json:"name"`
+	Format string `json:"format" enum:"jpeg,webp,gif,png,heic"`
+	Self   string `json:"self" format:"uri-reference"`
+}
+
+type SubObject struct {
+	Binary     []byte    `json:"binary"`
+	BinaryLong []byte    `json:"binary_long"`
+	Date       time.Time `json:"date"`
+	DateTime   time.Time `json:"date_time"`
+	URL        string    `json:"url" format:"uri"`
+}
+
+type TypesModel struct {
+	Nullable *struct{} `json:"nullable"`
+	Boolean  bool      `json:"boolean"`
+	Integer  int64     `json:"integer"`
+	Number   float64   `json:"number"`
+	String   string    `json:"string"`
+	Tags     []string  `json:"tags"`
+	Object   SubObject `json:"object"`
+}
+
+type TypesResponse struct {
+	Body TypesModel
+}
+
+// BinaryEncodingsModel represents the same bytes using each common binary to
+// text encoding, to compare how clients & generated code handle them.
+type BinaryEncodingsModel struct {
+	Length    int    `json:"length" doc:"Number of bytes encoded by each field"`
+	Bytes     []byte `json:"bytes" doc:"Default encoding of binary data, the same as base64"`
+	Base64    string `json:"base64" format:"byte" encoding:"base64" doc:"Standard base64 with padding, RFC 4648 section 4"`
+	Base64URL string `json:"base64url" format:"base64url" encoding:"base64url" pattern:"^[A-Za-z0-9_-]*$" doc:"URL and filename safe base64 without padding, RFC 4648 section 5, as used by JWTs"`
+	Base32    string `json:"base32" encoding:"base32" pattern:"^[A-Z2-7]*=*$" doc:"Base32 with padding, RFC 4648 section 6"`
+	Hex       string `json:"hex" encoding:"base16" pattern:"^[0-9a-f]*$" doc:"Lowercase hexadecimal, RFC 4648 section 8"`
+}
+
+type BinaryEncodingsResponse struct {
+	Body BinaryEncodingsModel
+}
+
+// MapItem is a value in a map of objects.
+type MapItem struct {
+	Name  string `json:"name" minLength:"1"`
+	Count int    `json:"count" minimum:"0"`
+}
+
+// FreeForm is an object allowing any properties, described in the schema with
+// `additionalProperties: true` rather than an empty value schema.
+type FreeForm map[string]any
+
+// Schema implements `huma.SchemaProvider`.
+func (FreeForm) Schema(r huma.Registry) *huma.Schema {
+	return &huma.Schema{Type: huma.TypeObject, AdditionalProperties: true}
+}
+
+// MapsModel shows off dictionary types, which use `additionalProperties` to
+// describe their values instead of listing properties.
+type MapsModel struct {
+	Counts  map[string]int     `json:"counts" doc:"Integers keyed by any string"`
+	Objects map[string]MapItem `json:"objects" doc:"Objects keyed by any string"`
+	Extra   FreeForm           `json:"extra" doc:"Free-form object allowing any properties & values"`
+}
+
+type MapsResponse struct {
+	Body MapsModel
+}
+
+// binaryExample includes bytes which encode to the characters that differ
+// between base64 variants, and a length which needs padding.
+var binaryExample = []byte{222, 173, 190, 239, 251, 255, 191, 0, 62, 63, 1}
+
+// linkTargetEscaper percent-encodes the characters which would end the URI in
+// a `Link` header and let the rest of the value add parameters or links.
+var linkTargetEscaper = strings.NewReplacer("<", "%3C", ">", "%3E", ",", "%2C", ";", "%3B", `"`, "%22", " ", "%20")
+
+type APIServer struct {
+	opts *Options
+
+	// prefix is prepended to resource paths, e.g. `/v1` for versioned APIs.
+	prefix string
+
+	// authors is whether the authors group is mounted, so books can link to
+	// their author.
+	authors bool
+
+	// oauthKeys signs the tokens issued by the mock OAuth 2.0 server.
+	oauthKeys *oauthKeySet
+
+	// routes counts requests for the runtime stats.
+	routes *routeCounters
+
+	// ctx is done once the handler is closed, stopping background work.
+	ctx context.Context
+}
+
+func (s *APIServer) RegisterTypes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-types-example",
+		Method:      http.MethodGet,
+		Path:        "/types",
+		Description: "Example structured data types",
+		Tags:        []string{"Types"},
+	}, func(ctx context.Context, i *struct {
+		FilterParams
+	}) (*TypesResponse, error) {
+		if err := i.CheckFilter(); err != nil {
+			return nil, err
+		}
+
+		return &TypesResponse{
+			Body: TypesModel{
+				Boolean: true,
+				Integer: 42,
+				Number:  123.45,
+				String:  "Hello, world!",
+				Tags:    []string{"example", "short"},
+				Object: SubObject{
+					Binary:     []byte{222, 173, 192, 222},
+					BinaryLong: []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+					Date:       time.Now().UTC().Truncate(24 * time.Hour),
+					DateTime:   time.Now().UTC(),
+					URL:        "https://rest.sh/",
+				},
+			},
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-types-binary",
+		Method:      http.MethodGet,
+		Path:        "/types/binary",
+		Description: "The same bytes encoded as base64, base64url, base32, and hex, each with the schema's `contentEncoding` & `format` set accordingly. Decoding every field should give identical bytes, which makes it a canonical payload for comparing how clients and generated code handle binary encodings.",
+		Tags:        []string{"Types"},
+	}, func(ctx context.Context, i *struct{}) (*BinaryEncodingsResponse, error) {
+		return &BinaryEncodingsResponse{
+			Body: BinaryEncodingsModel{
+				Length:    len(binaryExample),
+				Bytes:     binaryExample,
+				Base64:    base64.StdEncoding.EncodeToString(binaryExample),
+				Base64URL: base64.RawURLEncoding.EncodeToString(binaryExample),
+				Base32:    base32.StdEncoding.EncodeToString(binaryExample),
+				Hex:       hex.EncodeToString(binaryExample),
+			},
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-types-maps",
+		Method:      http.MethodGet,
+		Path:        "/types/maps",
+		Description: "Example dictionary types: a map of integers, a map of objects, and a free-form object allowing any properties via `additionalProperties: true`.",
+		Tags:        []string{"Types"},
+	}, func(ctx context.Context, i *struct{}) (*MapsResponse, error) {
+		return &MapsResponse{
+			Body: MapsModel{
+				Counts: map[string]int{"apples": 3, "oranges": 0, "kiwis": 12},
+				Objects: map[string]MapItem{
+					"first":  {Name: "First", Count: 1},
+					"second": {Name: "Second", Count: 2},
+				},
+				Extra: FreeForm{
+					"string": "value",
+					"number": 1.5,
+					"list":   []any{true, nil, "mixed"},
+					"nested": map[string]any{"any": "thing"},
+				},
+			},
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-types-maps",
+		Method:      http.MethodPut,
+		Path:        "/types/maps",
+		Description: "Validate dictionary types against their schemas and return them as parsed. Values of the wrong type in `counts` or `objects` are rejected with the location of each, while `extra` accepts anything.",
+		Tags:        []string{"Types"},
+	}, func(ctx context.Context, i *struct {
+		Body MapsModel
+	}) (*MapsResponse, error) {
+		return &MapsResponse{Body: i.Body}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-types-example",
+		Method:      http.MethodPut,
+		Path:        "/types",
+		Description: "Example write for edits",
+		Tags:        []string{"Types"},
+	}, s.echoHandler)
+}
+
+type CachedResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Date         string `header:"Date"`
+	Expires      string `header:"Expires"`
+	Body         CachedModel
+}
+
+func (s *APIServer) RegisterCached(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-cached",
+		Method:      http.MethodGet,
+		Path:        "/cached/{seconds}",
+		Description: "Cached response example. An HTTP/1.0 style Expires header can be sent instead of or alongside Cache-Control, including one which conflicts with max-age, which takes precedence.",
+		Tags:        []string{"Caching"},
+	}, func(ctx context.Context, input *struct {
+		Seconds     int  `path:"seconds" minimum:"1" maximum:"300" doc:"Number of seconds to cache"`
+		Private     bool `query:"private" doc:"Disabled shared caches like CDNs"`
+		ExpiresOnly bool `query:"expires-only" doc:"Omit Cache-Control, sending only Expires like an HTTP/1.0 server. Defaults expires to the number of seconds to cache."`
+		ExpiresParams
+	}) (*CachedResponse, error) {
+		header := fmt.Sprintf("max-age=%d", input.Seconds)
+		if input.Private {
+			header = "private, " + header
+		}
+		if input.ExpiresOnly {
+			header = ""
+			if input.Expires == 0 {
+				input.Expires = input.Seconds
+			}
+		}
+
+		now := clockNow(ctx)
+		date, expires := input.headers(now)
+		return &CachedResponse{
+			CacheControl: header,
+			Date:         date,
+			Expires:      expires,
+			Body: CachedModel{
+				Generated: now,
+				Until:     now.Add(time.Duration(input.Seconds) * time.Second),
+			},
+		}, nil
+	})
+}
+
+type StatusResponse struct {
+	Status      int
+	RetryAfter  string `header:"Retry-After"`
+	XRetryIn    string `header:"X-Retry-In"`
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+func (s *APIServer) RegisterStatus(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-status",
+		Method:      http.MethodGet,
+		Path:        "/status/{code}",
+		Description: "Status code example, with an optional body rendered from a template. A `451 Unavailable For Legal Reasons` includes a `Link` to whoever is blocking the resource and a problem body explaining why, as described in RFC 7725. " + templateDoc,
+		Tags:        []string{"Status"},
+	}, func(ctx context.Context, input *struct {
+		RequestInfo
+		TemplateParams
+		Code       int    `path:"code" minimum:"100" maximum:"599" doc:"Status code to return"`
+		RetryAfter string `query:"retry-after" doc:"Retry-After header value"`
+		XRetryIn   string `query:"x-retry-in" doc:"X-Retry-In header value"`
+		BlockedBy  string `query:"blocked-by" format:"uri" doc:"URI of the entity blocking the resource for a 451, defaulting to this server"`
+	}) (*StatusResponse, error) {
+		resp := &StatusResponse{
+			Status:     input.Code,
+			RetryAfter: input.RetryAfter,
+			XRetryIn:   input.XRetryIn,
+		}
+		if input.Code == http.StatusUnavailableForLegalReasons {
+			// RFC 7725 identifies the entity implementing the block, like a
+			// service provider, rather than the authority requiring it.
+			blockedBy := input.BlockedBy
+			if blockedBy == "" {
+				client := GetClientInfo(ctx)
+				blockedBy = client.Scheme + "://" + client.Host + "/"
+			} else if u, err := url.Parse(blockedBy); err != nil || !u.IsAbs() {
+				return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+					Location: "query.blocked-by",
+					Message:  "expected an absolute URI",
+					Value:    input.BlockedBy,
+				})
+			} else {
+				blockedBy = u.String()
+			}
+			input.ctx.SetHeader("Link", "<"+linkTargetEscaper.Replace(blockedBy)+`>; rel="blocked-by"`)
+			if input.Template == "" {
+				return nil, huma.NewError(input.Code, "This resource is unavailable due to a legal demand. The server at the blocked-by link is implementing the block, not the authority which requested it.")
+			}
+		}
+		if input.Template != "" {
+			body, err := renderTemplate(input.ctx, input.Template, "query.template", nil)
+			if err != nil {
+				return nil, err
+			}
+			setTemplateSecurityHeaders(input.ctx)
+			resp.ContentType = input.templateContentType()
+			resp.Body = body
+		}
+		return resp, nil
+	})
+}
+
+// syntheticImages is how many seeded random images are listed after the
+// sample images, so that pagination has enough items to be interesting.
+const syntheticImages = 45
+
+// imageItems lists the sample images followed by seeded random images.
+var imageItems = func() []ImageItem {
+	items := []ImageItem{
+		{Name: "Dragonfly macro", Format: "jpeg", Self: "/images/jpeg"},
+		{Name: "Origami under blacklight", Format: "webp", Self: "/images/webp"},
+		{Name: "Andy Warhol mural in Miami", Format: "gif", Self: "/images/gif"},
+		{Name: "Station in Prague", Format: "png", Self: "/images/png"},
+		{Name: "Chihuly glass in boats", Format: "heic", Self: "/images/heic"},
+	}
+	formats := []string{"png", "jpeg", "gif"}
+	for i := 1; i <= syntheticImages; i++ {
+		format := formats[(i-1)%len(formats)]
+		items = append(items, ImageItem{
+			Name:   fmt.Sprintf("Random image %d", i),
+			Format: format,
+			Self:   fmt.Sprintf("/images/random?seed=%d&format=%s", i, format),
+		})
+	}
+	return items
+}()
+
+// legacyImageCursors maps the cursors from before page sizes were supported
+// to their offsets, so existing clients keep working.
+var legacyImageCursors = map[string]int{"abc123": 2, "def456": 4}
+
+// imageCursor returns an opaque cursor for an offset into the image list.
+func imageCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("images:" + strconv.Itoa(offset)))
+}
+
+// parseImageCursor returns the offset for a cursor, or an error if it's
+// invalid.
+func parseImageCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	if offset, ok := legacyImageCursors[cursor]; ok {
+		return offset, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), "images:") {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), "images:"))
+	if err != nil || offset < 0 || offset >= len(imageItems) {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
+
+type ListImagesResponse struct {
+	Link       string `header:"Link"`
+	TotalCount int    `header:"X-Total-Count"`
+	Body       []ImageItem
+}
+
+func (s *APIServer) RegisterListImages(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-images",
+		Method:      http.MethodGet,
+		Path:        "/images",
+		Description: "List available images, a page at a time. The sample images are followed by seeded random images. Pages link to the `first`, `prev`, and `next` pages via the `Link` header, and the total number of images is returned in `X-Total-Count`.",
+		Tags:        []string{"Images"},
+	}, func(ctx context.Context, input *struct {
+		Cursor string `query:"cursor" doc:"Pagination cursor from a Link header"`
+		Limit  int    `query:"limit" default:"2" minimum:"1" maximum:"50" doc:"Maximum number of images per page"`
+	}) (*ListImagesResponse, error) {
+		offset, err := parseImageCursor(input.Cursor)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Message:  err.Error(),
+				Location: "query.cursor",
+				Value:    input.Cursor,
+			})
+		}
+
+		end := offset + input.Limit
+		if end > len(imageItems) {
+			end = len(imageItems)
+		}
+
+		page := func(offset int) string {
+			u := fmt.Sprintf("/images?limit=%d", input.Limit)
+			if offset > 0 {
+				u += "&cursor=" + imageCursor(offset)
+			}
+			return u
+		}
+		links := []string{"<" + page(0) + `>; rel="first"`}
+		if offset > 0 {
+			prev := offset - input.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			links = append(links, "<"+page(prev)+`>; rel="prev"`)
+		}
+		if end < len(imageItems) {
+			links = append(links, "<"+page(end)+`>; rel="next"`)
+		}
+
+		return &ListImagesResponse{
+			Link:       strings.Join(links, ", "),
+			TotalCount: len(imageItems),
+			Body:       imageItems[offset:end],
+		}, nil
+	})
+}
+
+type GetImageResponse struct {
+	ContentType  string `header:"Content-Type"`
+	ETag         string `header:"ETag"`
+	LastModified string `header:"Last-Modified"`
+	CacheControl string `header:"Cache-Control"`
+	Body         func(ctx huma.Context)
+}
+
+func (s *APIServer) RegisterGetImage(api huma.API) {
+	content := map[string]*huma.MediaType{}
+	for t := range exampleImages {
+		content["image/"+t] = &huma.MediaType{Schema: &huma.Schema{Type: huma.TypeString, Format: "binary"}}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-image",
+		Method:      http.MethodGet,
+		Path:        "/images/{type}",
+		Description: "Get an image. Supports `Range` requests for partial downloads, including `If-Range`, and conditional requests via `If-None-Match` & `If-Modified-Since` using a strong ETag of the image contents.",
+		Tags:        []string{"Images"},
+		Responses: map[string]*huma.Response{
+			"200": {Description: "OK", Content: content},
+			"304": {Description: "Not Modified"},
+		},
+	}, func(ctx context.Context, i *struct {
+		Type string `path:"type" enum:"jpeg,webp,png,gif,heic"`
+	}) (*GetImageResponse, error) {
+		name := exampleImages[i.Type]
+		f, err := staticFiles.Open(name)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("unable to open image", err)
+		}
+		return &GetImageResponse{
+			ContentType:  "image/" + i.Type,
+			ETag:         staticETags[name],
+			LastModified: staticModified.Format(http.TimeFormat),
+			CacheControl: "public, max-age=3600",
+			Body: func(ctx huma.Context) {
+				defer f.Close()
+				// Embedded files can seek, so they can be served without copying.
+				content := f.(io.ReadSeeker)
+				if w, ok := ctx.BodyWriter().(http.ResponseWriter); ok {
+					http.ServeContent(w, newServeContentRequest(ctx), name, staticModified, content)
+					return
+				}
+				io.Copy(ctx.BodyWriter(), content)
+			},
+		}, nil
+	})
+}
+
+// Options configures the API and the server it runs in. The tags describe
+// each option for Huma's CLI, including defaults which aren't applied when
+// using the struct directly.
+type Options struct {
+	Host string `doc:"Host to listen on"`
+	Port int    `default:"8888" doc:"Port to listen on"`
+	H2C  bool   `name:"h2c" doc:"Accept cleartext HTTP/2 (h2c) via upgrade or prior knowledge"`
+
+	UnixSocket string `name:"unix-socket" doc:"Path to a Unix domain socket to listen on instead of host/port"`
+
+	TLSPort       int    `name:"tls-port" doc:"Port to listen on for HTTPS in addition to plain HTTP"`
+	TLSCert       string `name:"tls-cert" doc:"TLS certificate file, otherwise a self-signed one is generated"`
+	TLSKey        string `name:"tls-key" doc:"TLS private key file"`
+	RedirectHTTPS bool   `name:"redirect-https" doc:"Redirect plain HTTP requests to the HTTPS port"`
+	HSTSMaxAge    int    `name:"hsts-max-age" doc:"Send Strict-Transport-Security with this max age in seconds over HTTPS"`
+
+	TrustedProxies string `name:"trusted-proxies" doc:"Comma-separated CIDRs of proxies whose Forwarded and X-Forwarded-* headers are honored"`
+	AllowCIDR      string `name:"allow-cidr" doc:"Comma-separated CIDRs of client IPs allowed to make requests. Others are rejected."`
+	DenyCIDR       string `name:"deny-cidr" doc:"Comma-separated CIDRs of client IPs to reject, taking precedence over the allow list"`
+
+	APIKeys     string `name:"api-keys" doc:"Comma-separated API keys, one of which must be sent in the X-Api-Key header. Empty disables API key auth."`
+	APIKeyQuota int    `name:"api-key-quota" default:"1000" doc:"Requests allowed per API key each day, resetting at midnight UTC"`
+
+	Debug      bool   `doc:"Mount Go's pprof profiling endpoints under /debug/pprof/ and list open connections & in-flight requests at /debug/connections"`
+	DebugToken string `name:"debug-token" doc:"Require this token for the debug endpoints, sent as a bearer token or via ?token="`
+
+	ReadOnly bool `name:"read-only" doc:"Reject requests with methods other than GET, HEAD, and OPTIONS with a 403 error"`
+
+	ClockSkew string `name:"clock-skew" default:"0s" doc:"Make the server's clock appear off by this duration, like -300s, shifting the Date header & freshness timestamps. Requests can override it via ?clock-skew="`
+
+	ETagAlgorithm string `name:"etag-algorithm" default:"xxh3" doc:"Hash for the ETags of / and /example, one of xxh3, sha256, or md5. Requests can override it via ?etag_algorithm="`
+
+	Enable  string `doc:"Comma-separated endpoint groups to mount, e.g. books,images,echo. Defaults to all of them."`
+	Disable string `doc:"Comma-separated endpoint groups to leave unmounted, taking precedence over the enabled groups"`
+
+	MaxBodySize int64 `name:"max-body-size" default:"1048576" doc:"Maximum request body size in bytes"`
+
+	GzipLevel     int `name:"gzip-level" default:"6" doc:"Gzip compression level from 0 (none) to 9 (best)"`
+	BrotliQuality int `name:"brotli-quality" default:"6" doc:"Brotli compression quality from 0 (fastest) to 11 (best)"`
+
+	// The default minimum compression size assumes an Internet MTU of 1500 bytes,
+	// so anything smaller will still require sending at least that size
+	// (including headers). Google's research at
+	// http://dev.chromium.org/spdy/spdy-whitepaper suggests headers are at least
+	// 200 bytes and average 700-800 bytes. If we assume an average 30%
+	// compression ratio and 500 bytes of headers, then (1400 * 0.7) + 500 = 1480
+	// bytes, just about the minimum MTU.
+	CompressMinBytes int `name:"compress-min-bytes" default:"1400" doc:"Minimum response body size in bytes to compress"`
+
+	ReadTimeout       string `name:"read-timeout" default:"5s" doc:"Maximum duration for reading an entire request"`
+	ReadHeaderTimeout string `name:"read-header-timeout" default:"1s" doc:"Maximum duration for reading request headers"`
+	WriteTimeout      string `name:"write-timeout" default:"10s" doc:"Maximum duration before timing out writes of a response, streaming operations are exempt"`
+	IdleTimeout       string `name:"idle-timeout" default:"30s" doc:"Maximum duration to wait for the next request on a keep-alive connection"`
+
+	Record          string `doc:"Record all requests & responses, e.g. har:traffic.har to write an HTTP Archive which is appended to every few seconds and on shutdown, up to 10,000 entries"`
+	RecordBodyLimit int    `name:"record-body-limit" default:"65536" doc:"Maximum bytes of each request & response body to record"`
+
+	AccessLog       string `name:"access-log" doc:"Write an access log to this file, or - for stdout"`
+	AccessLogFormat string `name:"access-log-format" default:"combined" doc:"Access log format, either common or combined"`
+
+	WebhooksAllowPrivate bool `name:"webhooks-allow-private" doc:"Allow webhook deliveries to loopback and private network addresses"`
+
+	JWKSRotateInterval string `name:"jwks-rotate-interval" default:"0s" doc:"Rotate the OAuth signing key on this interval of at least 1m, keeping previous keys published until their tokens expire. Zero disables rotation."`
+}
+
+// yamlFormat adds YAML support to the API's content negotiation.
+var yamlFormat = huma.Format{
+	Marshal: func(writer io.Writer, v any) error {
+		return yaml.NewEncoder(writer).Encode(v)
+	},
+	Unmarshal: func(data []byte, v any) error {
+		return yaml.Unmarshal(data, v)
+	},
+}
+
+// parseDuration parses a duration option, returning an error with a useful
+// message if the value is invalid. An empty value is zero.
+func parseDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s %q, must not be negative", name, value)
+	}
+	return d, nil
+}
+
+// mustParseDuration parses a duration option, panicking with a useful message
+// if the value is invalid. Options checked by `Validate` never panic.
+func mustParseDuration(name, value string) time.Duration {
+	d, err := parseDuration(name, value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// compressOptions returns the response compression settings.
+func (o *Options) compressOptions() CompressOptions {
+	return CompressOptions{
+		GzipLevel:     o.GzipLevel,
+		BrotliQuality: o.BrotliQuality,
+		MinSize:       o.CompressMinBytes,
+	}
+}
+
+// Validate returns an error describing the first invalid option, if any.
+func (o *Options) Validate() error {
+	for _, cidrs := range []string{o.TrustedProxies, o.AllowCIDR, o.DenyCIDR} {
+		if _, err := parseCIDRs(cidrs); err != nil {
+			return err
+		}
+	}
+	if err := o.compressOptions().Validate(); err != nil {
+		return err
+	}
+	if o.ClockSkew != "" {
+		if _, err := parseClockSkew(o.ClockSkew); err != nil {
+			return fmt.Errorf("invalid clock-skew %q, expected a duration from -%s to %s", o.ClockSkew, maxClockSkew, maxClockSkew)
+		}
+	}
+	if o.ETagAlgorithm != "" {
+		if err := validateETagAlgorithm(o.ETagAlgorithm); err != nil {
+			return err
+		}
+	}
+	if o.Record != "" {
+		if _, err := parseRecord(o.Record); err != nil {
+			return err
+		}
+	}
+	if o.AccessLog != "" && o.AccessLogFormat != accessLogCommon && o.AccessLogFormat != accessLogCombined {
+		return fmt.Errorf("invalid access log format %q", o.AccessLogFormat)
+	}
+	if o.APIKeys != "" && o.APIKeyQuota < 1 {
+		return fmt.Errorf("invalid api-key-quota %d, must be positive", o.APIKeyQuota)
+	}
+	for _, d := range []struct{ name, value string }{
+		{"read-timeout", o.ReadTimeout},
+		{"read-header-timeout", o.ReadHeaderTimeout},
+		{"write-timeout", o.WriteTimeout},
+		{"idle-timeout", o.IdleTimeout},
+		{"jwks-rotate-interval", o.JWKSRotateInterval},
+	} {
+		if _, err := parseDuration(d.name, d.value); err != nil {
+			return err
+		}
+	}
+	if rotate, _ := parseDuration("jwks-rotate-interval", o.JWKSRotateInterval); rotate > 0 && rotate < minOAuthKeyRotation {
+		return fmt.Errorf("invalid jwks-rotate-interval %q, must be zero or at least %s", o.JWKSRotateInterval, minOAuthKeyRotation)
+	}
+	return nil
+}
+
+// DefaultOptions returns the options with the same defaults as the CLI, which
+// is a good starting point when embedding the API.
+func DefaultOptions() *Options {
+	opts := &Options{}
+	v := reflect.ValueOf(opts).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		def, ok := v.Type().Field(i).Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString(def)
+		case reflect.Int, reflect.Int64:
+			n, _ := strconv.ParseInt(def, 10, 64)
+			f.SetInt(n)
+		case reflect.Bool:
+			f.SetBool(def == "true")
+		}
+	}
+	return opts
+}
+
+// Handler serves the APIs created by `New`. It should be closed once the
+// server has shut down to stop background work and write recorded traffic.
+type Handler struct {
+	http.Handler
+	closers []io.Closer
+}
+
+// Close stops background work, returning the first error encountered.
+func (h *Handler) Close() error {
+	var err error
+	for _, c := range h.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// closerFunc adapts a function to an `io.Closer`.
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// New creates the API described by the options, returning the handler which
+// serves it and the main unversioned API. Nil options use `DefaultOptions`.
+// It panics if any of the options are invalid, see `NewE` to get an error
+// instead. Data like the stored books is shared by every API created.
+func New(opts *Options) (*Handler, huma.API) {
+	handler, api, err := NewE(opts)
+	if err != nil {
+		panic(err)
+	}
+	return handler, api
+}
+
+// NewE is like `New` but returns an error if any of the options are invalid.
+func NewE(opts *Options) (*Handler, huma.API, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	var api huma.API
+	var apis []huma.API
+
+	router := chi.NewMux()
+
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+	trusted, _ := parseCIDRs(opts.TrustedProxies)
+	allow, _ := parseCIDRs(opts.AllowCIDR)
+	deny, _ := parseCIDRs(opts.DenyCIDR)
+	compress := opts.compressOptions()
+	var clockSkew time.Duration
+	if opts.ClockSkew != "" {
+		clockSkew, _ = parseClockSkew(opts.ClockSkew)
+	}
+
+	// Anything started before an error is stopped again.
+	var closers []io.Closer
+	fail := func(err error) (*Handler, huma.API, error) {
+		(&Handler{closers: closers}).Close()
+		return nil, nil, err
+	}
+	routes := newRouteCounters()
+	router.Use(middleware.Recoverer)
+	router.Use(routes.Middleware)
+	router.Use(ForwardedHeaders(trusted))
+	router.Use(ConnectionDetails)
+	if opts.Debug {
+		router.Use(TrackInFlight)
+	}
+	if opts.Record != "" {
+		path, _ := parseRecord(opts.Record)
+		rec, err := newHARRecorder(path, opts.RecordBodyLimit)
+		if err != nil {
+			return fail(err)
+		}
+		closers = append(closers, rec)
+		router.Use(rec.Middleware)
+	}
+	if opts.AccessLog != "" {
+		w, err := openAccessLog(opts.AccessLog)
+		if err != nil {
+			return fail(err)
+		}
+		router.Use(AccessLog(w, opts.AccessLogFormat))
+	}
+	if len(allow) > 0 || len(deny) > 0 {
+		router.Use(IPAccess(&api, allow, deny))
+	}
+	if opts.ReadOnly {
+		router.Use(ReadOnly(&api))
+	}
+	if opts.APIKeys != "" {
+		router.Use(APIKeyQuotas(&api, strings.Split(opts.APIKeys, ","), opts.APIKeyQuota))
+	}
+	if opts.TLSPort > 0 && opts.RedirectHTTPS {
+		router.Use(RedirectHTTPS(opts.TLSPort))
+	}
+	if opts.HSTSMaxAge > 0 {
+		router.Use(StrictTransportSecurity(opts.HSTSMaxAge))
+	}
+	router.Use(ClockSkew(&api, clockSkew))
+	router.Use(DecompressRequests(&api, opts.MaxBodySize))
+	router.Use(AuditBooks)
+	router.Use(HeadRequests)
+	router.Use(AllowedMethods(&apis))
+	router.Use(ContentEncoding(compress))
+	router.Use(PrecompressedResponses)
+
+	router.Use(LandingPage)
+
+	config := huma.DefaultConfig("Example API", "1.0.0")
+	config.Info.Description = docs
+	if opts.ReadOnly {
+		config.Info.Description += readOnlyDocs
+	}
+	if opts.APIKeys != "" {
+		documentAPIKeys(&config)
+	}
+	config.Servers = []*huma.Server{
+		{URL: "https://api.rest.sh"},
+	}
+
+	config.Formats["application/yaml"] = yamlFormat
+	config.Formats["yaml"] = yamlFormat
+	config.Transformers = append(config.Transformers, SparseFields, FilterResponse)
+
+	api = humachi.New(router, config)
+
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		ctx := humachi.NewContext(nil, r, w)
+		huma.WriteErr(api, ctx, http.StatusNotFound, "The requested resource was not found")
+	})
+
+	static := StaticFiles(router.NotFoundHandler())
+	router.Handle("/static", http.RedirectHandler("/static/", http.StatusMovedPermanently))
+	router.Get("/static/*", static)
+	router.Head("/static/*", static)
+
+	if opts.Debug {
+		mountDebug(router, &api, opts.DebugToken)
+	}
+
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			methods, _ := allowedMethods(rctx.Routes, routePath(rctx, r))
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+		}
+		ctx := humachi.NewContext(nil, r, w)
+		huma.WriteErr(api, ctx, http.StatusMethodNotAllowed, "HTTP method is not allowed on the given resource")
+	})
+
+	api.UseMiddleware(MaxBodySize(api, opts.MaxBodySize))
+	api.UseMiddleware(ExemptWriteTimeout)
+	api.UseMiddleware(HeadStreaming)
+	api.UseMiddleware(NotModifiedWithoutBody)
+
+	groups := newEndpointGroups(opts.Enable, opts.Disable)
+	ctx, cancel := context.WithCancel(context.Background())
+	closers = append(closers, closerFunc(func() error {
+		cancel()
+		return nil
+	}))
+	authors := groups.allows(&huma.Operation{Tags: []string{"Authors"}})
+	server := APIServer{opts: opts, authors: authors, oauthKeys: &oauthKeySet{}, routes: routes, ctx: ctx}
+	huma.AutoRegister(withGroups(api, groups), &server)
+	if err := groups.validate(); err != nil {
+		return fail(err)
+	}
+	groups.remove(api.OpenAPI())
+
+	autopatch.AutoPatch(api)
+	setMaxBodyBytes(api, opts.MaxBodySize)
+	addHeadOperations(api)
+	apis = append(apis, api)
+
+	// Versioned APIs only have books operations.
+	if groups.allows(&huma.Operation{Tags: []string{"Books"}}) {
+		for _, version := range apiVersions {
+			apis = append(apis, mountVersion(router, opts, version, authors))
+		}
+	}
+
+	var handler http.Handler = router
+	if opts.H2C {
+		// Allow HTTP/2 without TLS, either via an `Upgrade: h2c` request or
+		// by the client sending the HTTP/2 preface directly.
+		handler = h2c.NewHandler(router, &http2.Server{})
+	}
+
+	return &Handler{Handler: handler, closers: closers}, api, nil
+}
+
+// NewServer creates a server for a handler from `New` using the timeouts in
+// the options. Connections are tracked so that reuse can be reported, and
+// open connections listed in debug mode. It panics if a timeout is invalid,
+// which `Options.Validate` checks for.
+func NewServer(opts *Options, handler http.Handler) *http.Server {
+	return &http.Server{
+		ReadTimeout:       mustParseDuration("read-timeout", opts.ReadTimeout),
+		ReadHeaderTimeout: mustParseDuration("read-header-timeout", opts.ReadHeaderTimeout),
+		WriteTimeout:      mustParseDuration("write-timeout", opts.WriteTimeout),
+		IdleTimeout:       mustParseDuration("idle-timeout", opts.IdleTimeout),
+		Handler:           handler,
+		ConnContext:       TrackConnection,
+		ConnState:         trackConnState,
+	}
+}
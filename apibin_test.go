@@ -0,0 +1,177 @@
+package apibin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestServer starts a server for the API with the given options, which
+// is closed along with the handler when the test ends.
+func newTestServer(t *testing.T, opts *Options) *httptest.Server {
+	t.Helper()
+	handler, _, err := NewE(opts)
+	if err != nil {
+		t.Fatalf("unexpected error creating the API: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		server.Close()
+		if err := handler.Close(); err != nil {
+			t.Errorf("unexpected error closing the handler: %v", err)
+		}
+	})
+	return server
+}
+
+func TestNew(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	resp, err := http.Get(server.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for the OpenAPI, got %d", resp.StatusCode)
+	}
+	var doc struct {
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/books", "/example", "/flaky", "/breaker/{name}"} {
+		if doc.Paths[path] == nil {
+			t.Errorf("expected %s in the OpenAPI", path)
+		}
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*Options)
+		want   string
+	}{
+		{"cidr", func(o *Options) { o.TrustedProxies = "nope" }, "invalid IP address"},
+		{"gzip", func(o *Options) { o.GzipLevel = 42 }, "gzip level"},
+		{"clock-skew", func(o *Options) { o.ClockSkew = "99h" }, "clock-skew"},
+		{"etag", func(o *Options) { o.ETagAlgorithm = "crc32" }, "etag-algorithm"},
+		{"record", func(o *Options) { o.Record = "json:out.json" }, "record"},
+		{"timeout", func(o *Options) { o.ReadTimeout = "soon" }, "read-timeout"},
+		{"jwks rotation", func(o *Options) { o.JWKSRotateInterval = "1ms" }, "jwks-rotate-interval"},
+		{"quota", func(o *Options) { o.APIKeys, o.APIKeyQuota = "key", 0 }, "api-key-quota"},
+		{"group", func(o *Options) { o.Disable = "nope" }, "unknown endpoint group nope"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tc.modify(opts)
+			_, _, err := NewE(opts)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		status int
+		want   map[string]string
+	}{
+		{name: "flaky fails", path: "/flaky?key=test-fails&failures=1&retry-after=3", status: http.StatusServiceUnavailable, want: map[string]string{"X-Flaky-Attempt": "1", "Retry-After": "3"}},
+		{name: "flaky succeeds", path: "/flaky?key=test-succeeds&failures=0", status: http.StatusOK, want: map[string]string{"X-Flaky-Attempt": "1"}},
+		{name: "flaky status", path: "/flaky?key=test-status&status=429", status: http.StatusTooManyRequests},
+		{name: "breaker healthy", path: "/breaker/test-healthy", status: http.StatusOK, want: map[string]string{"X-Breaker-State": "healthy"}},
+		{name: "breaker state", path: "/breaker/test-state/state", status: http.StatusOK, want: map[string]string{"Cache-Control": "no-store"}},
+		{name: "timeout headers", path: "/timeout?after=0s&status=524", status: 524, want: map[string]string{"Content-Type": "application/problem+json"}},
+		{name: "timeout invalid", path: "/timeout?after=1h", status: http.StatusUnprocessableEntity},
+		{name: "status 451", path: "/status/451", status: http.StatusUnavailableForLegalReasons, want: map[string]string{"Link": `<` + server.URL + `/>; rel="blocked-by"`}},
+		{name: "status 451 blocked by", path: "/status/451?blocked-by=" + url.QueryEscape("http://a/?x=>;rel=foo,<http://b/>"), status: http.StatusUnavailableForLegalReasons, want: map[string]string{"Link": `<http://a/?x=%3E%3Brel=foo%2C%3Chttp://b/%3E>; rel="blocked-by"`}},
+		{name: "status template", path: "/status/201?template=%7B%7Bmethod%7D%7D", status: http.StatusCreated, want: map[string]string{"X-Content-Type-Options": "nosniff"}},
+		{name: "template html", path: "/template-echo?template=x&content_type=text/html", status: http.StatusUnprocessableEntity},
+		{name: "clock skew query", path: "/books?clock-skew=1h", status: http.StatusOK, want: map[string]string{"X-Apibin-Clock-Skew": "1h0m0s"}},
+		{name: "clock skew invalid", path: "/books?clock-skew=48h", status: http.StatusUnprocessableEntity},
+		{name: "soap wsdl", path: "/soap?wsdl", status: http.StatusOK, want: map[string]string{"Content-Type": "text/xml; charset=utf-8"}},
+		{name: "soap get", path: "/soap", status: http.StatusMethodNotAllowed, want: map[string]string{"Allow": "POST", "Content-Type": "application/problem+json"}},
+		{name: "book links", path: "/books/sapiens", status: http.StatusOK, want: map[string]string{"Link": `</authors/yuval-noah-harari>; rel="author", </books/sapiens/ratings>; rel="reviews"`}},
+		{name: "book links v1", path: "/v1/books/sapiens", status: http.StatusOK, want: map[string]string{"Link": `</authors/yuval-noah-harari>; rel="author", </v1/books/sapiens/ratings>; rel="reviews"`}},
+		{name: "book links v2", path: "/v2/books/sapiens", status: http.StatusOK, want: map[string]string{"Link": `</authors/yuval-noah-harari>; rel="author"`}},
+		{name: "bytes not compressed", path: "/bytes/4096", header: map[string]string{"Accept-Encoding": "gzip"}, status: http.StatusOK, want: map[string]string{"Content-Encoding": "", "Content-Length": "4096"}},
+		{name: "etag algorithm", path: "/example?etag_algorithm=md5", status: http.StatusOK, want: map[string]string{"X-Apibin-ETag-Algorithm": "md5"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, err := http.NewRequest(method, server.URL+tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tc.header {
+				req.Header.Set(name, value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, resp.StatusCode)
+			}
+			for name, value := range tc.want {
+				if got := resp.Header.Get(name); got != value {
+					t.Errorf("expected %s: %s, got %q", name, value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBookLinksWithoutAuthors(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Disable = "authors"
+	server := newTestServer(t, opts)
+
+	resp, err := http.Get(server.URL + "/books/sapiens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if link := resp.Header.Get("Link"); link != `</books/sapiens/ratings>; rel="reviews"` {
+		t.Errorf("expected only the reviews link, got %q", link)
+	}
+}
+
+func TestDebug(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Debug = true
+	server := newTestServer(t, opts)
+
+	for path, status := range map[string]int{
+		"/debug/pprof/":        http.StatusOK,
+		"/debug/pprof/cmdline": http.StatusNotFound,
+		"/debug/connections":   http.StatusOK,
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("expected %d for %s, got %d", status, path, resp.StatusCode)
+		}
+	}
+}
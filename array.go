@@ -0,0 +1,118 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/negotiation"
+)
+
+// arrayFlushEvery is how many items are written between flushes.
+const arrayFlushEvery = 1000
+
+// arrayFormats are the content types `/types/array` can be negotiated as. The
+// first is used when the client doesn't ask for one of them.
+var arrayFormats = []string{"application/json", "application/x-ndjson", "application/cbor"}
+
+// ArrayItem is a small object in a large array.
+type ArrayItem struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Active bool    `json:"active"`
+}
+
+// arrayItem returns the item at a position in the array. Items only depend
+// on their ID so every response with the same count is identical.
+func arrayItem(id int) ArrayItem {
+	return ArrayItem{
+		ID:     id,
+		Name:   fmt.Sprintf("item-%07d", id),
+		Value:  float64(id%1000) / 8,
+		Active: id%3 != 0,
+	}
+}
+
+func (s *APIServer) RegisterTypesArray(api huma.API) {
+	itemSchema := api.OpenAPI().Components.Schemas.Schema(reflect.TypeOf(ArrayItem{}), true, "")
+	arraySchema := api.OpenAPI().Components.Schemas.Schema(reflect.TypeOf([]ArrayItem{}), true, "")
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-types-array",
+		Method:      http.MethodGet,
+		Path:        "/types/array",
+		Description: "Stream a large array of small objects with deterministic contents, for measuring parser throughput & memory use. The array is sent as JSON, newline-delimited JSON with one item per line, or a CBOR indefinite-length array depending on the `Accept` header.",
+		Tags:        []string{"Types"},
+		Metadata:    map[string]any{streamingKey: true},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "OK",
+				Headers: map[string]*huma.Param{
+					"X-Total-Count": {Description: "Number of items in the array", Schema: &huma.Schema{Type: huma.TypeInteger}},
+				},
+				Content: map[string]*huma.MediaType{
+					"application/json":     {Schema: arraySchema},
+					"application/x-ndjson": {Schema: itemSchema},
+					"application/cbor":     {Schema: arraySchema},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *struct {
+		Count  int    `query:"count" default:"10000" minimum:"0" maximum:"1000000" doc:"Number of items in the array"`
+		Accept string `header:"Accept"`
+	}) (*huma.StreamResponse, error) {
+		ct := negotiation.SelectQValueFast(input.Accept, arrayFormats)
+		if ct == "" {
+			ct = arrayFormats[0]
+		}
+
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				ctx.SetHeader("Content-Type", ct)
+				ctx.AppendHeader("Vary", "Accept")
+				ctx.SetHeader("X-Total-Count", strconv.Itoa(input.Count))
+				ctx.SetStatus(http.StatusOK)
+
+				w := ctx.BodyWriter()
+				flusher, _ := w.(http.Flusher)
+				itemType := ct
+				switch ct {
+				case "application/json":
+					w.Write([]byte("["))
+				case "application/x-ndjson":
+					itemType = "application/json"
+				case "application/cbor":
+					// Start of an indefinite-length array, see RFC 8949 section 3.2.2.
+					w.Write([]byte{0x9f})
+				}
+
+				for id := 1; id <= input.Count; id++ {
+					if id > 1 && ct == "application/json" {
+						w.Write([]byte(","))
+					}
+					if err := api.Marshal(w, itemType, arrayItem(id)); err != nil {
+						return
+					}
+					if id%arrayFlushEvery == 0 && flusher != nil {
+						if ctx.Context().Err() != nil {
+							// The client went away, so stop writing.
+							return
+						}
+						flusher.Flush()
+					}
+				}
+
+				switch ct {
+				case "application/json":
+					w.Write([]byte("]\n"))
+				case "application/cbor":
+					w.Write([]byte{0xff})
+				}
+			},
+		}, nil
+	})
+}
@@ -0,0 +1,42 @@
+package apibin
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// readBasicAuth parses the credentials from an `Authorization` header.
+func readBasicAuth(header string) (string, string, bool) {
+	r := http.Request{Header: http.Header{"Authorization": {header}}}
+	return r.BasicAuth()
+}
+
+// AuthResult describes a successful authentication.
+type AuthResult struct {
+	Authenticated bool   `json:"authenticated"`
+	User          string `json:"user"`
+}
+
+func (s *APIServer) RegisterHiddenBasicAuth(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-hidden-basic-auth",
+		Method:      http.MethodGet,
+		Path:        "/hidden-basic-auth/{user}/{pass}",
+		Description: "Require HTTP basic auth with the username and password from the path. Wrong or missing credentials get a 404 without a `WWW-Authenticate` challenge, hiding that the resource exists, so browsers won't prompt for credentials.",
+		Tags:        []string{"Auth"},
+		Errors:      []int{http.StatusNotFound},
+	}, func(ctx context.Context, input *struct {
+		User          string `path:"user" doc:"Expected username"`
+		Pass          string `path:"pass" doc:"Expected password"`
+		Authorization string `header:"Authorization"`
+	}) (*struct{ Body AuthResult }, error) {
+		user, pass, ok := readBasicAuth(input.Authorization)
+		if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(input.User))&subtle.ConstantTimeCompare([]byte(pass), []byte(input.Pass)) != 1 {
+			return nil, huma.Error404NotFound("The requested resource was not found")
+		}
+		return &struct{ Body AuthResult }{Body: AuthResult{Authenticated: true, User: user}}, nil
+	})
+}
@@ -0,0 +1,152 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/danielgtaylor/huma/v2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// AuthorSummary provides a link to an author for the authors list response.
+type AuthorSummary struct {
+	URL   string `json:"url"`
+	Name  string `json:"name"`
+	Books int    `json:"books" doc:"Number of books by this author"`
+}
+
+// Author is derived from the stored books, so it always reflects their
+// current state.
+type Author struct {
+	Name  string   `json:"name"`
+	Books []string `json:"books" doc:"Links to the books by this author"`
+}
+
+// authorID generates a URL-safe identifier from an author's name, e.g.
+// `J. Kenji López-Alt` becomes `j-kenji-lopez-alt`.
+func authorID(name string) string {
+	id := strings.Builder{}
+	dash := false
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop accents left over from decomposition.
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && id.Len() > 0 {
+				id.WriteByte('-')
+			}
+			id.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	return id.String()
+}
+
+// bookLinks returns the value of a `Link` header pointing to a book's related
+// resources, which can also be embedded via `?embed=`. Only resources which
+// are mounted are linked: authors are unversioned and may be disabled, and
+// the reviews only when the API has a ratings route.
+func (s *APIServer) bookLinks(id string, b *Book, reviews bool) string {
+	links := []string{}
+	if s.authors && b.Author != "" {
+		links = append(links, `</authors/`+authorID(b.Author)+`>; rel="author"`)
+	}
+	if reviews {
+		links = append(links, `<`+s.prefix+`/books/`+id+`/ratings>; rel="reviews"`)
+	}
+	return strings.Join(links, ", ")
+}
+
+// findAuthor returns the author with the given ID, or nil if no books are by
+// that author. The caller must hold the read lock.
+func findAuthor(id string) *Author {
+	_, names, authorBooks := listAuthors()
+	name, ok := names[id]
+	if !ok {
+		return nil
+	}
+
+	author := &Author{Name: name, Books: []string{}}
+	for _, k := range authorBooks[id] {
+		author.Books = append(author.Books, "/books/"+k)
+	}
+	return author
+}
+
+// listAuthors returns the author IDs in book order, along with the names and
+// book IDs for each. The caller must hold the read lock.
+func listAuthors() ([]string, map[string]string, map[string][]string) {
+	order := []string{}
+	names := map[string]string{}
+	authorBooks := map[string][]string{}
+	for _, k := range booksOrder {
+		b := books[k]
+		if b.Author == "" {
+			continue
+		}
+		id := authorID(b.Author)
+		if _, ok := names[id]; !ok {
+			order = append(order, id)
+			names[id] = b.Author
+		}
+		authorBooks[id] = append(authorBooks[id], k)
+	}
+	return order, names, authorBooks
+}
+
+func (s *APIServer) RegisterListAuthors(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-authors",
+		Method:      http.MethodGet,
+		Path:        "/authors",
+		Tags:        []string{"Authors"},
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body []AuthorSummary }, error) {
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		order, names, authorBooks := listAuthors()
+		l := make([]AuthorSummary, 0, len(order))
+		for _, id := range order {
+			l = append(l, AuthorSummary{
+				URL:   "/authors/" + id,
+				Name:  names[id],
+				Books: len(authorBooks[id]),
+			})
+		}
+
+		return &struct{ Body []AuthorSummary }{Body: l}, nil
+	})
+}
+
+type GetAuthorResponse struct {
+	Link string `header:"Link"`
+	Body *Author
+}
+
+func (s *APIServer) RegisterGetAuthor(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-author",
+		Method:      http.MethodGet,
+		Path:        "/authors/{author-id}",
+		Tags:        []string{"Authors"},
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"author-id"`
+	}) (*GetAuthorResponse, error) {
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		author := findAuthor(input.ID)
+		if author == nil {
+			return nil, huma.Error404NotFound(input.ID + " not found")
+		}
+
+		return &GetAuthorResponse{
+			Link: `</books?author=` + input.ID + `>; rel="related"`,
+			Body: author,
+		}, nil
+	})
+}
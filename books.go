@@ -1,4 +1,4 @@
-package main
+package apibin
 
 import (
 	"context"
@@ -8,7 +8,6 @@ import (
 	"hash/fnv"
 	"net/http"
 	"sort"
-	"sync"
 	"time"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -32,10 +31,29 @@ type Book struct {
 	RatingAverage float64   `json:"rating_average,omitempty"`
 	RecentRatings []Rating  `json:"recent_ratings,omitempty"`
 	modified      time.Time `json:"-"`
+	version       string    `json:"-"`
+
+	Embedded *BookEmbedded `json:"_embedded,omitempty" readOnly:"true" doc:"Related resources included inline via ?embed= instead of as links"`
+}
+
+// BookEmbedded holds a book's related resources, which are otherwise only
+// available via links.
+type BookEmbedded struct {
+	Author  *Author          `json:"author,omitempty"`
+	Reviews *RatingAggregate `json:"reviews,omitempty"`
 }
 
-// Version computes a base64 hash of the book's public fields.
+// Version returns a base64 hash of the book's public fields. It is cached
+// when the book is stored to avoid marshaling the book on every read.
 func (b Book) Version() string {
+	if b.version != "" {
+		return b.version
+	}
+	return b.computeVersion()
+}
+
+// computeVersion hashes the book's public fields.
+func (b Book) computeVersion() string {
 	data, _ := json.Marshal(b)
 
 	h := fnv.New128()
@@ -45,6 +63,13 @@ func (b Book) Version() string {
 	return base64.StdEncoding.EncodeToString(result)
 }
 
+// updated marks the book as modified now and caches its new version. It must
+// be called after changing any of the book's public fields.
+func (b *Book) updated() {
+	b.modified = time.Now()
+	b.version = b.computeVersion()
+}
+
 // BookSummary provides a link and version for the books list response.
 type BookSummary struct {
 	URL      string    `json:"url"`
@@ -52,12 +77,16 @@ type BookSummary struct {
 	Modified time.Time `json:"modified"`
 }
 
+// maxBooks limits the total number of stored books. The oldest are deleted
+// first when the limit is reached.
+const maxBooks = 20
+
 // booksMu controls access to the map/slice. This is necessary because maps &
 // slices are not goroutine-safe and each incoming request may use a separate
 // goroutine to handle it. The slice is used to provide a consistent list and
 // deletion order since Go maps are unordered. On initial load from the
 // unordered JSON an alphanumeric sort is used.
-var booksMu = sync.RWMutex{}
+var booksMu = instrumentedRWMutex{}
 var books map[string]*Book
 var booksOrder = []string{}
 
@@ -77,13 +106,17 @@ func init() {
 				// Set the last-modified time for conditional update headers to service
 				// startup. This will rev on restarts but is good enough for demonstration
 				// purposes.
-				b.modified = time.Now()
+				b.updated()
 			}
 
 			booksMu.Lock()
 			books = loaded
 			booksOrder = maps.Keys(books)
 			sort.Strings(booksOrder)
+			bookHistory = map[string][]bookRevision{}
+			for k, b := range books {
+				recordRevision(k, b)
+			}
 			booksMu.Unlock()
 
 			time.Sleep(10 * time.Minute)
@@ -98,43 +131,128 @@ func init() {
 			booksMu.Lock()
 			b := books["sapiens"]
 			if b != nil {
-				b.modified = time.Now()
 				b.RecentRatings = []Rating{
 					{Date: time.Now(), Rating: 4.6},
 				}
+				b.updated()
 				books["sapiens"] = b
+				publishBookEvent(BookUpdatedEvent(newBookEvent("sapiens", b)))
 			}
 			booksMu.Unlock()
 		}
 	}()
 }
 
+// storeBook creates or replaces a book. The caller must hold the write lock.
+func storeBook(id string, b *Book) {
+	created := books[id] == nil
+	if created {
+		booksOrder = append(booksOrder, id)
+	}
+	b.Embedded = nil
+	b.updated()
+	books[id] = b
+	recordRevision(id, b)
+
+	if created {
+		publishBookEvent(BookCreatedEvent(newBookEvent(id, b)))
+	} else {
+		publishBookEvent(BookUpdatedEvent(newBookEvent(id, b)))
+	}
+
+	// Limit the total number of books by deleting the oldest first. These will
+	// get reset periodically by the goroutine in `init()` above.
+	for len(books) > maxBooks {
+		removeBook(booksOrder[0])
+	}
+}
+
+// removeBook deletes a book and its history. The caller must hold the write
+// lock.
+func removeBook(id string) {
+	if books[id] == nil {
+		return
+	}
+
+	delete(books, id)
+	delete(bookHistory, id)
+	if idx := slices.Index(booksOrder, id); idx > -1 {
+		booksOrder = slices.Delete(booksOrder, idx, idx+1)
+	}
+
+	publishBookEvent(BookDeletedEvent(newBookEvent(id, nil)))
+}
+
 type ListResponse struct {
+	ETag         string    `header:"Etag"`
+	LastModified time.Time `header:"Last-Modified"`
+	Vary         string    `header:"Vary"`
+
 	Body []BookSummary
 }
 
+// collectionVersion computes a base64 hash of the members of a list of books,
+// so it changes whenever a book is added, removed, or modified.
+func collectionVersion(l []BookSummary) string {
+	h := fnv.New128()
+	for _, summary := range l {
+		h.Write([]byte(summary.URL + "\n" + summary.Version + "\n"))
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
 func (s *APIServer) RegisterListBooks(api huma.API) {
 	huma.Register(api, huma.Operation{
 		OperationID: "list-books",
 		Method:      http.MethodGet,
-		Path:        "/books",
+		Path:        s.prefix + "/books",
 		Tags:        []string{"Books"},
-	}, func(ctx context.Context, input *struct{}) (*ListResponse, error) {
+	}, func(ctx context.Context, input *struct {
+		conditional.Params
+		ETagParams
+		FieldsParams
+		FilterParams
+		Author string `query:"author" doc:"Only include books by this author ID"`
+	}) (*ListResponse, error) {
+		if err := input.CheckFields(BookSummary{}); err != nil {
+			return nil, err
+		}
+		if err := input.CheckFilter(); err != nil {
+			return nil, err
+		}
+
 		booksMu.RLock()
 		defer booksMu.RUnlock()
 
 		// Return a list of summaries with metadata about each book.
 		l := make([]BookSummary, 0, len(books))
+		modified := time.Time{}
 		for _, k := range booksOrder {
 			b := books[k]
+			if input.Author != "" && authorID(b.Author) != input.Author {
+				continue
+			}
 			l = append(l, BookSummary{
-				URL:      "/books/" + k,
+				URL:      s.prefix + "/books/" + k,
 				Version:  b.Version(),
 				Modified: b.modified,
 			})
+			if b.modified.After(modified) {
+				modified = b.modified
+			}
 		}
 
-		return &ListResponse{Body: l}, nil
+		version := collectionVersion(l)
+		if err := input.PreconditionFailed(version, modified); err != nil {
+			return nil, err
+		}
+
+		return &ListResponse{
+			ETag:         formatETag(version, input.ETag),
+			LastModified: modified,
+			Vary:         "Accept, Accept-Encoding, Origin",
+			Body:         l,
+		}, nil
 	})
 }
 
@@ -142,6 +260,7 @@ type GetBookResponse struct {
 	CacheControl string    `header:"Cache-Control"`
 	ETag         string    `header:"Etag"`
 	LastModified time.Time `header:"Last-Modified"`
+	Link         string    `header:"Link"`
 	Vary         string    `header:"Vary"`
 
 	Body *Book
@@ -151,12 +270,23 @@ func (s *APIServer) RegisterGetBook(api huma.API) {
 	huma.Register(api, huma.Operation{
 		OperationID: "get-book",
 		Method:      http.MethodGet,
-		Path:        "/books/{book-id}",
+		Path:        s.prefix + "/books/{book-id}",
 		Tags:        []string{"Books"},
 	}, func(ctx context.Context, input *struct {
 		conditional.Params
-		ID string `path:"book-id"`
+		ETagParams
+		FieldsParams
+		FilterParams
+		ID    string   `path:"book-id"`
+		Embed []string `query:"embed" enum:"author,reviews" doc:"Related resources to include inline"`
 	}) (*GetBookResponse, error) {
+		if err := input.CheckFields(Book{}); err != nil {
+			return nil, err
+		}
+		if err := input.CheckFilter(); err != nil {
+			return nil, err
+		}
+
 		booksMu.RLock()
 		defer booksMu.RUnlock()
 
@@ -171,20 +301,97 @@ func (s *APIServer) RegisterGetBook(api huma.API) {
 
 		resp := &GetBookResponse{
 			CacheControl: "max-age:0",
-			ETag:         b.Version(),
+			ETag:         formatETag(b.Version(), input.ETag),
 			LastModified: b.modified,
+			Link:         s.bookLinks(input.ID, b, true),
 			Vary:         "Accept, Accept-Encoding, Origin",
 			Body:         b,
 		}
+
+		if len(input.Embed) > 0 {
+			// Copy so the stored book is not modified.
+			embedded := *b
+			embedded.Embedded = &BookEmbedded{}
+			for _, name := range input.Embed {
+				switch name {
+				case "author":
+					if b.Author != "" {
+						embedded.Embedded.Author = findAuthor(authorID(b.Author))
+					}
+				case "reviews":
+					embedded.Embedded.Reviews = newRatingAggregate(b)
+				}
+			}
+			resp.Body = &embedded
+		}
+
 		return resp, nil
 	})
 }
 
+// BatchGetInput lists the books to fetch in a single request.
+type BatchGetInput struct {
+	IDs []string `json:"ids" minItems:"1" maxItems:"100" doc:"Book IDs to fetch"`
+}
+
+// BatchBook is a single book found by a batch get.
+type BatchBook struct {
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	Version string `json:"version"`
+	Book    *Book  `json:"book"`
+}
+
+// BatchGetResult contains the found books and the IDs which were not found,
+// each in request order.
+type BatchGetResult struct {
+	Items   []BatchBook `json:"items"`
+	Missing []string    `json:"missing"`
+}
+
+func (s *APIServer) RegisterBatchGetBooks(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "batch-get-books",
+		Method:      http.MethodPost,
+		Path:        s.prefix + "/books/batch-get",
+		Description: "Get multiple books at once. Partial success is not an error: IDs which are not found are listed in `missing` and duplicates are returned once.",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		Body BatchGetInput
+	}) (*struct{ Body BatchGetResult }, error) {
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		result := BatchGetResult{Items: []BatchBook{}, Missing: []string{}}
+		seen := map[string]bool{}
+		for _, id := range input.Body.IDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			b := books[id]
+			if b == nil {
+				result.Missing = append(result.Missing, id)
+				continue
+			}
+			result.Items = append(result.Items, BatchBook{
+				ID:      id,
+				URL:     s.prefix + "/books/" + id,
+				Version: b.Version(),
+				Book:    b,
+			})
+		}
+
+		return &struct{ Body BatchGetResult }{Body: result}, nil
+	})
+}
+
 func (s *APIServer) RegisterPutBook(api huma.API) {
 	huma.Register(api, huma.Operation{
 		OperationID: "put-book",
 		Method:      http.MethodPut,
-		Path:        "/books/{book-id}",
+		Path:        s.prefix + "/books/{book-id}",
 		Tags:        []string{"Books"},
 	}, func(ctx context.Context, input *struct {
 		conditional.Params
@@ -195,6 +402,9 @@ func (s *APIServer) RegisterPutBook(api huma.API) {
 		defer booksMu.Unlock()
 
 		if input.HasConditionalParams() {
+			if err := rejectWeakIfMatch(input.IfMatch); err != nil {
+				return nil, err
+			}
 			existing := books[input.ID]
 			if existing != nil {
 				if err := input.PreconditionFailed(existing.Version(), existing.modified); err != nil {
@@ -203,18 +413,7 @@ func (s *APIServer) RegisterPutBook(api huma.API) {
 			}
 		}
 
-		if books[input.ID] == nil {
-			booksOrder = append(booksOrder, input.ID)
-		}
-		input.Body.modified = time.Now()
-		books[input.ID] = &input.Body
-
-		// Limit the total number of books by deleting the oldest first. These will
-		// get reset periodically by the goroutine in `init()` above.
-		for len(books) > 20 {
-			delete(books, booksOrder[0])
-			booksOrder = booksOrder[1:]
-		}
+		storeBook(input.ID, &input.Body)
 
 		return nil, nil
 	})
@@ -224,7 +423,7 @@ func (s *APIServer) RegisterDeleteBook(api huma.API) {
 	huma.Register(api, huma.Operation{
 		OperationID: "delete-book",
 		Method:      http.MethodDelete,
-		Path:        "/books/{book-id}",
+		Path:        s.prefix + "/books/{book-id}",
 		Tags:        []string{"Books"},
 	}, func(ctx context.Context, input *struct {
 		conditional.Params
@@ -234,6 +433,9 @@ func (s *APIServer) RegisterDeleteBook(api huma.API) {
 		defer booksMu.Unlock()
 
 		if input.HasConditionalParams() {
+			if err := rejectWeakIfMatch(input.IfMatch); err != nil {
+				return nil, err
+			}
 			existing := books[input.ID]
 			if existing != nil {
 				if err := input.PreconditionFailed(existing.Version(), existing.modified); err != nil {
@@ -242,12 +444,91 @@ func (s *APIServer) RegisterDeleteBook(api huma.API) {
 			}
 		}
 
-		// Remove the book from both the map and the slice.
-		delete(books, input.ID)
-		if idx := slices.Index(booksOrder, input.ID); idx > -1 {
-			booksOrder = slices.Delete(booksOrder, idx, idx+1)
-		}
+		removeBook(input.ID)
 
 		return nil, nil
 	})
 }
+
+// maxRecentRatings limits how many of the latest ratings are kept per book.
+const maxRecentRatings = 5
+
+// RatingAggregate summarizes a book's ratings.
+type RatingAggregate struct {
+	Ratings       int      `json:"ratings"`
+	RatingAverage float64  `json:"rating_average"`
+	RecentRatings []Rating `json:"recent_ratings"`
+}
+
+func newRatingAggregate(b *Book) *RatingAggregate {
+	recent := b.RecentRatings
+	if recent == nil {
+		recent = []Rating{}
+	}
+	return &RatingAggregate{
+		Ratings:       b.Ratings,
+		RatingAverage: b.RatingAverage,
+		RecentRatings: recent,
+	}
+}
+
+func (s *APIServer) RegisterPostRating(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "post-book-rating",
+		Method:      http.MethodPost,
+		Path:        s.prefix + "/books/{book-id}/ratings",
+		Description: "Submit a new rating for a book, returning the updated rating aggregate.",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		ID   string `path:"book-id"`
+		Body struct {
+			Rating float64 `json:"rating" minimum:"0" maximum:"5" doc:"Rating from zero to five stars"`
+		}
+	}) (*struct{ Body RatingAggregate }, error) {
+		booksMu.Lock()
+		defer booksMu.Unlock()
+
+		existing := books[input.ID]
+		if existing == nil {
+			return nil, huma.Error404NotFound(input.ID + " not found")
+		}
+
+		// Replace rather than modify the book so responses being written for
+		// other requests are not affected.
+		b := *existing
+		b.RatingAverage = (b.RatingAverage*float64(b.Ratings) + input.Body.Rating) / float64(b.Ratings+1)
+		b.Ratings++
+		b.RecentRatings = append([]Rating{{Date: time.Now(), Rating: input.Body.Rating}}, b.RecentRatings...)
+		if len(b.RecentRatings) > maxRecentRatings {
+			b.RecentRatings = b.RecentRatings[:maxRecentRatings]
+		}
+		b.updated()
+		books[input.ID] = &b
+		recordRevision(input.ID, &b)
+		publishBookEvent(BookUpdatedEvent(newBookEvent(input.ID, &b)))
+
+		return &struct{ Body RatingAggregate }{Body: *newRatingAggregate(&b)}, nil
+	})
+}
+
+func (s *APIServer) RegisterGetRatings(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-book-ratings",
+		Method:      http.MethodGet,
+		Path:        s.prefix + "/books/{book-id}/ratings",
+		Description: "Get the rating aggregate for a book, which can also be embedded in the book via `?embed=reviews`.",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"book-id"`
+	}) (*struct{ Body RatingAggregate }, error) {
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		b := books[input.ID]
+		if b == nil {
+			return nil, huma.Error404NotFound(input.ID + " not found")
+		}
+
+		return &struct{ Body RatingAggregate }{Body: *newRatingAggregate(b)}, nil
+	})
+}
@@ -0,0 +1,254 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	breakerHealthy    = "healthy"
+	breakerDegraded   = "degraded"
+	breakerDown       = "down"
+	breakerRecovering = "recovering"
+)
+
+const (
+	// breakerDegradedDelay is how long successful requests take while
+	// degraded.
+	breakerDegradedDelay = 500 * time.Millisecond
+
+	// breakerRetryAfter is the `Retry-After` sent while down without a
+	// scheduled end.
+	breakerRetryAfter = 30 * time.Second
+
+	// maxBreakerPhase limits the duration of each scheduled phase.
+	maxBreakerPhase = time.Hour
+
+	// maxBreakers limits how many breakers are tracked. The least recently
+	// changed are removed first.
+	maxBreakers = 1000
+)
+
+// BreakerPhase is a state a breaker is in for a while.
+type BreakerPhase struct {
+	State    string `json:"state" enum:"healthy,degraded,down,recovering"`
+	Duration string `json:"duration" doc:"How long the phase lasts, as a duration like 30s, up to 1h"`
+}
+
+// breakerPhase is a parsed phase.
+type breakerPhase struct {
+	state    string
+	duration time.Duration
+}
+
+// breaker is a scripted outage. It's in each phase in turn from its start,
+// and healthy after the last unless that lasts forever.
+type breaker struct {
+	start    time.Time
+	phases   []breakerPhase
+	requests int
+	failures int
+}
+
+// current returns the breaker's state and when it changes, which is zero if
+// it never does.
+func (b *breaker) current(now time.Time) (string, time.Time) {
+	end := b.start
+	for _, p := range b.phases {
+		if p.duration == 0 {
+			return p.state, time.Time{}
+		}
+		end = end.Add(p.duration)
+		if now.Before(end) {
+			return p.state, end
+		}
+	}
+	return breakerHealthy, time.Time{}
+}
+
+// breakersMu protects the breakers.
+var breakersMu = sync.Mutex{}
+var breakers = map[string]*breaker{}
+var breakerOrder = []string{}
+
+// getBreaker returns the named breaker, creating one which is healthy until
+// changed. The breakers lock must be held.
+func getBreaker(name string) *breaker {
+	b := breakers[name]
+	if b == nil {
+		b = &breaker{start: time.Now()}
+		setBreaker(name, b)
+	}
+	return b
+}
+
+// setBreaker replaces the named breaker. The breakers lock must be held.
+func setBreaker(name string, b *breaker) {
+	breakers[name] = b
+	breakerOrder = slices.DeleteFunc(breakerOrder, func(v string) bool { return v == name })
+	breakerOrder = append(breakerOrder, name)
+	for len(breakerOrder) > maxBreakers {
+		delete(breakers, breakerOrder[0])
+		breakerOrder = breakerOrder[1:]
+	}
+}
+
+// BreakerStatus describes a breaker's current state.
+type BreakerStatus struct {
+	Name     string         `json:"name"`
+	State    string         `json:"state" enum:"healthy,degraded,down,recovering"`
+	Until    *time.Time     `json:"until,omitempty" doc:"When the state changes, if it's scheduled to"`
+	Schedule []BreakerPhase `json:"schedule,omitempty" doc:"Phases the breaker was scheduled to go through, after which it's healthy"`
+	Requests int            `json:"requests" doc:"Requests made since the state was last set"`
+	Failures int            `json:"failures" doc:"Requests which failed since the state was last set"`
+}
+
+// status describes the breaker. The breakers lock must be held.
+func (b *breaker) status(name string, now time.Time) BreakerStatus {
+	state, until := b.current(now)
+	s := BreakerStatus{Name: name, State: state, Requests: b.requests, Failures: b.failures}
+	if !until.IsZero() {
+		s.Until = &until
+	}
+	for _, p := range b.phases {
+		if p.duration > 0 {
+			s.Schedule = append(s.Schedule, BreakerPhase{State: p.state, Duration: p.duration.String()})
+		}
+	}
+	return s
+}
+
+type BreakerResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Body         BreakerStatus
+}
+
+func (s *APIServer) RegisterBreaker(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-breaker",
+		Method:      http.MethodGet,
+		Path:        "/breaker/{name}",
+		Description: fmt.Sprintf("Call a dependency whose health is scripted via `PUT /breaker/{name}/state`, to test client-side circuit breakers. While `healthy` every request succeeds. While `degraded` every other request fails with a `503` and the rest take %s. While `down` every request fails with a `503` and a `Retry-After` until the outage is scheduled to end, or %s otherwise. While `recovering` one in four requests fails. The current state is sent in `X-Breaker-State`.", breakerDegradedDelay, breakerRetryAfter),
+		Tags:        []string{"Breaker"},
+		Responses: map[string]*huma.Response{
+			"503": {Description: "Service Unavailable"},
+		},
+	}, func(ctx context.Context, input *struct {
+		RequestInfo
+		Name string `path:"name" pattern:"^[A-Za-z0-9_.-]+$" maxLength:"64"`
+	}) (*BreakerResponse, error) {
+		now := time.Now()
+
+		breakersMu.Lock()
+		b := getBreaker(input.Name)
+		state, until := b.current(now)
+		b.requests++
+		fail := false
+		switch state {
+		case breakerDegraded:
+			fail = b.requests%2 == 0
+		case breakerDown:
+			fail = true
+		case breakerRecovering:
+			fail = b.requests%4 == 0
+		}
+		if fail {
+			b.failures++
+		}
+		status := b.status(input.Name, now)
+		breakersMu.Unlock()
+
+		input.ctx.SetHeader("X-Breaker-State", state)
+		if fail {
+			input.ctx.SetHeader("Cache-Control", "no-store")
+			// Intermittent failures can be retried right away, while an outage
+			// lasts until it's scheduled to end.
+			retry := time.Second
+			if state == breakerDown {
+				retry = breakerRetryAfter
+				if !until.IsZero() {
+					retry = until.Sub(now)
+				}
+			}
+			input.ctx.SetHeader("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			return nil, huma.Error503ServiceUnavailable(fmt.Sprintf("%s is %s", input.Name, state))
+		}
+
+		if state == breakerDegraded {
+			select {
+			case <-ctx.Done():
+			case <-time.After(breakerDegradedDelay):
+			}
+		}
+		return &BreakerResponse{CacheControl: "no-store", Body: status}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-breaker-state",
+		Method:      http.MethodGet,
+		Path:        "/breaker/{name}/state",
+		Description: "Get a breaker's current state and schedule without counting as a request to it.",
+		Tags:        []string{"Breaker"},
+	}, func(ctx context.Context, input *struct {
+		Name string `path:"name" pattern:"^[A-Za-z0-9_.-]+$" maxLength:"64"`
+	}) (*BreakerResponse, error) {
+		breakersMu.Lock()
+		defer breakersMu.Unlock()
+		return &BreakerResponse{CacheControl: "no-store", Body: getBreaker(input.Name).status(input.Name, time.Now())}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-breaker-state",
+		Method:      http.MethodPut,
+		Path:        "/breaker/{name}/state",
+		Description: "Set a breaker's state, either toggling it to a single state until changed again, or scheduling an outage like `degraded` then `down` then `recovering` which starts now and ends with the breaker healthy. Setting the state resets the request counts.",
+		Tags:        []string{"Breaker"},
+	}, func(ctx context.Context, input *struct {
+		Name string `path:"name" pattern:"^[A-Za-z0-9_.-]+$" maxLength:"64"`
+		Body struct {
+			State    string         `json:"state,omitempty" enum:"healthy,degraded,down,recovering" doc:"State to stay in until changed"`
+			Schedule []BreakerPhase `json:"schedule,omitempty" maxItems:"20" doc:"Phases to go through in order, after which the breaker is healthy"`
+		}
+	}) (*BreakerResponse, error) {
+		if (input.Body.State == "") == (len(input.Body.Schedule) == 0) {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Location: "body",
+				Message:  "expected either a state or a schedule",
+			})
+		}
+
+		now := time.Now()
+		b := &breaker{start: now}
+		if input.Body.State != "" {
+			b.phases = []breakerPhase{{state: input.Body.State}}
+		}
+		errs := []error{}
+		for i, p := range input.Body.Schedule {
+			d, err := time.ParseDuration(p.Duration)
+			if err != nil || d <= 0 || d > maxBreakerPhase {
+				errs = append(errs, &huma.ErrorDetail{
+					Location: fmt.Sprintf("body.schedule[%d].duration", i),
+					Message:  "expected a duration greater than 0s and up to " + maxBreakerPhase.String(),
+					Value:    p.Duration,
+				})
+			}
+			b.phases = append(b.phases, breakerPhase{state: p.State, duration: d})
+		}
+		if len(errs) > 0 {
+			return nil, huma.Error422UnprocessableEntity("validation failed", errs...)
+		}
+
+		breakersMu.Lock()
+		defer breakersMu.Unlock()
+		setBreaker(input.Name, b)
+		return &BreakerResponse{CacheControl: "no-store", Body: b.status(input.Name, now)}, nil
+	})
+}
@@ -0,0 +1,318 @@
+package apibin
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// bookCSVHeader lists the columns used for CSV import & export. Recent ratings
+// are not included in CSV.
+var bookCSVHeader = []string{"id", "title", "author", "published", "ratings", "rating_average"}
+
+// ImportError describes a single row which could not be imported.
+type ImportError struct {
+	Row     int    `json:"row" doc:"Row number, starting at 1 for the first book"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult summarizes a bulk import.
+type ImportResult struct {
+	DryRun  bool          `json:"dry_run"`
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Errors  []ImportError `json:"errors"`
+}
+
+type importedBook struct {
+	id   string
+	book *Book
+}
+
+// validateImport checks an imported book, returning an error message or an
+// empty string if the book is valid.
+func validateImport(id string, b *Book) string {
+	switch {
+	case id == "":
+		return "id is required"
+	case b.Title == "":
+		return "title is required"
+	case b.Ratings < 0:
+		return "ratings must be >= 0"
+	case b.RatingAverage < 0 || b.RatingAverage > 5:
+		return "rating_average must be between 0 and 5"
+	}
+	return ""
+}
+
+// parseImportJSON decodes an object of book IDs to books one at a time, in
+// the same format as the export, reporting errors for each invalid book.
+func parseImportJSON(r io.Reader) ([]importedBook, []ImportError) {
+	imported := []importedBook{}
+	errs := []ImportError{}
+
+	dec := json.NewDecoder(r)
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, []ImportError{{Message: "expected a JSON object of book IDs to books"}}
+	}
+
+	for row := 1; dec.More(); row++ {
+		t, err := dec.Token()
+		if err != nil {
+			return imported, append(errs, ImportError{Row: row, Message: err.Error()})
+		}
+		id, _ := t.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return imported, append(errs, ImportError{Row: row, ID: id, Message: err.Error()})
+		}
+
+		b := &Book{}
+		if err := json.Unmarshal(raw, b); err != nil {
+			errs = append(errs, ImportError{Row: row, ID: id, Message: err.Error()})
+			continue
+		}
+		if msg := validateImport(id, b); msg != "" {
+			errs = append(errs, ImportError{Row: row, ID: id, Message: msg})
+			continue
+		}
+		imported = append(imported, importedBook{id: id, book: b})
+	}
+
+	return imported, errs
+}
+
+// parseImportCSV reads CSV rows with a header one at a time, reporting errors
+// for each invalid row.
+func parseImportCSV(r io.Reader) ([]importedBook, []ImportError) {
+	imported := []importedBook{}
+	errs := []ImportError{}
+
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, []ImportError{{Message: "expected a CSV header row: " + err.Error()}}
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"id", "title"} {
+		if _, ok := columns[name]; !ok {
+			return nil, []ImportError{{Message: "missing required column " + name}}
+		}
+	}
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, ImportError{Row: row, Message: err.Error()})
+			if _, ok := err.(*csv.ParseError); ok {
+				continue
+			}
+			break
+		}
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+
+		id := get("id")
+		b := &Book{Title: get("title"), Author: get("author")}
+		if v := get("published"); v != "" {
+			if b.Published, err = time.Parse(time.RFC3339, v); err != nil {
+				errs = append(errs, ImportError{Row: row, ID: id, Message: "invalid published date: " + err.Error()})
+				continue
+			}
+		}
+		if v := get("ratings"); v != "" {
+			if b.Ratings, err = strconv.Atoi(v); err != nil {
+				errs = append(errs, ImportError{Row: row, ID: id, Message: "invalid ratings: " + err.Error()})
+				continue
+			}
+		}
+		if v := get("rating_average"); v != "" {
+			if b.RatingAverage, err = strconv.ParseFloat(v, 64); err != nil {
+				errs = append(errs, ImportError{Row: row, ID: id, Message: "invalid rating_average: " + err.Error()})
+				continue
+			}
+		}
+		if msg := validateImport(id, b); msg != "" {
+			errs = append(errs, ImportError{Row: row, ID: id, Message: msg})
+			continue
+		}
+		imported = append(imported, importedBook{id: id, book: b})
+	}
+
+	return imported, errs
+}
+
+// snapshotBooks copies the stored books and their IDs in order.
+func snapshotBooks() ([]string, []Book) {
+	booksMu.RLock()
+	defer booksMu.RUnlock()
+	ids := append([]string{}, booksOrder...)
+	snapshot := make([]Book, 0, len(ids))
+	for _, id := range ids {
+		snapshot = append(snapshot, *books[id])
+	}
+	return ids, snapshot
+}
+
+// exportFlushEvery is how many books are written between flushes when
+// exporting, so large exports start arriving right away.
+const exportFlushEvery = 100
+
+func (s *APIServer) RegisterExportBooks(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "export-books",
+		Method:      http.MethodGet,
+		Path:        "/books/export",
+		Description: "Stream all books as a JSON object of book IDs to books, or as CSV without the recent ratings.",
+		Tags:        []string{"Books"},
+		Metadata:    map[string]any{streamingKey: true},
+	}, func(ctx context.Context, input *struct {
+		Format string `query:"format" enum:"json,csv" default:"json" doc:"Export format"`
+	}) (*huma.StreamResponse, error) {
+		// Snapshot the books so the lock isn't held while writing.
+		ids, snapshot := snapshotBooks()
+
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				ctx.SetHeader("Content-Disposition", `attachment; filename="books.`+input.Format+`"`)
+
+				if input.Format == "csv" {
+					ctx.SetHeader("Content-Type", "text/csv; charset=utf-8")
+					flusher, _ := ctx.BodyWriter().(http.Flusher)
+					w := csv.NewWriter(ctx.BodyWriter())
+					w.Write(bookCSVHeader)
+					for i, b := range snapshot {
+						if i > 0 && i%exportFlushEvery == 0 {
+							w.Flush()
+							if flusher != nil {
+								flusher.Flush()
+							}
+						}
+						published := ""
+						if !b.Published.IsZero() {
+							published = b.Published.Format(time.RFC3339)
+						}
+						w.Write([]string{
+							ids[i],
+							b.Title,
+							b.Author,
+							published,
+							strconv.Itoa(b.Ratings),
+							strconv.FormatFloat(b.RatingAverage, 'f', -1, 64),
+						})
+					}
+					w.Flush()
+					return
+				}
+
+				ctx.SetHeader("Content-Type", "application/json")
+				w := ctx.BodyWriter()
+				flusher, _ := w.(http.Flusher)
+				w.Write([]byte("{"))
+				for i, b := range snapshot {
+					if i > 0 {
+						w.Write([]byte(","))
+						if i%exportFlushEvery == 0 && flusher != nil {
+							flusher.Flush()
+						}
+					}
+					key, _ := json.Marshal(ids[i])
+					value, _ := json.Marshal(b)
+					w.Write([]byte("\n  "))
+					w.Write(key)
+					w.Write([]byte(": "))
+					w.Write(value)
+				}
+				w.Write([]byte("\n}\n"))
+			},
+		}, nil
+	})
+}
+
+func (s *APIServer) RegisterImportBooks(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "import-books",
+		Method:      http.MethodPost,
+		Path:        "/books/import",
+		Description: fmt.Sprintf("Create or replace up to %d books in bulk using the same formats as the export. Valid rows are imported even if others fail, unless `dry_run` is set, in which case nothing is changed. Later rows for the same ID replace earlier ones.", maxBooks),
+		Tags:        []string{"Books"},
+		RequestBody: &huma.RequestBody{
+			Required: true,
+			Content: map[string]*huma.MediaType{
+				"application/json": {Schema: &huma.Schema{Type: huma.TypeObject, Description: "Object of book IDs to books, see the PUT operation for the book schema."}},
+				"text/csv":         {Schema: &huma.Schema{Type: huma.TypeString, Description: "CSV with a header row of " + fmt.Sprint(bookCSVHeader)}},
+			},
+		},
+	}, func(ctx context.Context, input *struct {
+		ContentType string `header:"Content-Type"`
+		DryRun      bool   `query:"dry_run" doc:"Validate the import without changing anything"`
+		RawBody     []byte
+	}) (*struct{ Body ImportResult }, error) {
+		var imported []importedBook
+		var errs []ImportError
+
+		mediaType, _, _ := mime.ParseMediaType(input.ContentType)
+		switch mediaType {
+		case "application/json", "":
+			imported, errs = parseImportJSON(bytes.NewReader(input.RawBody))
+		case "text/csv":
+			imported, errs = parseImportCSV(bytes.NewReader(input.RawBody))
+		default:
+			return nil, huma.Error415UnsupportedMediaType("Content type should be one of application/json or text/csv")
+		}
+
+		// Importing more books than can be stored would evict some of them
+		// right away, so the whole import is rejected.
+		seen := map[string]bool{}
+		for _, item := range imported {
+			seen[item.id] = true
+		}
+		if len(seen) > maxBooks {
+			return nil, huma.Error422UnprocessableEntity(fmt.Sprintf("imports are limited to %d books but %d were given", maxBooks, len(seen)))
+		}
+
+		result := ImportResult{DryRun: input.DryRun, Errors: errs}
+
+		booksMu.Lock()
+		defer booksMu.Unlock()
+
+		// Books are counted against those stored plus any imported so far, so
+		// repeated IDs are only created once even in a dry run.
+		created := map[string]bool{}
+		for _, item := range imported {
+			if books[item.id] == nil && !created[item.id] {
+				created[item.id] = true
+				result.Created++
+			} else {
+				result.Updated++
+			}
+			if !input.DryRun {
+				storeBook(item.id, item.book)
+			}
+		}
+
+		return &struct{ Body ImportResult }{Body: result}, nil
+	})
+}
@@ -0,0 +1,190 @@
+package apibin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	transferChunked = "chunked"
+	transferLength  = "length"
+)
+
+// TransferParams lets the client pick how the response body is framed.
+type TransferParams struct {
+	Transfer string `query:"transfer" enum:"chunked,length" doc:"Force chunked transfer encoding or a precomputed Content-Length"`
+}
+
+// writeBytes writes `n` pseudo-random bytes generated from `seed` to the
+// response in chunks of `chunkSize`, using the requested transfer framing.
+// Chunked responses never set a length and are flushed after every chunk,
+// which forces chunked transfer encoding on HTTP/1.1. Compression would
+// change the framing, so the response is marked as not to be transformed.
+func writeBytes(ctx huma.Context, n, chunkSize int, seed int64, transfer string) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	ctx.SetHeader("Content-Type", "application/octet-stream")
+	ctx.SetHeader("Cache-Control", "no-store, no-transform")
+	if transfer == transferLength {
+		ctx.SetHeader("Content-Length", strconv.Itoa(n))
+	}
+	ctx.SetStatus(http.StatusOK)
+
+	w := ctx.BodyWriter()
+	flusher, _ := w.(http.Flusher)
+	chunk := make([]byte, chunkSize)
+	for remaining := n; remaining > 0; remaining -= len(chunk) {
+		if remaining < len(chunk) {
+			chunk = chunk[:remaining]
+		}
+		r.Read(chunk)
+		if _, err := w.Write(chunk); err != nil {
+			return
+		}
+		if transfer == transferChunked && flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if transfer == transferChunked && flusher != nil {
+		// Make sure even empty responses are sent without a length.
+		flusher.Flush()
+	}
+}
+
+func (s *APIServer) RegisterBytes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-bytes",
+		Method:      http.MethodGet,
+		Path:        "/bytes/{n}",
+		Description: "Get `n` random bytes. Sent with a `Content-Length` by default.",
+		Tags:        []string{"Binary"},
+		Metadata:    map[string]any{streamingKey: true},
+	}, func(ctx context.Context, input *struct {
+		N    int   `path:"n" minimum:"0" maximum:"104857600" doc:"Number of bytes to return"`
+		Seed int64 `query:"seed" doc:"Random seed for reproducible output"`
+		TransferParams
+	}) (*huma.StreamResponse, error) {
+		transfer := input.Transfer
+		if transfer == "" {
+			transfer = transferLength
+		}
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				writeBytes(ctx, input.N, 32*1024, input.Seed, transfer)
+			},
+		}, nil
+	})
+}
+
+func (s *APIServer) RegisterStreamBytes(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "stream-bytes",
+		Method:      http.MethodGet,
+		Path:        "/stream-bytes/{n}",
+		Description: "Stream `n` random bytes in chunks. Sent with chunked transfer encoding by default.",
+		Tags:        []string{"Binary"},
+		Metadata:    map[string]any{streamingKey: true},
+	}, func(ctx context.Context, input *struct {
+		N         int   `path:"n" minimum:"0" maximum:"104857600" doc:"Number of bytes to return"`
+		ChunkSize int   `query:"chunk-size" default:"10240" minimum:"1" maximum:"1048576" doc:"Size of each written chunk"`
+		Seed      int64 `query:"seed" doc:"Random seed for reproducible output"`
+		TransferParams
+	}) (*huma.StreamResponse, error) {
+		transfer := input.Transfer
+		if transfer == "" {
+			transfer = transferChunked
+		}
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				writeBytes(ctx, input.N, input.ChunkSize, input.Seed, transfer)
+			},
+		}, nil
+	})
+}
+
+// alphabetReader reads `size` bytes of the repeating lowercase alphabet. It
+// can seek anywhere without generating the data up front.
+type alphabetReader struct {
+	size   int64
+	offset int64
+}
+
+func (r *alphabetReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if remaining := r.size - r.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	for i := range p {
+		p[i] = 'a' + byte((r.offset+int64(i))%26)
+	}
+	r.offset += int64(len(p))
+	return len(p), nil
+}
+
+func (r *alphabetReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+type RangeResponse struct {
+	ETag         string `header:"ETag"`
+	CacheControl string `header:"Cache-Control"`
+	Body         func(ctx huma.Context)
+}
+
+func (s *APIServer) RegisterRange(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-range",
+		Method:      http.MethodGet,
+		Path:        "/range/{n}",
+		Description: "Get `n` bytes of the repeating lowercase alphabet, so any part of the response is easy to check. Supports `Range` requests, including `If-Range`. Multiple ranges get a `multipart/byteranges` response with a part for each range, unless they overlap too much or add up to more than the whole response, in which case everything is sent.",
+		Tags:        []string{"Binary"},
+		Metadata:    map[string]any{streamingKey: true},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "OK",
+				Content: map[string]*huma.MediaType{
+					"application/octet-stream": {Schema: &huma.Schema{Type: huma.TypeString, Format: "binary"}},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *struct {
+		N int64 `path:"n" minimum:"1" maximum:"104857600" doc:"Number of bytes to return"`
+	}) (*RangeResponse, error) {
+		return &RangeResponse{
+			ETag:         `"range-` + strconv.FormatInt(input.N, 10) + `"`,
+			CacheControl: "public, max-age=3600, no-transform",
+			Body: func(ctx huma.Context) {
+				ctx.SetHeader("Content-Type", "application/octet-stream")
+				content := &alphabetReader{size: input.N}
+				if w, ok := ctx.BodyWriter().(http.ResponseWriter); ok {
+					http.ServeContent(w, newServeContentRequest(ctx), "", staticModified, content)
+					return
+				}
+				io.Copy(ctx.BodyWriter(), content)
+			},
+		}, nil
+	})
+}
@@ -0,0 +1,285 @@
+package apibin
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// cacheSecondsDirectives take a `delta-seconds` argument.
+var cacheSecondsDirectives = map[string]bool{
+	"max-age":                true,
+	"s-maxage":               true,
+	"max-stale":              true,
+	"min-fresh":              true,
+	"stale-while-revalidate": true,
+	"stale-if-error":         true,
+}
+
+// isToken returns whether a string is a valid RFC 9110 token.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isQuotedString returns whether a string is a valid RFC 9110 quoted string.
+func isQuotedString(s string) bool {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return false
+	}
+	escaped := false
+	for _, r := range s[1 : len(s)-1] {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"' || r < 0x20 && r != '\t' || r == 0x7f:
+			return false
+		}
+	}
+	return !escaped
+}
+
+// CacheDirective is a single parsed `Cache-Control` directive.
+type CacheDirective struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// parseCacheControl parses and validates a comma-separated list of
+// `Cache-Control` directives.
+func parseCacheControl(value string) ([]CacheDirective, error) {
+	directives := []CacheDirective{}
+	for _, part := range splitQuoted(value, ',') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, arg, hasArg := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		arg = strings.TrimSpace(arg)
+		if !isToken(name) {
+			return nil, fmt.Errorf("invalid directive name %q", name)
+		}
+		if hasArg && !isToken(arg) && !isQuotedString(arg) {
+			return nil, fmt.Errorf("invalid argument for %s, expected a token or quoted string", name)
+		}
+		if cacheSecondsDirectives[strings.ToLower(name)] {
+			if _, err := strconv.ParseUint(arg, 10, 31); err != nil && (!strings.EqualFold(name, "max-stale") || hasArg) {
+				return nil, fmt.Errorf("invalid argument for %s, expected a number of seconds", name)
+			}
+		}
+		directives = append(directives, CacheDirective{Name: name, Value: arg})
+	}
+	if len(directives) == 0 {
+		return nil, errors.New("at least one directive is required")
+	}
+	return directives, nil
+}
+
+// CacheControlModel describes a response with custom cache directives.
+type CacheControlModel struct {
+	Generated    time.Time        `json:"generated" doc:"Time when this response was generated"`
+	CacheControl string           `json:"cache_control" doc:"The Cache-Control header value sent"`
+	Directives   []CacheDirective `json:"directives"`
+}
+
+type CacheControlResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Date         string `header:"Date"`
+	Expires      string `header:"Expires"`
+	Body         CacheControlModel
+}
+
+func (s *APIServer) RegisterCacheControl(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-cache-control",
+		Method:      http.MethodGet,
+		Path:        "/cache/control",
+		Description: "Respond with exactly the requested `Cache-Control` directives, after checking they are valid. Directive names keep their case, since clients must treat them case-insensitively. Any which take a number of seconds, like `max-age`, must have one.",
+		Tags:        []string{"Caching"},
+	}, func(ctx context.Context, input *struct {
+		Directives string `query:"directives" required:"true" example:"no-cache, max-age=60, private" doc:"Comma-separated Cache-Control directives"`
+		ExpiresParams
+	}) (*CacheControlResponse, error) {
+		directives, err := parseCacheControl(input.Directives)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Message:  err.Error(),
+				Location: "query.directives",
+				Value:    input.Directives,
+			})
+		}
+
+		parts := make([]string, len(directives))
+		for i, d := range directives {
+			parts[i] = d.Name
+			if d.Value != "" {
+				parts[i] += "=" + d.Value
+			}
+		}
+		header := strings.Join(parts, ", ")
+
+		now := clockNow(ctx)
+		date, expires := input.headers(now)
+		return &CacheControlResponse{
+			CacheControl: header,
+			Date:         date,
+			Expires:      expires,
+			Body: CacheControlModel{
+				Generated:    now,
+				CacheControl: header,
+				Directives:   directives,
+			},
+		}, nil
+	})
+}
+
+// ExpiresParams adds a legacy `Expires` header to a response.
+type ExpiresParams struct {
+	Expires  int `query:"expires" minimum:"-86400" maximum:"31536000" doc:"Send an Expires header this many seconds after the Date header. Negative values send an already expired response. Zero omits it."`
+	DateSkew int `query:"date-skew" minimum:"-86400" maximum:"86400" doc:"Shift the Date header by this many seconds to simulate a server with a wrong clock. Expires is shifted too, so only clients computing freshness from Expires minus Date get the intended lifetime."`
+}
+
+// headers returns the `Date` and `Expires` header values to send, which are
+// empty if they should be left alone.
+func (p ExpiresParams) headers(now time.Time) (string, string) {
+	if p.Expires == 0 && p.DateSkew == 0 {
+		return "", ""
+	}
+	date := now.Add(time.Duration(p.DateSkew) * time.Second)
+	expires := ""
+	if p.Expires != 0 {
+		expires = date.Add(time.Duration(p.Expires) * time.Second).UTC().Format(http.TimeFormat)
+	}
+	return date.UTC().Format(http.TimeFormat), expires
+}
+
+// maxCDNEntries limits how many keys the simulated CDN caches. The oldest are
+// removed first.
+const maxCDNEntries = 1000
+
+// cdnEntry is a response stored by the simulated CDN.
+type cdnEntry struct {
+	stored time.Time
+	ttl    int
+	body   CDNModel
+}
+
+// cdnMu protects the simulated CDN cache.
+var cdnMu = sync.Mutex{}
+var cdnEntries = map[string]*cdnEntry{}
+var cdnOrder = []string{}
+
+// CDNModel is the body cached by the simulated CDN.
+type CDNModel struct {
+	Key       string    `json:"key"`
+	Generated time.Time `json:"generated" doc:"When the origin generated this response"`
+	ID        string    `json:"id" doc:"Random ID which stays the same until the cached response expires"`
+}
+
+type CDNResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Age          string `header:"Age"`
+	CacheStatus  string `header:"Cache-Status"`
+	XCache       string `header:"X-Cache"`
+	Body         CDNModel
+}
+
+func (s *APIServer) RegisterCacheCDN(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-cache-cdn",
+		Method:      http.MethodGet,
+		Path:        "/cache/cdn",
+		Description: "Pretend to be a shared cache in front of the origin. The first request for a key is a miss which stores the response, and later requests are hits with the same body and an increasing `Age` until the TTL runs out. Hits and misses are reported via `Cache-Status` and `X-Cache`.",
+		Tags:        []string{"Caching"},
+	}, func(ctx context.Context, input *struct {
+		Key string `query:"key" default:"default" maxLength:"256" doc:"Cache key, each of which is cached separately"`
+		TTL int    `query:"ttl" default:"60" minimum:"1" maximum:"86400" doc:"Seconds the response stays fresh in the simulated cache"`
+	}) (*CDNResponse, error) {
+		now := time.Now()
+
+		cdnMu.Lock()
+		defer cdnMu.Unlock()
+
+		entry := cdnEntries[input.Key]
+		hit := entry != nil && now.Before(entry.stored.Add(time.Duration(entry.ttl)*time.Second))
+		if !hit {
+			b := make([]byte, 8)
+			if _, err := crand.Read(b); err != nil {
+				return nil, huma.Error500InternalServerError("unable to read random bytes", err)
+			}
+			if entry == nil {
+				cdnOrder = append(cdnOrder, input.Key)
+				for len(cdnOrder) > maxCDNEntries {
+					delete(cdnEntries, cdnOrder[0])
+					cdnOrder = cdnOrder[1:]
+				}
+			}
+			entry = &cdnEntry{
+				stored: now,
+				ttl:    input.TTL,
+				body:   CDNModel{Key: input.Key, Generated: now, ID: hex.EncodeToString(b)},
+			}
+			cdnEntries[input.Key] = entry
+		}
+
+		age := int(now.Sub(entry.stored) / time.Second)
+		resp := &CDNResponse{
+			CacheControl: fmt.Sprintf("public, max-age=%d", entry.ttl),
+			Age:          strconv.Itoa(age),
+			CacheStatus:  "apibin; fwd=miss; stored",
+			XCache:       "MISS",
+			Body:         entry.body,
+		}
+		if hit {
+			resp.CacheStatus = fmt.Sprintf("apibin; hit; ttl=%d", entry.ttl-age)
+			resp.XCache = "HIT"
+		}
+		return resp, nil
+	})
+}
+
+type NotModifiedResponse struct {
+	ETag         string `header:"ETag"`
+	LastModified string `header:"Last-Modified"`
+	CacheControl string `header:"Cache-Control"`
+}
+
+func (s *APIServer) RegisterCacheNotModified(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "get-cache-not-modified",
+		Method:        http.MethodGet,
+		Path:          "/cache/not-modified",
+		Description:   "Always respond `304 Not Modified` with validators but no body, regardless of any conditional request headers. Clients should reuse a stored response, and handle having none to reuse.",
+		Tags:          []string{"Caching"},
+		DefaultStatus: http.StatusNotModified,
+	}, func(ctx context.Context, input *struct {
+		ETag string `query:"etag" default:"\"apibin\"" doc:"ETag header value to send"`
+	}) (*NotModifiedResponse, error) {
+		lastModified, _ := time.Parse(time.RFC3339, "2022-02-01T12:34:56Z")
+		return &NotModifiedResponse{
+			ETag:         input.ETag,
+			LastModified: lastModified.Format(http.TimeFormat),
+			CacheControl: "max-age=60",
+		}, nil
+	})
+}
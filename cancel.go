@@ -0,0 +1,163 @@
+package apibin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// maxCancelAfter limits how long `/cancel` works before responding.
+const maxCancelAfter = 5 * time.Minute
+
+// maxCancelRecent is how many finished requests `/cancel/stats` lists.
+const maxCancelRecent = 50
+
+// CancelOutcome describes how a request to `/cancel` ended.
+type CancelOutcome struct {
+	ID        int64     `json:"id"`
+	Client    string    `json:"client,omitempty"`
+	Started   time.Time `json:"started"`
+	Requested string    `json:"requested" doc:"How long the request asked the server to work"`
+	Elapsed   string    `json:"elapsed" doc:"How long the server worked before completing or noticing the client was gone"`
+	Outcome   string    `json:"outcome" enum:"completed,canceled,in-progress"`
+}
+
+// CancelStats counts requests to `/cancel` by outcome.
+type CancelStats struct {
+	Started    int64           `json:"started"`
+	Completed  int64           `json:"completed"`
+	Canceled   int64           `json:"canceled" doc:"Requests where the client disconnected before the work completed"`
+	InProgress int64           `json:"in_progress"`
+	Recent     []CancelOutcome `json:"recent" doc:"Most recent requests first, including those in progress"`
+}
+
+// cancelTracker records the outcome of each request to `/cancel`.
+var cancelTracker = struct {
+	sync.Mutex
+	stats  CancelStats
+	recent []*CancelOutcome
+}{}
+
+// startCancel records a new request, returning its outcome to update.
+func startCancel(client string, after time.Duration) *CancelOutcome {
+	cancelTracker.Lock()
+	defer cancelTracker.Unlock()
+
+	cancelTracker.stats.Started++
+	cancelTracker.stats.InProgress++
+	outcome := &CancelOutcome{
+		ID:        cancelTracker.stats.Started,
+		Client:    client,
+		Started:   time.Now().UTC(),
+		Requested: after.String(),
+		Outcome:   "in-progress",
+	}
+	cancelTracker.recent = append(cancelTracker.recent, outcome)
+	if len(cancelTracker.recent) > maxCancelRecent {
+		cancelTracker.recent = cancelTracker.recent[1:]
+	}
+	return outcome
+}
+
+// finishCancel records how a request ended and logs it.
+func finishCancel(outcome *CancelOutcome, canceled bool) {
+	cancelTracker.Lock()
+	defer cancelTracker.Unlock()
+
+	cancelTracker.stats.InProgress--
+	outcome.Elapsed = time.Since(outcome.Started).Round(time.Millisecond).String()
+	outcome.Outcome = "completed"
+	if canceled {
+		outcome.Outcome = "canceled"
+		cancelTracker.stats.Canceled++
+		fmt.Fprintf(os.Stderr, "cancel %d: client %s disconnected after %s of %s\n", outcome.ID, outcome.Client, outcome.Elapsed, outcome.Requested)
+	} else {
+		cancelTracker.stats.Completed++
+		fmt.Fprintf(os.Stderr, "cancel %d: completed %s for client %s\n", outcome.ID, outcome.Requested, outcome.Client)
+	}
+}
+
+type CancelStatsResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Body         CancelStats
+}
+
+func (s *APIServer) RegisterCancel(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "cancel",
+		Method:      http.MethodGet,
+		Path:        "/cancel",
+		Description: "Work for the given duration before responding, noticing if the client disconnects first. The status & headers, including the `X-Cancel-Id` to look up at `/cancel/stats`, are sent right away and the body once the work completes. Use it to verify that a client's timeout or abort actually closes the request rather than just ignoring the response.",
+		Tags:        []string{"Cancel"},
+		Metadata:    map[string]any{streamingKey: true},
+	}, func(ctx context.Context, input *struct {
+		After string `query:"after" default:"10s" doc:"How long to work before responding, as a duration like 500ms or 10s, up to 5m"`
+	}) (*huma.StreamResponse, error) {
+		after, err := time.ParseDuration(input.After)
+		if err != nil || after < 0 || after > maxCancelAfter {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Location: "query.after",
+				Message:  "expected a duration from 0s to " + maxCancelAfter.String(),
+				Value:    input.After,
+			})
+		}
+
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				outcome := startCancel(GetClientInfo(ctx.Context()).IP, after)
+				ctx.SetHeader("Content-Type", "application/json")
+				ctx.SetHeader("Cache-Control", "no-store")
+				ctx.SetHeader("X-Cancel-Id", strconv.FormatInt(outcome.ID, 10))
+				ctx.SetStatus(http.StatusOK)
+				if f, ok := ctx.BodyWriter().(http.Flusher); ok {
+					f.Flush()
+				}
+
+				select {
+				case <-ctx.Context().Done():
+					finishCancel(outcome, true)
+					return
+				case <-time.After(after):
+				}
+				finishCancel(outcome, false)
+
+				cancelTracker.Lock()
+				result := *outcome
+				cancelTracker.Unlock()
+				json.NewEncoder(ctx.BodyWriter()).Encode(result)
+			},
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-cancel-stats",
+		Method:      http.MethodGet,
+		Path:        "/cancel/stats",
+		Description: "Get how many requests to `/cancel` completed or were canceled by the client, along with the most recent ones.",
+		Tags:        []string{"Cancel"},
+	}, func(ctx context.Context, input *struct {
+		ID int64 `query:"id" doc:"Only list the request with this X-Cancel-Id"`
+	}) (*CancelStatsResponse, error) {
+		cancelTracker.Lock()
+		defer cancelTracker.Unlock()
+
+		stats := cancelTracker.stats
+		stats.Recent = []CancelOutcome{}
+		for i := len(cancelTracker.recent) - 1; i >= 0; i-- {
+			if o := cancelTracker.recent[i]; input.ID == 0 || o.ID == input.ID {
+				stats.Recent = append(stats.Recent, *o)
+			}
+		}
+		if input.ID != 0 && len(stats.Recent) == 0 {
+			return nil, huma.Error404NotFound(fmt.Sprintf("request %d not found, only the last %d are kept", input.ID, maxCancelRecent))
+		}
+		return &CancelStatsResponse{CacheControl: "no-store", Body: stats}, nil
+	})
+}
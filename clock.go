@@ -0,0 +1,73 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+)
+
+// maxClockSkew limits how far the server's clock can appear to be off.
+const maxClockSkew = 24 * time.Hour
+
+var clockSkewKey contextKey = "apibin/clock-skew"
+
+// parseClockSkew parses a clock skew like `-300s` or `1h`, which must be
+// within `maxClockSkew` in either direction.
+func parseClockSkew(value string) (time.Duration, error) {
+	skew, err := time.ParseDuration(value)
+	if err != nil || skew < -maxClockSkew || skew > maxClockSkew {
+		return 0, &huma.ErrorDetail{
+			Location: "query.clock-skew",
+			Message:  "expected a duration from -" + maxClockSkew.String() + " to " + maxClockSkew.String(),
+			Value:    value,
+		}
+	}
+	return skew, nil
+}
+
+// GetClockSkew returns how far the server's clock appears to be off for a
+// request, which is zero if the `ClockSkew` middleware did not run.
+func GetClockSkew(ctx context.Context) time.Duration {
+	skew, _ := ctx.Value(clockSkewKey).(time.Duration)
+	return skew
+}
+
+// clockNow returns the current time as it appears to a request's client,
+// which should be used for the `Date` header & freshness timestamps.
+func clockNow(ctx context.Context) time.Time {
+	return time.Now().Add(GetClockSkew(ctx))
+}
+
+// ClockSkew makes the server's clock appear off by the server-wide skew, or
+// the `?clock-skew=` of a request, by shifting the `Date` header along with
+// freshness timestamps that use `clockNow`. The skew is reported via
+// `X-Apibin-Clock-Skew` so it isn't mistaken for a real clock problem. The
+// API is passed by reference since the middleware is created before it.
+func ClockSkew(api *huma.API, server time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			skew := server
+			if value := r.URL.Query().Get("clock-skew"); value != "" {
+				var err error
+				if skew, err = parseClockSkew(value); err != nil {
+					ctx := humachi.NewContext(nil, r, w)
+					huma.WriteErr(*api, ctx, http.StatusUnprocessableEntity, "validation failed", err)
+					return
+				}
+			}
+			if skew == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// The server only sets `Date` when it's missing, so this one is kept
+			// unless an operation sets its own.
+			w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+			w.Header().Set("X-Apibin-Clock-Skew", skew.String())
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clockSkewKey, skew)))
+		})
+	}
+}
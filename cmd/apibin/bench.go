@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// benchPaths are requested in turn when benchmarking an apibin server, which
+// covers JSON, binary, and conditional responses.
+var benchPaths = []string{"/types", "/books", "/books/sapiens", "/example", "/cached/60"}
+
+// benchAccept are sent in turn to exercise content negotiation.
+var benchAccept = []string{"application/json", "application/cbor", "application/yaml", "*/*"}
+
+// benchResults collects the outcome of each request.
+type benchResults struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    map[string]int
+	bytes     int64
+	dropped   atomic.Int64
+}
+
+func (r *benchResults) record(latency time.Duration, status int, n int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.errors[err.Error()]++
+		return
+	}
+	r.latencies = append(r.latencies, latency)
+	r.statuses[status]++
+	r.bytes += n
+}
+
+// percentile returns the latency at the given quantile of sorted latencies.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+// print writes a summary of the results.
+func (r *benchResults) print(w io.Writer, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	total := len(r.latencies)
+	for _, n := range r.errors {
+		total += n
+	}
+
+	fmt.Fprintf(w, "Requests:   %d in %s (%.1f/s)\n", total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds())
+	fmt.Fprintf(w, "Received:   %d bytes\n", r.bytes)
+	if dropped := r.dropped.Load(); dropped > 0 {
+		fmt.Fprintf(w, "Dropped:    %d, increase --concurrency to reach the target rate\n", dropped)
+	}
+
+	codes := make([]int, 0, len(r.statuses))
+	for code := range r.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	statuses := []string{}
+	for _, code := range codes {
+		statuses = append(statuses, fmt.Sprintf("%d=%d", code, r.statuses[code]))
+	}
+	fmt.Fprintf(w, "Statuses:   %s\n", strings.Join(statuses, " "))
+	for msg, n := range r.errors {
+		fmt.Fprintf(w, "Error:      %s (%d)\n", msg, n)
+	}
+
+	fmt.Fprintln(w, "Latency:")
+	for _, p := range []struct {
+		name string
+		q    float64
+	}{{"p50", 0.5}, {"p90", 0.9}, {"p95", 0.95}, {"p99", 0.99}, {"max", 1}} {
+		fmt.Fprintf(w, "  %-4s %s\n", p.name, percentile(r.latencies, p.q).Round(time.Microsecond))
+	}
+}
+
+// benchTarget generates requests, remembering ETags so that repeat requests
+// for the same URL and format are conditional.
+type benchTarget struct {
+	urls  []string
+	next  atomic.Int64
+	mu    sync.Mutex
+	etags map[string]string
+}
+
+// newBenchTarget creates a target for a URL. A URL without a path drives the
+// built-in mix of apibin operations, otherwise only the given URL is used.
+func newBenchTarget(target string) (*benchTarget, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid target %q, expected an absolute http or https URL", target)
+	}
+
+	t := &benchTarget{etags: map[string]string{}}
+	if u.Path == "" || u.Path == "/" {
+		base := strings.TrimSuffix(u.String(), "/")
+		for _, p := range benchPaths {
+			t.urls = append(t.urls, base+p)
+		}
+	} else {
+		t.urls = []string{u.String()}
+	}
+	return t, nil
+}
+
+// request makes the next request in the rotation. Every other request for a
+// URL and format with a known ETag sends `If-None-Match`.
+func (t *benchTarget) request(ctx context.Context, client *http.Client) (int, int64, error) {
+	i := t.next.Add(1) - 1
+	u := t.urls[i%int64(len(t.urls))]
+	accept := benchAccept[(i/int64(len(t.urls)))%int64(len(benchAccept))]
+	key := accept + " " + u
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	req.Header.Set("User-Agent", "apibin-bench")
+
+	t.mu.Lock()
+	etag := t.etags[key]
+	t.mu.Unlock()
+	if etag != "" && i%2 == 0 {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return 0, n, err
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		t.mu.Lock()
+		t.etags[key] = etag
+		t.mu.Unlock()
+	}
+	return resp.StatusCode, n, nil
+}
+
+// benchCommand generates load against an apibin server or any other URL and
+// reports latency percentiles.
+func benchCommand() *cobra.Command {
+	var target string
+	var rps int
+	var duration time.Duration
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Generate load against a server and report latencies",
+		Long:  "Send requests at a fixed rate and report latency percentiles. Without a path in the target URL, a mix of apibin operations is requested using each supported format and conditional requests with previously seen ETags. Otherwise only the target URL is requested.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if rps < 1 || concurrency < 1 || duration <= 0 {
+				fmt.Fprintln(os.Stderr, "The rate, concurrency, and duration must be positive")
+				os.Exit(1)
+			}
+			t, err := newBenchTarget(target)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			client := &http.Client{
+				Timeout: 30 * time.Second,
+				Transport: &http.Transport{
+					Proxy:               http.ProxyFromEnvironment,
+					MaxIdleConnsPerHost: concurrency,
+					// Compression is negotiated explicitly and not decoded.
+					DisableCompression: true,
+				},
+			}
+
+			fmt.Fprintf(os.Stderr, "Sending %d requests/s to %s for %s\n", rps, target, duration)
+
+			results := &benchResults{statuses: map[int]int{}, errors: map[string]int{}}
+			slots := make(chan struct{}, concurrency)
+			wg := sync.WaitGroup{}
+			ctx, cancel := context.WithTimeout(cmd.Context(), duration)
+			defer cancel()
+
+			start := time.Now()
+			ticker := time.NewTicker(time.Second / time.Duration(rps))
+			defer ticker.Stop()
+		loop:
+			for {
+				select {
+				case <-ctx.Done():
+					break loop
+				case <-ticker.C:
+					select {
+					case slots <- struct{}{}:
+					default:
+						results.dropped.Add(1)
+						continue
+					}
+					wg.Add(1)
+					go func() {
+						defer func() {
+							<-slots
+							wg.Done()
+						}()
+						started := time.Now()
+						status, n, err := t.request(context.Background(), client)
+						results.record(time.Since(started), status, n, err)
+					}()
+				}
+			}
+			wg.Wait()
+
+			results.print(os.Stdout, time.Since(start))
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "target", "http://localhost:8888", "Base URL of an apibin server, or any URL to request")
+	cmd.Flags().IntVar(&rps, "rps", 100, "Requests per second")
+	cmd.Flags().DurationVar(&duration, "duration", 30*time.Second, "How long to send requests")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 50, "Maximum requests in flight")
+
+	return cmd
+}
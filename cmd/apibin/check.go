@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/apibin"
+	"github.com/spf13/cobra"
+)
+
+// checkCase is a single request made by the `check` command along with what
+// the response must look like.
+type checkCase struct {
+	group  string
+	method string
+	path   string
+	header map[string]string
+	body   string
+
+	status      int
+	contentType string
+	encoding    string
+	requires    string // a header which must be present
+
+	// conditional repeats the request with the returned `ETag` in
+	// `If-None-Match` and expects a `304 Not Modified`.
+	conditional bool
+}
+
+// checkCases cover each endpoint group. Only requests which don't change any
+// state are made, so it's safe to run against shared servers.
+var checkCases = []checkCase{
+	{group: "Docs", method: http.MethodGet, path: "/openapi.json", status: http.StatusOK, contentType: "application/vnd.oai.openapi+json"},
+	{group: "Docs", method: http.MethodGet, path: "/docs", status: http.StatusOK, contentType: "text/html"},
+	{group: "Types", method: http.MethodGet, path: "/types", status: http.StatusOK, contentType: "application/json"},
+	{group: "Types", method: http.MethodGet, path: "/types", header: map[string]string{"Accept": "application/cbor"}, status: http.StatusOK, contentType: "application/cbor"},
+	{group: "Types", method: http.MethodGet, path: "/types", header: map[string]string{"Accept": "application/yaml"}, status: http.StatusOK, contentType: "application/yaml"},
+	{group: "Types", method: http.MethodHead, path: "/types", status: http.StatusOK, requires: "Content-Length"},
+	{group: "Types", method: http.MethodGet, path: "/types/binary", status: http.StatusOK, contentType: "application/json"},
+	{group: "Types", method: http.MethodGet, path: "/types/maps", status: http.StatusOK, contentType: "application/json"},
+	{group: "Types", method: http.MethodGet, path: "/types/array", header: map[string]string{"Accept": "application/x-ndjson"}, status: http.StatusOK, contentType: "application/x-ndjson"},
+	{group: "Example", method: http.MethodGet, path: "/example", status: http.StatusOK, contentType: "application/json", requires: "ETag", conditional: true},
+	{group: "Example", method: http.MethodGet, path: "/example", header: map[string]string{"Accept-Encoding": "gzip"}, status: http.StatusOK, encoding: "gzip"},
+	{group: "Example", method: http.MethodGet, path: "/example", header: map[string]string{"Accept-Encoding": "br"}, status: http.StatusOK, encoding: "br"},
+	{group: "Echo", method: http.MethodPost, path: "/", header: map[string]string{"Content-Type": "application/json"}, body: `{"hello":"world"}`, status: http.StatusOK, contentType: "application/json"},
+	{group: "Echo", method: http.MethodGet, path: "/trace-echo", status: http.StatusOK, contentType: "message/http"},
+	{group: "Echo", method: http.MethodGet, path: "/template-echo?template=%7B%7Bmethod%7D%7D&content_type=text/plain", status: http.StatusOK, contentType: "text/plain"},
+	{group: "Books", method: http.MethodGet, path: "/books", status: http.StatusOK, contentType: "application/json", conditional: true},
+	{group: "Books", method: http.MethodGet, path: "/books/sapiens", status: http.StatusOK, contentType: "application/json", conditional: true},
+	{group: "Books", method: http.MethodGet, path: "/books/does-not-exist", status: http.StatusNotFound, contentType: "application/problem+json"},
+	{group: "Books", method: http.MethodGet, path: "/books/stats", status: http.StatusOK, contentType: "application/json"},
+	{group: "Books", method: http.MethodOptions, path: "/books/sapiens", status: http.StatusNoContent, requires: "Allow"},
+	{group: "Books", method: http.MethodGet, path: "/v1/books", status: http.StatusOK, contentType: "application/json"},
+	{group: "Books", method: http.MethodGet, path: "/v2/books", status: http.StatusOK, contentType: "application/json"},
+	{group: "Books", method: http.MethodGet, path: "/reports/books.csv", status: http.StatusOK, contentType: "text/csv", requires: "Content-Disposition"},
+	{group: "Books", method: http.MethodGet, path: "/reports/books.xlsx", status: http.StatusOK, contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	{group: "Cancel", method: http.MethodGet, path: "/cancel?after=0s", status: http.StatusOK, contentType: "application/json", requires: "X-Cancel-Id"},
+	{group: "Timeout", method: http.MethodGet, path: "/timeout?after=0s", status: http.StatusGatewayTimeout, contentType: "application/problem+json"},
+	{group: "Flaky", method: http.MethodGet, path: "/flaky?failures=0", status: http.StatusOK, contentType: "application/json", requires: "X-Flaky-Attempt"},
+	{group: "Breaker", method: http.MethodGet, path: "/breaker/check/state", status: http.StatusOK, contentType: "application/json"},
+	{group: "Authors", method: http.MethodGet, path: "/authors", status: http.StatusOK, contentType: "application/json"},
+	{group: "Images", method: http.MethodGet, path: "/images", status: http.StatusOK, contentType: "application/json", requires: "Link"},
+	{group: "Images", method: http.MethodGet, path: "/images/png", status: http.StatusOK, contentType: "image/png", conditional: true},
+	{group: "Images", method: http.MethodGet, path: "/images/random?seed=1&format=png", status: http.StatusOK, contentType: "image/png", conditional: true},
+	{group: "Images", method: http.MethodGet, path: "/qr?data=apibin", status: http.StatusOK, contentType: "image/png"},
+	{group: "Binary", method: http.MethodGet, path: "/bytes/16", status: http.StatusOK, contentType: "application/octet-stream"},
+	{group: "Binary", method: http.MethodGet, path: "/range/100", header: map[string]string{"Range": "bytes=0-9"}, status: http.StatusPartialContent, requires: "Content-Range"},
+	{group: "Binary", method: http.MethodGet, path: "/static/index.html", status: http.StatusOK, contentType: "text/html", conditional: true},
+	{group: "Caching", method: http.MethodGet, path: "/cached/60", status: http.StatusOK, requires: "Cache-Control"},
+	{group: "Caching", method: http.MethodGet, path: "/cache/control?directives=max-age%3D60", status: http.StatusOK, requires: "Cache-Control"},
+	{group: "Caching", method: http.MethodGet, path: "/cache/not-modified", status: http.StatusNotModified, requires: "ETag"},
+	{group: "Negotiation", method: http.MethodGet, path: "/negotiation/strict", header: map[string]string{"Accept": "application/x-unknown"}, status: http.StatusNotAcceptable},
+	{group: "Negotiation", method: http.MethodGet, path: "/negotiation/debug", status: http.StatusOK, contentType: "application/json"},
+	{group: "Generate", method: http.MethodGet, path: "/generate/people?seed=1", status: http.StatusOK, contentType: "application/json"},
+	{group: "Generate", method: http.MethodGet, path: "/generate/text", status: http.StatusOK},
+	{group: "Redirects", method: http.MethodGet, path: "/redirect/2", status: http.StatusFound, requires: "Location"},
+	{group: "Status", method: http.MethodGet, path: "/status/418", status: http.StatusTeapot},
+	{group: "Status", method: http.MethodGet, path: "/status/451", status: http.StatusUnavailableForLegalReasons, contentType: "application/problem+json", requires: "Link"},
+	{group: "Cookies", method: http.MethodGet, path: "/cookies", status: http.StatusOK},
+	{group: "Security", method: http.MethodGet, path: "/security-headers", status: http.StatusOK},
+	{group: "Limits", method: http.MethodGet, path: "/limits", status: http.StatusOK, contentType: "application/json"},
+	{group: "OAuth", method: http.MethodGet, path: "/.well-known/openid-configuration", status: http.StatusOK, contentType: "application/json"},
+	{group: "OAuth", method: http.MethodGet, path: "/.well-known/jwks.json", status: http.StatusOK},
+	{group: "Errors", method: http.MethodGet, path: "/does-not-exist", status: http.StatusNotFound, contentType: "application/problem+json"},
+}
+
+// run makes the request and checks the response, returning a description of
+// the first problem found.
+func (c checkCase) run(client *http.Client, base string) error {
+	resp, err := c.request(client, base, "")
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != c.status {
+		return fmt.Errorf("expected status %d but got %d", c.status, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); c.contentType != "" && !strings.HasPrefix(ct, c.contentType) {
+		return fmt.Errorf("expected content type %s but got %q", c.contentType, ct)
+	}
+	if ce := resp.Header.Get("Content-Encoding"); c.encoding != "" && ce != c.encoding {
+		return fmt.Errorf("expected content encoding %s but got %q", c.encoding, ce)
+	}
+	if c.requires != "" && resp.Header.Get(c.requires) == "" {
+		return fmt.Errorf("expected a %s header", c.requires)
+	}
+
+	if c.conditional {
+		etag := resp.Header.Get("ETag")
+		if etag == "" {
+			return fmt.Errorf("expected an ETag header")
+		}
+		resp, err := c.request(client, base, etag)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusNotModified {
+			return fmt.Errorf("expected status 304 for If-None-Match %s but got %d", etag, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+// request sends the request, optionally conditional on an ETag, and reads
+// the full response body.
+func (c checkCase) request(client *http.Client, base, etag string) (*http.Response, error) {
+	var body io.Reader
+	if c.body != "" {
+		body = strings.NewReader(c.body)
+	}
+	req, err := http.NewRequest(c.method, base+c.path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "apibin-check")
+	for name, value := range c.header {
+		req.Header.Set(name, value)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return nil, fmt.Errorf("cannot read body: %w", err)
+	}
+	return resp, nil
+}
+
+// checkCommand verifies that an apibin server behaves as expected, starting
+// one locally unless a target is given. The handler is only available once
+// the options are parsed, so is passed as a function.
+func checkCommand(handler func() http.Handler) *cobra.Command {
+	var target string
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Verify that each endpoint group works",
+		Long:  "Make requests to each endpoint group checking status codes, content negotiation, compression, and conditional requests, exiting non-zero on any failure. Only read-only requests are made. Without a target, a server is started locally using the given options.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			base := strings.TrimSuffix(target, "/")
+			if base == "" {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Unable to listen:", err)
+					os.Exit(1)
+				}
+				server := &http.Server{Handler: handler(), ConnContext: apibin.TrackConnection}
+				go server.Serve(ln)
+				defer server.Close()
+				base = "http://" + ln.Addr().String()
+			} else if u, err := url.Parse(base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				fmt.Fprintf(os.Stderr, "invalid target %q, expected an absolute http or https URL\n", target)
+				os.Exit(1)
+			}
+
+			client := &http.Client{
+				Timeout: 10 * time.Second,
+				Transport: &http.Transport{
+					Proxy: http.ProxyFromEnvironment,
+					// Compression is negotiated explicitly and not decoded.
+					DisableCompression: true,
+				},
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			fmt.Printf("Checking %s\n", base)
+			failed := 0
+			for _, c := range checkCases {
+				name := fmt.Sprintf("%-12s %s %s", c.group, c.method, c.path)
+				if err := c.run(client, base); err != nil {
+					failed++
+					fmt.Printf("FAIL %s: %s\n", name, err)
+					continue
+				}
+				fmt.Printf("ok   %s\n", name)
+			}
+
+			fmt.Printf("%d passed, %d failed\n", len(checkCases)-failed, failed)
+			if failed > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "target", "", "Base URL of an apibin server to check instead of starting one")
+
+	return cmd
+}
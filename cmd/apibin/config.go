@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// configEnvPrefix is prepended to the upper snake-cased option name to get
+// its environment variable, e.g. `APIBIN_TLS_PORT` for `--tls-port`.
+const configEnvPrefix = "APIBIN_"
+
+// configEnv returns the environment variable name for an option.
+func configEnv(name string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// readConfigFile reads option values from a YAML or TOML file, chosen by its
+// extension, with keys matching the option names like `tls-port`. Only
+// scalar values are supported since every option is one.
+func readConfigFile(path string, flags *pflag.FlagSet) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config: %w", err)
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".yaml", ".yml", ".json":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config format %s, expected .yaml, .yml, .json or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	for key, value := range raw {
+		if key == "config" || flags.Lookup(key) == nil {
+			return nil, fmt.Errorf("unknown option %s in config %s", key, path)
+		}
+		switch value.(type) {
+		case string, bool, int, int64, float64:
+			values[key] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("invalid value for %s in config %s, expected a string, number or boolean", key, path)
+		}
+	}
+	return values, nil
+}
+
+// loadConfig sets options which weren't passed on the command line from
+// `APIBIN_*` environment variables, then from the config file if one is
+// given with `--config` or `APIBIN_CONFIG`. Anything left unset keeps its
+// default, which Huma lets `SERVICE_*` environment variables override.
+func loadConfig(flags *pflag.FlagSet) error {
+	values := map[string]string{}
+
+	path := flags.Lookup("config").Value.String()
+	if v, ok := os.LookupEnv(configEnv("config")); ok && !flags.Changed("config") {
+		path = v
+	}
+	if path != "" {
+		var err error
+		if values, err = readConfigFile(path, flags); err != nil {
+			return err
+		}
+	}
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if v, ok := os.LookupEnv(configEnv(f.Name)); ok && f.Name != "config" {
+			values[f.Name] = v
+		}
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flags.Changed(name) {
+			continue
+		}
+		if err := flags.Set(name, values[name]); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/apibin"
+)
+
+// sdListenFDsStart is the first file descriptor passed by systemd when using
+// socket activation. See `sd_listen_fds(3)`.
+const sdListenFDsStart = 3
+
+// systemdListeners returns the listeners inherited from systemd via socket
+// activation, if any. The environment variables are unset afterward so they
+// are not passed on to child processes.
+func systemdListeners() ([]net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := sdListenFDsStart + i
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		// The file listener is a duplicate of the inherited descriptor, so the
+		// original can be closed right away.
+		f := os.NewFile(uintptr(fd), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("socket activation fd %d: %w", fd, err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// listen opens the listeners the server should accept connections on. These
+// are either inherited from systemd socket activation, a Unix domain socket if
+// one was configured, or a TCP host & port.
+func listen(opts *apibin.Options) ([]net.Listener, error) {
+	if listeners, err := systemdListeners(); err != nil || len(listeners) > 0 {
+		return listeners, err
+	}
+
+	if opts.UnixSocket != "" {
+		// Clean up a stale socket left behind by an unclean exit, but never
+		// remove anything that isn't actually a socket.
+		if info, err := os.Stat(opts.UnixSocket); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return nil, fmt.Errorf("%s exists and is not a socket", opts.UnixSocket)
+			}
+			if err := os.Remove(opts.UnixSocket); err != nil {
+				return nil, err
+			}
+		}
+
+		// Unix listeners remove the socket file when closed, which happens as
+		// part of the server's graceful shutdown.
+		l, err := net.Listen("unix", opts.UnixSocket)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{l}, nil
+	}
+
+	l, err := net.Listen("tcp", fmt.Sprintf("%s:%d", opts.Host, opts.Port))
+	if err != nil {
+		return nil, err
+	}
+	return []net.Listener{l}, nil
+}
+
+// listenerURL returns a human-friendly description of where a listener is
+// accepting connections.
+func listenerURL(l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return "unix:" + l.Addr().String()
+	}
+	return "http://" + l.Addr().String()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/danielgtaylor/apibin"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/spf13/cobra"
+)
+
+// Options adds options which only apply to the command line.
+type Options struct {
+	apibin.Options
+
+	Config string `doc:"YAML or TOML file to read options from, see the README for precedence"`
+}
+
+func main() {
+	var api huma.API
+	var handler *apibin.Handler
+
+	cli := huma.NewCLI(func(hooks huma.Hooks, opts *Options) {
+		var err error
+		handler, api, err = apibin.NewE(&opts.Options)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		httpServer := apibin.NewServer(&opts.Options, handler)
+
+		if opts.TLSPort > 0 {
+			config, err := tlsConfig(&opts.Options)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			httpServer.TLSConfig = config
+		}
+
+		// closeHandler stops background work & writes recorded traffic once
+		// the server has shut down.
+		closeHandler := func() {
+			httpServer.Shutdown(context.Background())
+			if err := handler.Close(); err != nil {
+				fmt.Fprintln(os.Stderr, "Unable to close:", err)
+			}
+		}
+
+		hooks.OnStart(func() {
+			// The stop hook doesn't run if the server fails to start, so clean
+			// up here and exit with an error for supervisors to see.
+			failListen := func(err error) {
+				fmt.Fprintln(os.Stderr, "Unable to listen:", err)
+				closeHandler()
+				os.Exit(1)
+			}
+
+			listeners, err := listen(&opts.Options)
+			if err != nil {
+				failListen(err)
+			}
+
+			wg := sync.WaitGroup{}
+			for _, l := range listeners {
+				fmt.Println("Starting server on " + listenerURL(l))
+				wg.Add(1)
+				go func(l net.Listener) {
+					defer wg.Done()
+					httpServer.Serve(l)
+				}(l)
+			}
+
+			if opts.TLSPort > 0 {
+				tlsListener, err := listenTLS(&opts.Options)
+				if err != nil {
+					failListen(err)
+				}
+
+				fmt.Println("Starting server on https://" + tlsListener.Addr().String())
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					httpServer.ServeTLS(tlsListener, "", "")
+				}()
+			}
+
+			wg.Wait()
+		})
+
+		hooks.OnStop(closeHandler)
+	})
+
+	cli.Root().AddCommand(&cobra.Command{
+		Use:   "openapi",
+		Short: "Generate OpenAPI spec",
+		Run: func(cmd *cobra.Command, args []string) {
+			b, _ := json.MarshalIndent(api.OpenAPI(), "", "  ")
+			fmt.Println(string(b))
+		},
+	})
+
+	cli.Root().AddCommand(benchCommand())
+	cli.Root().AddCommand(checkCommand(func() http.Handler { return handler }))
+	cli.Root().AddCommand(replayCommand())
+	cli.Root().AddCommand(validateCommand())
+
+	// Initializers run after flags are parsed but before Huma reads them into
+	// the options, so values from the environment & config file apply to
+	// every command.
+	cobra.OnInitialize(func() {
+		if err := loadConfig(cli.Root().PersistentFlags()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	})
+
+	cli.Run()
+}
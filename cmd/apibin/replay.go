@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/apibin"
+	"github.com/spf13/cobra"
+)
+
+// replayMaxDiffs limits the differences shown for each response.
+const replayMaxDiffs = 5
+
+// replaySkipHeaders aren't replayed since they describe the recorded
+// connection or body, which the client sets itself. Recorded bodies are
+// already decoded, so compression isn't negotiated.
+var replaySkipHeaders = map[string]bool{
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// replayResult is the outcome of replaying a single entry.
+type replayResult struct {
+	err   error
+	diffs []string
+}
+
+// readHAR reads an HTTP Archive from a file.
+func readHAR(path string) (*apibin.HAR, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var har apibin.HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR %s: %w", path, err)
+	}
+	return &har, nil
+}
+
+// harText returns the raw bytes of HAR body text.
+func harText(text, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return []byte(text), nil
+}
+
+// replayRequest builds the request for an entry sent to the target. Only the
+// recorded path & query are kept from the URL.
+func replayRequest(entry apibin.HAREntry, target string) (*http.Request, error) {
+	u, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if pd := entry.Request.PostData; pd != nil {
+		data, err := harText(pd.Text, pd.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(entry.Request.Method, target+u.RequestURI(), body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range entry.Request.Headers {
+		// Values redacted by `--record` are left out rather than sent.
+		if name := http.CanonicalHeaderKey(h.Name); !replaySkipHeaders[name] && h.Value != "[redacted]" {
+			req.Header.Add(name, h.Value)
+		}
+	}
+	return req, nil
+}
+
+// replay sends the entry's request to the target and compares the response
+// with the recorded one.
+func replay(client *http.Client, entry apibin.HAREntry, target string, ignore map[string]bool) replayResult {
+	req, err := replayRequest(entry, target)
+	if err != nil {
+		return replayResult{err: err}
+	}
+	if pd := entry.Request.PostData; pd != nil && pd.Truncated {
+		return replayResult{err: fmt.Errorf("the recorded request body was truncated")}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return replayResult{err: err}
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return replayResult{err: fmt.Errorf("cannot read body: %w", err)}
+	}
+
+	recorded := entry.Response
+	diffs := []string{}
+	if resp.StatusCode != recorded.Status {
+		diffs = append(diffs, fmt.Sprintf("status: %d != %d", recorded.Status, resp.StatusCode))
+	}
+	wantType, _, _ := mime.ParseMediaType(recorded.Content.MimeType)
+	gotType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if wantType != gotType {
+		diffs = append(diffs, fmt.Sprintf("content type: %q != %q", wantType, gotType))
+	}
+
+	// Bodies are only compared when they were recorded in full. Links to the
+	// recorded server are rewritten to point at the target.
+	if !recorded.Content.Truncated {
+		want, err := harText(recorded.Content.Text, recorded.Content.Encoding)
+		if err != nil {
+			return replayResult{err: fmt.Errorf("invalid recorded body: %w", err)}
+		}
+		if u, err := url.Parse(entry.Request.URL); err == nil && recorded.Content.Encoding == "" {
+			want = bytes.ReplaceAll(want, []byte(u.Scheme+"://"+u.Host), []byte(target))
+		}
+		diffs = append(diffs, diffBodies(want, got, ignore)...)
+	}
+	return replayResult{diffs: diffs}
+}
+
+// diffBodies compares response bodies, describing each differing field for
+// JSON and the first differing line otherwise.
+func diffBodies(want, got []byte, ignore map[string]bool) []string {
+	var wantValue, gotValue any
+	if json.Unmarshal(want, &wantValue) == nil && json.Unmarshal(got, &gotValue) == nil {
+		diffs := []string{}
+		diffJSON("body", wantValue, gotValue, ignore, &diffs)
+		return diffs
+	}
+	if bytes.Equal(want, got) {
+		return nil
+	}
+
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return []string{fmt.Sprintf("body line %d: %q != %q", i+1, truncate(w), truncate(g))}
+		}
+	}
+	return []string{fmt.Sprintf("body: %d bytes != %d bytes", len(want), len(got))}
+}
+
+// diffJSON appends the paths of values which differ, skipping object fields
+// named in `ignore`.
+func diffJSON(path string, want, got any, ignore map[string]bool, diffs *[]string) {
+	switch w := want.(type) {
+	case map[string]any:
+		if g, ok := got.(map[string]any); ok {
+			keys := map[string]bool{}
+			for k := range w {
+				keys[k] = true
+			}
+			for k := range g {
+				keys[k] = true
+			}
+			sorted := []string{}
+			for k := range keys {
+				if !ignore[k] {
+					sorted = append(sorted, k)
+				}
+			}
+			sort.Strings(sorted)
+			for _, k := range sorted {
+				diffJSON(path+"."+k, w[k], g[k], ignore, diffs)
+			}
+			return
+		}
+	case []any:
+		if g, ok := got.([]any); ok {
+			if len(w) != len(g) {
+				*diffs = append(*diffs, fmt.Sprintf("%s: %d items != %d items", path, len(w), len(g)))
+				return
+			}
+			for i := range w {
+				diffJSON(fmt.Sprintf("%s[%d]", path, i), w[i], g[i], ignore, diffs)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(want, got) {
+		*diffs = append(*diffs, fmt.Sprintf("%s: %s != %s", path, jsonValue(want), jsonValue(got)))
+	}
+}
+
+// jsonValue formats a value in a diff, with missing values shown as null.
+func jsonValue(v any) string {
+	if v == nil {
+		return "null"
+	}
+	b, _ := json.Marshal(v)
+	return truncate(string(b))
+}
+
+// truncate shortens long values in diffs.
+func truncate(s string) string {
+	if len(s) > 60 {
+		return s[:57] + "..."
+	}
+	return s
+}
+
+// replayCommand re-issues the requests in an HTTP Archive against a server
+// and reports how the responses differ from the recording.
+func replayCommand() *cobra.Command {
+	var target string
+	var speed float64
+	var ignore []string
+
+	cmd := &cobra.Command{
+		Use:   "replay file.har",
+		Short: "Replay recorded requests and diff the responses",
+		Long:  "Re-issue the requests in an HTTP Archive, like one written via --record, against a server and compare the status, content type, and body of each response with the recording, exiting non-zero on any difference. JSON bodies are compared field by field. Credentials redacted in the recording are not sent. Requests are sent one after another unless a speed is given, which replays them at the recorded timing scaled by the speed, e.g. 1 for the original timing or 2 for twice as fast.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			har, err := readHAR(args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			base := strings.TrimSuffix(target, "/")
+			if u, err := url.Parse(base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				fmt.Fprintf(os.Stderr, "invalid target %q, expected an absolute http or https URL\n", target)
+				os.Exit(1)
+			}
+			if speed < 0 {
+				fmt.Fprintln(os.Stderr, "The speed must not be negative")
+				os.Exit(1)
+			}
+			ignored := map[string]bool{}
+			for _, field := range ignore {
+				ignored[field] = true
+			}
+
+			client := &http.Client{
+				Timeout: 30 * time.Second,
+				Transport: &http.Transport{
+					Proxy: http.ProxyFromEnvironment,
+					// Recorded bodies are decoded, so neither are replayed ones.
+					DisableCompression: true,
+				},
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			entries := har.Log.Entries
+			sort.SliceStable(entries, func(i, j int) bool {
+				return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+			})
+
+			// With a speed, each request is sent at its scaled offset from the
+			// first so slow responses don't delay the rest. Results are still
+			// printed in order.
+			results := make([]chan replayResult, len(entries))
+			start := time.Now()
+			for i, entry := range entries {
+				if speed == 0 {
+					break
+				}
+				results[i] = make(chan replayResult, 1)
+				offset := time.Duration(float64(entry.StartedDateTime.Sub(entries[0].StartedDateTime)) / speed)
+				go func(entry apibin.HAREntry, done chan replayResult) {
+					time.Sleep(time.Until(start.Add(offset)))
+					done <- replay(client, entry, base, ignored)
+				}(entry, results[i])
+			}
+
+			fmt.Printf("Replaying %d requests against %s\n", len(entries), base)
+			failed := 0
+			for i, entry := range entries {
+				name := entry.Request.Method + " " + entry.Request.URL
+				if u, err := url.Parse(entry.Request.URL); err == nil {
+					name = entry.Request.Method + " " + u.RequestURI()
+				}
+				var result replayResult
+				if results[i] != nil {
+					result = <-results[i]
+				} else {
+					result = replay(client, entry, base, ignored)
+				}
+				switch {
+				case result.err != nil:
+					failed++
+					fmt.Printf("FAIL %s: %s\n", name, result.err)
+				case len(result.diffs) > 0:
+					failed++
+					fmt.Printf("DIFF %s\n", name)
+					for j, diff := range result.diffs {
+						if j == replayMaxDiffs {
+							fmt.Printf("     ... and %d more\n", len(result.diffs)-j)
+							break
+						}
+						fmt.Printf("     %s\n", diff)
+					}
+				default:
+					fmt.Printf("ok   %s\n", name)
+				}
+			}
+
+			fmt.Printf("%d matched, %d differed or failed\n", len(entries)-failed, failed)
+			if failed > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "target", "http://localhost:8888", "Base URL of the server to replay requests against")
+	cmd.Flags().Float64Var(&speed, "speed", 0, "Replay at the recorded timing scaled by this multiplier, or back to back when zero")
+	cmd.Flags().StringSliceVar(&ignore, "ignore", nil, "Comma-separated JSON field names to leave out when comparing bodies, e.g. generated,until")
+
+	return cmd
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/danielgtaylor/apibin"
+)
+
+// tlsConfig loads the configured certificate & key. If none are given, then a
+// self-signed certificate is generated which is good enough for local testing.
+func tlsConfig(opts *apibin.Options) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if opts.TLSCert != "" || opts.TLSKey != "" {
+		cert, err = tls.LoadX509KeyPair(opts.TLSCert, opts.TLSKey)
+	} else {
+		cert, err = selfSignedCert()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}, nil
+}
+
+// selfSignedCert generates a short-lived self-signed certificate for
+// `localhost` and the loopback addresses.
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"API Bin"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
+
+// listenTLS opens the TLS listener's underlying TCP socket. The TLS handshake
+// itself is handled by the HTTP server so that HTTP/2 can be negotiated.
+func listenTLS(opts *apibin.Options) (net.Listener, error) {
+	return net.Listen("tcp", fmt.Sprintf("%s:%d", opts.Host, opts.TLSPort))
+}
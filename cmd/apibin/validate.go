@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/danielgtaylor/apibin"
+	"github.com/spf13/cobra"
+)
+
+// validateCommand checks books & example data files, which would otherwise
+// only fail with a panic on startup. Without arguments the embedded files are
+// checked.
+func validateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [books.json] [example.json]",
+		Short: "Validate books & example data files",
+		Long:  "Check books & example (resume) data files against the JSON Schemas of the types they're loaded into, printing the location of each problem. Without arguments the embedded files are checked.",
+		Args:  cobra.MaximumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			files := apibin.EmbeddedDataFiles()
+			if len(args) > 0 {
+				files = files[:len(args)]
+				for i, path := range args {
+					data, err := os.ReadFile(path)
+					if err != nil {
+						fmt.Fprintln(os.Stderr, err)
+						os.Exit(1)
+					}
+					files[i].Name = path
+					files[i].Data = data
+				}
+			}
+
+			failed := false
+			for _, f := range files {
+				problems := f.Validate()
+				if len(problems) == 0 {
+					fmt.Printf("%s: ok\n", f.Name)
+					continue
+				}
+				failed = true
+				for _, p := range problems {
+					fmt.Println(p)
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+}
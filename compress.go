@@ -1,15 +1,18 @@
 // This file is copied from Huma v1 middleware, slightly modified to use v2
 // for content negotiation.
-package main
+package apibin
 
 import (
 	"bytes"
 	"compress/gzip"
+	"fmt"
 	"io"
 	"net/http"
 	"path"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/andybalholm/brotli"
 	"github.com/danielgtaylor/huma/v2/negotiation"
@@ -18,9 +21,20 @@ import (
 const gzipEncoding = "gzip"
 const brotliEncoding = "br"
 
+// compressLevelHeader lets a request override the configured compression level
+// for the negotiated encoding, e.g. to compare levels while benchmarking.
+const compressLevelHeader = "X-Apibin-Compress-Level"
+
 var supportedEncodings []string = []string{brotliEncoding, gzipEncoding}
 var compressDenyList []string = []string{".gif", ".png", ".jpg", ".jpeg", ".zip", ".gz", ".bz2"}
 
+// compressDenyTypes are response content types which are already compressed
+// or otherwise not worth compressing, so they are passed through as-is. Byte
+// range parts refer to the unencoded representation, so aren't compressed.
+// Other responses which must be sent exactly as written, like the random
+// bytes, opt out via `Cache-Control: no-transform` instead.
+var compressDenyTypes []string = []string{"multipart/byteranges", "application/zip", "application/gzip", "image/jpeg", "image/webp", "image/gif", "image/png", "image/heic"}
+
 type contentEncodingWriter struct {
 	http.ResponseWriter
 	status      int
@@ -28,9 +42,73 @@ type contentEncodingWriter struct {
 	buf         *bytes.Buffer
 	writer      io.Writer
 	minSize     int
+	level       int
 	gzPool      *sync.Pool
 	brPool      *sync.Pool
 	wroteHeader bool
+	passthrough bool
+}
+
+// skip returns whether the response should be passed through without any
+// modification, e.g. because it is already encoded or is a binary format.
+func (w *contentEncodingWriter) skip() bool {
+	if w.Header().Get("Content-Encoding") != "" {
+		// Content encoding was already set, so we should ignore this!
+		return true
+	}
+
+	if w.Header().Get("Content-Range") != "" {
+		// Byte ranges refer to the unencoded representation.
+		return true
+	}
+
+	for _, directive := range strings.Split(w.Header().Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-transform") {
+			// The handler needs its exact bytes & framing sent.
+			return true
+		}
+	}
+
+	ct := w.Header().Get("Content-Type")
+	if i := strings.IndexByte(ct, ';'); i > -1 {
+		ct = ct[:i]
+	}
+	for _, deny := range compressDenyTypes {
+		if strings.TrimSpace(ct) == deny {
+			return true
+		}
+	}
+	return false
+}
+
+// startEncoding sets the compressed writer, sends the headers, and writes out
+// anything that was buffered so far.
+func (w *contentEncodingWriter) startEncoding() (int, error) {
+	switch {
+	case w.level >= 0 && w.encoding == gzipEncoding:
+		// Level was already validated, so this can't fail.
+		w.writer, _ = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	case w.level >= 0 && w.encoding == brotliEncoding:
+		w.writer = brotli.NewWriterLevel(w.ResponseWriter, w.level)
+	case w.encoding == gzipEncoding:
+		gz := w.gzPool.Get().(*gzip.Writer)
+		gz.Reset(w.ResponseWriter)
+		w.writer = gz
+	case w.encoding == brotliEncoding:
+		br := w.brPool.Get().(*brotli.Writer)
+		br.Reset(w.ResponseWriter)
+		w.writer = br
+	}
+	if w.level >= 0 {
+		w.Header().Set(compressLevelHeader, strconv.Itoa(w.level))
+	}
+	w.Header().Set("Content-Encoding", w.encoding)
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.wroteHeader = true
+	bufData := w.buf.Bytes()
+	w.buf.Reset()
+	return w.writer.Write(bufData)
 }
 
 func (w *contentEncodingWriter) Write(data []byte) (int, error) {
@@ -39,8 +117,12 @@ func (w *contentEncodingWriter) Write(data []byte) (int, error) {
 		return w.writer.Write(data)
 	}
 
-	if w.Header().Get("Content-Encoding") != "" {
-		// Content encoding was already set, so we should ignore this!
+	if w.passthrough || w.skip() {
+		if !w.wroteHeader {
+			w.ResponseWriter.WriteHeader(w.status)
+			w.wroteHeader = true
+		}
+		w.passthrough = true
 		return w.ResponseWriter.Write(data)
 	}
 
@@ -51,23 +133,7 @@ func (w *contentEncodingWriter) Write(data []byte) (int, error) {
 	if cl >= w.minSize || w.buf.Len() >= w.minSize {
 		// We reached our minimum compression size. Set the writer, write the buffer
 		// and make sure to set the correct headers.
-		switch w.encoding {
-		case gzipEncoding:
-			gz := w.gzPool.Get().(*gzip.Writer)
-			gz.Reset(w.ResponseWriter)
-			w.writer = gz
-		case brotliEncoding:
-			br := w.brPool.Get().(*brotli.Writer)
-			br.Reset(w.ResponseWriter)
-			w.writer = br
-		}
-		w.Header().Set("Content-Encoding", w.encoding)
-		w.Header().Add("Vary", "Accept-Encoding")
-		w.ResponseWriter.WriteHeader(w.status)
-		w.wroteHeader = true
-		bufData := w.buf.Bytes()
-		w.buf.Reset()
-		return w.writer.Write(bufData)
+		return w.startEncoding()
 	}
 
 	// Not sure yet whether this should be compressed.
@@ -75,10 +141,38 @@ func (w *contentEncodingWriter) Write(data []byte) (int, error) {
 }
 
 func (w *contentEncodingWriter) WriteHeader(code int) {
+	if w.skip() {
+		// Send the response as-is, including any `Content-Length` header.
+		w.passthrough = true
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
 	w.Header().Del("Content-Length")
 	w.status = code
 }
 
+// Flush sends any buffered data to the client. Once a streaming response has
+// been flushed we can no longer wait to see how big it is, so compression
+// starts immediately.
+func (w *contentEncodingWriter) Flush() {
+	if w.writer == nil && !w.passthrough {
+		if w.skip() {
+			w.Write(nil)
+		} else {
+			w.startEncoding()
+		}
+	}
+
+	if f, ok := w.writer.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (w *contentEncodingWriter) finish() {
 	if !w.wroteHeader {
 		w.ResponseWriter.WriteHeader(w.status)
@@ -88,12 +182,29 @@ func (w *contentEncodingWriter) finish() {
 	}
 }
 
+// Unwrap returns the underlying response writer, which allows the use of
+// `http.ResponseController`.
+func (w *contentEncodingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// SetWriteDeadline sets the write deadline of the underlying connection, which
+// some streaming writers check for directly instead of using a controller.
+func (w *contentEncodingWriter) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(w.ResponseWriter).SetWriteDeadline(deadline)
+}
+
 func (w *contentEncodingWriter) Close() {
 	if w.writer != nil {
 		if wc, ok := w.writer.(io.WriteCloser); ok {
 			wc.Close()
 		}
 
+		if w.level >= 0 {
+			// Writers with a custom level are not pooled.
+			return
+		}
+
 		// Return the writer to the pool so it can be reused.
 		switch w.encoding {
 		case gzipEncoding:
@@ -104,10 +215,66 @@ func (w *contentEncodingWriter) Close() {
 	}
 }
 
+// compressLevelRange returns the minimum and maximum levels for an encoding.
+func compressLevelRange(encoding string) (int, int) {
+	if encoding == brotliEncoding {
+		return brotli.BestSpeed, brotli.BestCompression
+	}
+	return gzip.NoCompression, gzip.BestCompression
+}
+
+// requestCompressLevel returns the level requested via the override header,
+// or -1 if there is no valid override for the encoding.
+func requestCompressLevel(r *http.Request, encoding string) int {
+	value := r.Header.Get(compressLevelHeader)
+	if value == "" {
+		return -1
+	}
+	level, err := strconv.Atoi(value)
+	lo, hi := compressLevelRange(encoding)
+	if err != nil || level < lo || level > hi {
+		return -1
+	}
+	return level
+}
+
+// CompressOptions configure the `ContentEncoding` middleware.
+type CompressOptions struct {
+	// GzipLevel is the gzip compression level from 0 to 9.
+	GzipLevel int
+
+	// BrotliQuality is the Brotli quality from 0 to 11.
+	BrotliQuality int
+
+	// MinSize is the body size in bytes at which compression is enabled.
+	MinSize int
+}
+
+// Validate checks the levels are in range for their encodings.
+func (o CompressOptions) Validate() error {
+	if lo, hi := compressLevelRange(gzipEncoding); o.GzipLevel < lo || o.GzipLevel > hi {
+		return fmt.Errorf("invalid gzip level %d, expected %d-%d", o.GzipLevel, lo, hi)
+	}
+	if lo, hi := compressLevelRange(brotliEncoding); o.BrotliQuality < lo || o.BrotliQuality > hi {
+		return fmt.Errorf("invalid brotli quality %d, expected %d-%d", o.BrotliQuality, lo, hi)
+	}
+	if o.MinSize < 0 {
+		return fmt.Errorf("invalid minimum compression size %d", o.MinSize)
+	}
+	return nil
+}
+
 // ContentEncoding uses content negotiation with the client to pick
 // an appropriate encoding (compression) method and transparently encodes
-// the response. Supports GZip and Brotli.
-func ContentEncoding(next http.Handler) http.Handler {
+// the response. Supports GZip and Brotli. Requests may override the level for
+// the negotiated encoding via the `X-Apibin-Compress-Level` header.
+func ContentEncoding(opts CompressOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return contentEncoding(opts, next)
+	}
+}
+
+func contentEncoding(opts CompressOptions, next http.Handler) http.Handler {
 	// Use pools to reduce allocations. We use a byte buffer to temporarily store
 	// some of each response in order to determine whether compression should
 	// be applied. The others are just re-using the GZip and Brotli compressors.
@@ -119,13 +286,15 @@ func ContentEncoding(next http.Handler) http.Handler {
 
 	gzPool := sync.Pool{
 		New: func() interface{} {
-			return gzip.NewWriter(io.Discard)
+			// Level was already validated, so this can't fail.
+			gz, _ := gzip.NewWriterLevel(io.Discard, opts.GzipLevel)
+			return gz
 		},
 	}
 
 	brPool := sync.Pool{
 		New: func() interface{} {
-			return brotli.NewWriter(io.Discard)
+			return brotli.NewWriterLevel(io.Discard, opts.BrotliQuality)
 		},
 	}
 
@@ -140,6 +309,12 @@ func ContentEncoding(next http.Handler) http.Handler {
 			}
 		}
 
+		if r.Header.Get("Upgrade") != "" {
+			// Upgrades switch protocols, so there's no response body to encode.
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		if ac := r.Header.Get("Accept-Encoding"); ac != "" {
 			best := negotiation.SelectQValueFast(ac, supportedEncodings)
 
@@ -155,15 +330,8 @@ func ContentEncoding(next http.Handler) http.Handler {
 					buf:            buf,
 					gzPool:         &gzPool,
 					brPool:         &brPool,
-
-					// minSize of the body at which compression is enabled. Internet MTU
-					// size is 1500 bytes, so anything smaller will still require sending
-					// at least that size (including headers). Google's research at
-					// http://dev.chromium.org/spdy/spdy-whitepaper suggests headers
-					// are at least 200 bytes and average 700-800 bytes. If we assume
-					// an average 30% compression ratio and 500 bytes of headers, then
-					// (1400 * 0.7) + 500 = 1480 bytes, just about the minimum MTU.
-					minSize: 1400,
+					minSize:        opts.MinSize,
+					level:          requestCompressLevel(r, best),
 				}
 
 				// Make sure to clean up and return the buffer and encoder to their
@@ -0,0 +1,45 @@
+package apibin
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContentEncodingSkip(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 4096)
+
+	for _, tc := range []struct {
+		name         string
+		contentType  string
+		cacheControl string
+		want         string
+	}{
+		{"binary", "application/octet-stream", "", "gzip"},
+		{"compressed", "application/zip", "", ""},
+		{"no-transform", "application/octet-stream", "no-store, No-Transform", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := ContentEncoding(CompressOptions{GzipLevel: 6, BrotliQuality: 6, MinSize: 1400})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				if tc.cacheControl != "" {
+					w.Header().Set("Cache-Control", tc.cacheControl)
+				}
+				w.Write(body)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if ce := w.Header().Get("Content-Encoding"); ce != tc.want {
+				t.Errorf("expected content encoding %q, got %q", tc.want, ce)
+			}
+			if tc.want == "" && !bytes.Equal(w.Body.Bytes(), body) {
+				t.Errorf("expected the body to be sent as-is")
+			}
+		})
+	}
+}
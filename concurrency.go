@@ -0,0 +1,131 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// instrumentedRWMutex is a read/write mutex which tracks how long callers
+// wait to acquire it.
+type instrumentedRWMutex struct {
+	sync.RWMutex
+
+	readersWaiting int64
+	writersWaiting int64
+	reads          int64
+	writes         int64
+	readWait       int64
+	writeWait      int64
+	maxWait        int64
+}
+
+// observe records a completed wait.
+func (m *instrumentedRWMutex) observe(count, total *int64, wait time.Duration) {
+	atomic.AddInt64(count, 1)
+	atomic.AddInt64(total, int64(wait))
+	for {
+		max := atomic.LoadInt64(&m.maxWait)
+		if int64(wait) <= max || atomic.CompareAndSwapInt64(&m.maxWait, max, int64(wait)) {
+			return
+		}
+	}
+}
+
+func (m *instrumentedRWMutex) Lock() {
+	start := time.Now()
+	atomic.AddInt64(&m.writersWaiting, 1)
+	m.RWMutex.Lock()
+	atomic.AddInt64(&m.writersWaiting, -1)
+	m.observe(&m.writes, &m.writeWait, time.Since(start))
+}
+
+func (m *instrumentedRWMutex) RLock() {
+	start := time.Now()
+	atomic.AddInt64(&m.readersWaiting, 1)
+	m.RWMutex.RLock()
+	atomic.AddInt64(&m.readersWaiting, -1)
+	m.observe(&m.reads, &m.readWait, time.Since(start))
+}
+
+// averageWait returns the mean wait as a string.
+func averageWait(total, count int64) string {
+	if count == 0 {
+		return time.Duration(0).String()
+	}
+	return time.Duration(total / count).String()
+}
+
+// Stats returns a snapshot of the lock's wait statistics.
+func (m *instrumentedRWMutex) Stats() LockStats {
+	reads := atomic.LoadInt64(&m.reads)
+	writes := atomic.LoadInt64(&m.writes)
+	return LockStats{
+		ReadersWaiting:    atomic.LoadInt64(&m.readersWaiting),
+		WritersWaiting:    atomic.LoadInt64(&m.writersWaiting),
+		ReadAcquisitions:  reads,
+		WriteAcquisitions: writes,
+		AverageReadWait:   averageWait(atomic.LoadInt64(&m.readWait), reads),
+		AverageWriteWait:  averageWait(atomic.LoadInt64(&m.writeWait), writes),
+		MaxWait:           time.Duration(atomic.LoadInt64(&m.maxWait)).String(),
+	}
+}
+
+// LockStats describes contention on the books lock since the server started.
+type LockStats struct {
+	ReadersWaiting    int64  `json:"readers_waiting" doc:"Requests currently waiting to read books"`
+	WritersWaiting    int64  `json:"writers_waiting" doc:"Requests currently waiting to modify books"`
+	ReadAcquisitions  int64  `json:"read_acquisitions"`
+	WriteAcquisitions int64  `json:"write_acquisitions"`
+	AverageReadWait   string `json:"average_read_wait"`
+	AverageWriteWait  string `json:"average_write_wait"`
+	MaxWait           string `json:"max_wait" doc:"Longest wait for either a read or write"`
+}
+
+// ConcurrencyResult describes a single hold of the books lock.
+type ConcurrencyResult struct {
+	Waited string `json:"waited" doc:"Time spent waiting to acquire the lock"`
+	Held   string `json:"held" doc:"Time the lock was held"`
+}
+
+func (s *APIServer) RegisterConcurrency(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "hold-books-lock",
+		Method:      http.MethodPost,
+		Path:        "/concurrency/{ms}",
+		Description: "Hold the books write lock for the given number of milliseconds. Concurrent book requests queue behind it, which makes the effect of contention observable. Holds are served one at a time, so concurrent calls also wait for each other.",
+		Tags:        []string{"Concurrency"},
+		Metadata:    map[string]any{streamingKey: true},
+	}, func(ctx context.Context, input *struct {
+		Ms int `path:"ms" minimum:"1" maximum:"5000" doc:"Milliseconds to hold the lock"`
+	}) (*struct{ Body ConcurrencyResult }, error) {
+		start := time.Now()
+		booksMu.Lock()
+		defer booksMu.Unlock()
+		acquired := time.Now()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(input.Ms) * time.Millisecond):
+		}
+
+		return &struct{ Body ConcurrencyResult }{Body: ConcurrencyResult{
+			Waited: acquired.Sub(start).String(),
+			Held:   time.Since(acquired).String(),
+		}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-lock-stats",
+		Method:      http.MethodGet,
+		Path:        "/concurrency",
+		Description: "Get wait statistics for the books lock, which is used by all book operations.",
+		Tags:        []string{"Concurrency"},
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body LockStats }, error) {
+		return &struct{ Body LockStats }{Body: booksMu.Stats()}, nil
+	})
+}
@@ -0,0 +1,92 @@
+package apibin
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+var connStateKey contextKey = "apibin/conn-state"
+var connectionKey contextKey = "apibin/connection"
+
+// connState tracks per-connection state across requests.
+type connState struct {
+	requests atomic.Int64
+}
+
+// TrackConnection is used as the server's `ConnContext` hook to attach state
+// to each new connection, which makes it possible to detect reuse.
+func TrackConnection(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connStateKey, &connState{})
+}
+
+// TLSModel describes the negotiated TLS parameters of a connection.
+type TLSModel struct {
+	Version     string `json:"version" doc:"TLS protocol version, e.g. TLS 1.3"`
+	CipherSuite string `json:"cipher_suite" doc:"Negotiated cipher suite"`
+	ALPN        string `json:"alpn,omitempty" doc:"Application protocol negotiated via ALPN, e.g. h2"`
+	ServerName  string `json:"server_name,omitempty" doc:"Server name sent by the client via SNI"`
+	Resumed     bool   `json:"resumed" doc:"Whether the TLS session was resumed"`
+}
+
+// ConnectionModel describes the connection a request arrived on.
+type ConnectionModel struct {
+	Protocol   string    `json:"protocol" doc:"HTTP protocol version, e.g. HTTP/1.1 or HTTP/2.0"`
+	RemoteAddr string    `json:"remote_addr" doc:"Address of the directly connected peer, which may be a proxy"`
+	Requests   int64     `json:"requests,omitempty" doc:"Number of requests served on this connection so far, including this one"`
+	Reused     bool      `json:"reused" doc:"Whether the connection was reused from a previous request (keep-alive or HTTP/2 multiplexing)"`
+	TLS        *TLSModel `json:"tls,omitempty" doc:"TLS details when the connection is encrypted"`
+}
+
+// tlsVersionName returns a human-readable TLS version.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// GetConnection returns the connection details for a request context, as set
+// by the `ConnectionDetails` middleware.
+func GetConnection(ctx context.Context) *ConnectionModel {
+	conn, _ := ctx.Value(connectionKey).(*ConnectionModel)
+	return conn
+}
+
+// ConnectionDetails records information about the underlying connection of
+// each request and stores it in the request context.
+func ConnectionDetails(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := &ConnectionModel{
+			Protocol:   r.Proto,
+			RemoteAddr: r.RemoteAddr,
+		}
+
+		if state, ok := r.Context().Value(connStateKey).(*connState); ok {
+			conn.Requests = state.requests.Add(1)
+			conn.Reused = conn.Requests > 1
+		}
+
+		if r.TLS != nil {
+			conn.TLS = &TLSModel{
+				Version:     tlsVersionName(r.TLS.Version),
+				CipherSuite: tls.CipherSuiteName(r.TLS.CipherSuite),
+				ALPN:        r.TLS.NegotiatedProtocol,
+				ServerName:  r.TLS.ServerName,
+				Resumed:     r.TLS.DidResume,
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), connectionKey, conn)))
+	})
+}
@@ -0,0 +1,112 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// sameSiteMode converts a `SameSite` attribute name into its mode. An empty
+// name omits the attribute.
+func sameSiteMode(name string) http.SameSite {
+	switch name {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return 0
+}
+
+// CookieModel is a single cookie sent by the client.
+type CookieModel struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SetCookieResult describes a cookie that was set.
+type SetCookieResult struct {
+	SetCookie string `json:"set_cookie" doc:"The Set-Cookie header value sent"`
+}
+
+type SetCookieResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	SetCookie    string `header:"Set-Cookie"`
+	Body         SetCookieResult
+}
+
+func (s *APIServer) RegisterCookies(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-cookies",
+		Method:      http.MethodGet,
+		Path:        "/cookies",
+		Description: "List the cookies sent by the client, in the order sent. Cookies with the same name, e.g. from different paths, are all included.",
+		Tags:        []string{"Cookies"},
+	}, func(ctx context.Context, input *struct {
+		Cookie string `header:"Cookie"`
+	}) (*struct {
+		CacheControl string `header:"Cache-Control"`
+		Body         []CookieModel
+	}, error) {
+		r := http.Request{Header: http.Header{"Cookie": {input.Cookie}}}
+		cookies := []CookieModel{}
+		for _, c := range r.Cookies() {
+			cookies = append(cookies, CookieModel{Name: c.Name, Value: c.Value})
+		}
+		return &struct {
+			CacheControl string `header:"Cache-Control"`
+			Body         []CookieModel
+		}{CacheControl: "no-store", Body: cookies}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-cookies-set-advanced",
+		Method:      http.MethodGet,
+		Path:        "/cookies/set-advanced",
+		Description: "Set a cookie with any combination of attributes, including ones browsers reject like `SameSite=None` without `Secure`. Check what the client sends back via `/cookies`.",
+		Tags:        []string{"Cookies"},
+	}, func(ctx context.Context, input *struct {
+		Name        string `query:"name" default:"apibin" minLength:"1" pattern:"^[!#$%&'*+.^_|~0-9A-Za-z-]+$" doc:"Cookie name"`
+		Value       string `query:"value" default:"1" doc:"Cookie value"`
+		Secure      bool   `query:"secure" doc:"Set the Secure attribute"`
+		HTTPOnly    bool   `query:"httponly" doc:"Set the HttpOnly attribute"`
+		SameSite    string `query:"samesite" enum:"lax,strict,none" doc:"SameSite attribute, omitted if not set"`
+		Domain      string `query:"domain" doc:"Domain attribute, omitted if not set"`
+		Path        string `query:"path" doc:"Path attribute, omitted if not set"`
+		MaxAge      int    `query:"max-age" minimum:"-1" doc:"Max-Age attribute in seconds. Zero omits it and -1 sends Max-Age=0 to delete the cookie."`
+		Partitioned bool   `query:"partitioned" doc:"Set the Partitioned attribute for CHIPS. Browsers require Secure."`
+	}) (*SetCookieResponse, error) {
+		c := &http.Cookie{
+			Name:     input.Name,
+			Value:    input.Value,
+			Secure:   input.Secure,
+			HttpOnly: input.HTTPOnly,
+			SameSite: sameSiteMode(input.SameSite),
+			Domain:   input.Domain,
+			Path:     input.Path,
+			MaxAge:   input.MaxAge,
+		}
+		value := c.String()
+		if c.Domain != "" && !strings.Contains(value, "; Domain=") {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Message:  "invalid cookie domain",
+				Location: "query.domain",
+				Value:    input.Domain,
+			})
+		}
+		if input.Partitioned {
+			// Set manually as `http.Cookie` only supports it in newer Go versions.
+			value += "; Partitioned"
+		}
+
+		return &SetCookieResponse{
+			CacheControl: "no-store",
+			SetCookie:    value,
+			Body:         SetCookieResult{SetCookie: value},
+		}, nil
+	})
+}
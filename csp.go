@@ -0,0 +1,242 @@
+package apibin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	// maxCSPReports limits how many violation reports are kept. The oldest are
+	// removed first.
+	maxCSPReports = 100
+
+	// cspReportPath is where browsers send violation reports, and
+	// cspReportGroup is the Reporting API endpoint name pointing to it.
+	cspReportPath  = "/csp/report"
+	cspReportGroup = "csp-endpoint"
+)
+
+// cspPageTemplate renders a page which tries a handful of things commonly
+// blocked by a Content Security Policy, showing which of them worked.
+var cspPageTemplate = template.Must(template.New("csp").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>CSP test page</title>
+<style>.inline-style { color: green; }</style>
+</head>
+<body>
+<h1>CSP test page</h1>
+<p>{{if .ReportOnly}}Content-Security-Policy-Report-Only{{else}}Content-Security-Policy{{end}}: <code>{{.Policy}}</code></p>
+<p>Violations are reported to <a href="/csp/reports">/csp/reports</a>.</p>
+<ul>
+<li id="inline-script">Inline script: blocked</li>
+<li id="eval">eval(): blocked</li>
+<li class="inline-style">Inline style: green if allowed</li>
+<li style="color: green">Inline style attribute: green if allowed</li>
+<li>Same-origin image: <img src="/images/jpeg" width="32" height="32" alt="blocked"></li>
+<li>Cross-origin image: <img src="https://www.gravatar.com/avatar/?d=identicon&s=32" width="32" height="32" alt="blocked"></li>
+</ul>
+<script>
+document.getElementById("inline-script").textContent = "Inline script: allowed";
+try {
+	eval("document.getElementById('eval').textContent = 'eval(): allowed'");
+} catch (e) {}
+</script>
+</body>
+</html>
+`))
+
+// CSPReport is a stored violation report.
+type CSPReport struct {
+	ID          int            `json:"id"`
+	Received    time.Time      `json:"received"`
+	ContentType string         `json:"content_type" doc:"Content type the report was sent with"`
+	UserAgent   string         `json:"user_agent,omitempty"`
+	Type        string         `json:"type" doc:"Report type, e.g. csp-violation"`
+	URL         string         `json:"url,omitempty" doc:"URL of the document where the violation happened"`
+	Body        map[string]any `json:"body" doc:"Report body as sent by the browser"`
+}
+
+// cspReportsMu protects the stored violation reports.
+var cspReportsMu = sync.Mutex{}
+var cspReports = []CSPReport{}
+var cspReportsNext = 1
+
+// parseCSPReports reads reports in either the legacy `application/csp-report`
+// format from the `report-uri` directive, or the Reporting API format from
+// the `report-to` directive, which may batch several reports together.
+func parseCSPReports(contentType string, body []byte) ([]CSPReport, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if mediaType == "application/csp-report" {
+		var legacy struct {
+			Report map[string]any `json:"csp-report"`
+		}
+		if err := json.Unmarshal(body, &legacy); err != nil {
+			return nil, err
+		}
+		if legacy.Report == nil {
+			return nil, errors.New("missing csp-report object")
+		}
+		uri, _ := legacy.Report["document-uri"].(string)
+		return []CSPReport{{Type: "csp-violation", URL: uri, Body: legacy.Report}}, nil
+	}
+
+	var batch []struct {
+		Type      string         `json:"type"`
+		URL       string         `json:"url"`
+		UserAgent string         `json:"user_agent"`
+		Body      map[string]any `json:"body"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(body), &batch); err != nil {
+		return nil, err
+	}
+	reports := make([]CSPReport, 0, len(batch))
+	for _, r := range batch {
+		reports = append(reports, CSPReport{Type: r.Type, URL: r.URL, UserAgent: r.UserAgent, Body: r.Body})
+	}
+	return reports, nil
+}
+
+type CSPPageResponse struct {
+	ContentType        string `header:"Content-Type"`
+	CacheControl       string `header:"Cache-Control"`
+	CSP                string `header:"Content-Security-Policy"`
+	CSPReportOnly      string `header:"Content-Security-Policy-Report-Only"`
+	ReportingEndpoints string `header:"Reporting-Endpoints"`
+	Body               []byte
+}
+
+func (s *APIServer) RegisterCSP(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-csp-page",
+		Method:      http.MethodGet,
+		Path:        "/csp/page",
+		Description: "Serve an HTML page with the given Content Security Policy. The page tries inline scripts and styles, `eval`, and same and cross-origin images, showing which were allowed. Violations are reported to `/csp/report` unless the policy sets its own reporting directives.",
+		Tags:        []string{"CSP"},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "CSP test page",
+				Content: map[string]*huma.MediaType{
+					"text/html": {Schema: &huma.Schema{Type: huma.TypeString}},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *struct {
+		Policy     string `query:"policy" default:"default-src 'self'" maxLength:"4096" doc:"Content Security Policy to apply to the page"`
+		ReportOnly bool   `query:"report_only" doc:"Send the policy via Content-Security-Policy-Report-Only so nothing is blocked"`
+	}) (*CSPPageResponse, error) {
+		policy := strings.TrimSpace(input.Policy)
+		if !strings.Contains(policy, "report-uri") && !strings.Contains(policy, "report-to") {
+			policy = strings.TrimSuffix(policy, ";") + "; report-uri " + cspReportPath + "; report-to " + cspReportGroup
+		}
+
+		buf := bytes.Buffer{}
+		if err := cspPageTemplate.Execute(&buf, map[string]any{
+			"Policy":     policy,
+			"ReportOnly": input.ReportOnly,
+		}); err != nil {
+			return nil, huma.Error500InternalServerError("unable to render page", err)
+		}
+
+		resp := &CSPPageResponse{
+			ContentType:        "text/html; charset=utf-8",
+			CacheControl:       "no-store",
+			ReportingEndpoints: cspReportGroup + `="` + cspReportPath + `"`,
+			Body:               buf.Bytes(),
+		}
+		if input.ReportOnly {
+			resp.CSPReportOnly = policy
+		} else {
+			resp.CSP = policy
+		}
+		return resp, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "post-csp-report",
+		Method:        http.MethodPost,
+		Path:          cspReportPath,
+		Description:   "Collect Content Security Policy violation reports sent by browsers, either as `application/csp-report` from the `report-uri` directive or `application/reports+json` from the `report-to` directive. Stored reports are available at `/csp/reports`.",
+		Tags:          []string{"CSP"},
+		DefaultStatus: http.StatusNoContent,
+		RequestBody: &huma.RequestBody{
+			Required: true,
+			Content: map[string]*huma.MediaType{
+				"application/csp-report":   {Schema: &huma.Schema{Type: huma.TypeObject}},
+				"application/reports+json": {Schema: &huma.Schema{Type: huma.TypeArray, Items: &huma.Schema{Type: huma.TypeObject}}},
+			},
+		},
+		Errors: []int{http.StatusBadRequest},
+	}, func(ctx context.Context, input *struct {
+		ContentType string `header:"Content-Type"`
+		UserAgent   string `header:"User-Agent"`
+		RawBody     []byte
+	}) (*struct{}, error) {
+		reports, err := parseCSPReports(input.ContentType, input.RawBody)
+		if err != nil {
+			return nil, huma.Error400BadRequest("invalid report", err)
+		}
+
+		cspReportsMu.Lock()
+		defer cspReportsMu.Unlock()
+
+		for _, r := range reports {
+			r.ID = cspReportsNext
+			cspReportsNext++
+			r.Received = time.Now()
+			r.ContentType = input.ContentType
+			if r.UserAgent == "" {
+				r.UserAgent = input.UserAgent
+			}
+			cspReports = append(cspReports, r)
+		}
+		if len(cspReports) > maxCSPReports {
+			cspReports = cspReports[len(cspReports)-maxCSPReports:]
+		}
+		return nil, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-csp-reports",
+		Method:      http.MethodGet,
+		Path:        "/csp/reports",
+		Description: "List recently received Content Security Policy violation reports, newest first.",
+		Tags:        []string{"CSP"},
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body []CSPReport }, error) {
+		cspReportsMu.Lock()
+		defer cspReportsMu.Unlock()
+
+		l := make([]CSPReport, 0, len(cspReports))
+		for i := len(cspReports) - 1; i >= 0; i-- {
+			l = append(l, cspReports[i])
+		}
+		return &struct{ Body []CSPReport }{Body: l}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "delete-csp-reports",
+		Method:        http.MethodDelete,
+		Path:          "/csp/reports",
+		Description:   "Remove all stored violation reports.",
+		Tags:          []string{"CSP"},
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		cspReportsMu.Lock()
+		defer cspReportsMu.Unlock()
+
+		cspReports = []CSPReport{}
+		return nil, nil
+	})
+}
@@ -0,0 +1,130 @@
+package apibin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	csrfCookie = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+)
+
+// readCookie returns the value of a named cookie from a `Cookie` request
+// header, or an empty string if it is not present.
+func readCookie(header, name string) string {
+	r := http.Request{Header: http.Header{"Cookie": {header}}}
+	if c, err := r.Cookie(name); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// CSRFToken describes an issued token and how to send it back.
+type CSRFToken struct {
+	Token     string `json:"token"`
+	Cookie    string `json:"cookie" doc:"Name of the cookie holding the token"`
+	Header    string `json:"header" doc:"Name of the header to send the token in"`
+	FormField string `json:"form_field" doc:"Name of the form field to send the token in, as an alternative to the header"`
+}
+
+type CSRFTokenResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	SetCookie    string `header:"Set-Cookie"`
+	Body         CSRFToken
+}
+
+// CSRFSubmitResult describes a successful protected submission.
+type CSRFSubmitResult struct {
+	OK  bool   `json:"ok"`
+	Via string `json:"via" enum:"header,form" doc:"Where the matching token was found"`
+}
+
+func (s *APIServer) RegisterCSRF(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-csrf-token",
+		Method:      http.MethodGet,
+		Path:        "/csrf/token",
+		Description: "Issue a CSRF token for the double-submit cookie pattern. The token is set as a cookie readable by scripts and returned in the body. Send it back in the `X-CSRF-Token` header or a `csrf_token` form field along with the cookie when submitting.",
+		Tags:        []string{"CSRF"},
+	}, func(ctx context.Context, input *struct{}) (*CSRFTokenResponse, error) {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, huma.Error500InternalServerError("unable to read random bytes", err)
+		}
+		token := hex.EncodeToString(b)
+
+		cookie := &http.Cookie{
+			Name:     csrfCookie,
+			Value:    token,
+			Path:     "/csrf",
+			SameSite: http.SameSiteStrictMode,
+			Secure:   GetClientInfo(ctx).Scheme == "https",
+		}
+
+		return &CSRFTokenResponse{
+			CacheControl: "no-store",
+			SetCookie:    cookie.String(),
+			Body: CSRFToken{
+				Token:     token,
+				Cookie:    csrfCookie,
+				Header:    csrfHeader,
+				FormField: csrfField,
+			},
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-csrf-submit",
+		Method:      http.MethodPost,
+		Path:        "/csrf/submit",
+		Description: "Submit a CSRF-protected request. It is rejected with a 403 unless the `csrf_token` cookie matches the token sent in the `X-CSRF-Token` header or a `csrf_token` form field.",
+		Tags:        []string{"CSRF"},
+		RequestBody: &huma.RequestBody{
+			Description: "Any content. URL-encoded forms may include the token in a `csrf_token` field.",
+			Content: map[string]*huma.MediaType{
+				"application/x-www-form-urlencoded": {Schema: &huma.Schema{
+					Type: huma.TypeObject,
+					Properties: map[string]*huma.Schema{
+						csrfField: {Type: huma.TypeString},
+					},
+				}},
+				"application/octet-stream": {Schema: &huma.Schema{Type: huma.TypeString, Format: "binary"}},
+			},
+		},
+		Errors: []int{http.StatusForbidden},
+	}, func(ctx context.Context, input *struct {
+		Cookie      string `header:"Cookie"`
+		Token       string `header:"X-CSRF-Token"`
+		ContentType string `header:"Content-Type"`
+		RawBody     []byte
+	}) (*struct{ Body CSRFSubmitResult }, error) {
+		expected := readCookie(input.Cookie, csrfCookie)
+		if expected == "" {
+			return nil, huma.Error403Forbidden("missing " + csrfCookie + " cookie, get one from /csrf/token")
+		}
+
+		token, via := input.Token, "header"
+		if mediaType, _, _ := mime.ParseMediaType(input.ContentType); token == "" && mediaType == "application/x-www-form-urlencoded" {
+			if form, err := url.ParseQuery(string(input.RawBody)); err == nil {
+				token, via = form.Get(csrfField), "form"
+			}
+		}
+		if token == "" {
+			return nil, huma.Error403Forbidden("missing CSRF token in the " + csrfHeader + " header or " + csrfField + " form field")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			return nil, huma.Error403Forbidden("CSRF token does not match the " + csrfCookie + " cookie")
+		}
+
+		return &struct{ Body CSRFSubmitResult }{Body: CSRFSubmitResult{OK: true, Via: via}}, nil
+	})
+}
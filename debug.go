@@ -0,0 +1,56 @@
+package apibin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/go-chi/chi/v5"
+)
+
+// debugToken returns the token sent with a request, either as a bearer token
+// or via `?token=` for tools which can't set headers like `go tool pprof`.
+func debugToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
+// DebugAuth requires the given token for debug endpoints when it isn't empty.
+// The write deadline is removed since profiles can take longer than the
+// server's write timeout to collect. The API is passed by reference since the
+// middleware is created before it.
+func DebugAuth(api *huma.API, token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token != "" && subtle.ConstantTimeCompare([]byte(debugToken(r)), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="debug"`)
+				huma.WriteErr(*api, humachi.NewContext(nil, r, w), http.StatusUnauthorized, "a valid debug token is required")
+				return
+			}
+			http.NewResponseController(w).SetWriteDeadline(time.Time{})
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// mountDebug adds the debug endpoints to the router, which aren't part of the
+// API so are left out of the OpenAPI. These are pprof and a list of open
+// connections & in-flight requests. The pprof command line is left out since
+// it would expose the flags, including any secrets, when no token is set.
+func mountDebug(router chi.Router, api *huma.API, token string) {
+	router.Route("/debug", func(r chi.Router) {
+		r.Use(DebugAuth(api, token))
+		r.HandleFunc("/pprof/", pprof.Index)
+		r.HandleFunc("/pprof/profile", pprof.Profile)
+		r.HandleFunc("/pprof/symbol", pprof.Symbol)
+		r.HandleFunc("/pprof/trace", pprof.Trace)
+		r.HandleFunc("/pprof/{profile}", pprof.Index)
+		r.Get("/connections", debugConnections)
+	})
+}
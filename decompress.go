@@ -0,0 +1,152 @@
+package apibin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/klauspost/compress/zstd"
+)
+
+var requestEncodingKey contextKey = "apibin/request-encoding"
+
+// zstdMaxWindow limits the memory used to decode `zstd` request bodies, as
+// HTTP's `zstd` content coding allows encoders to use up to 8 MiB.
+const zstdMaxWindow = 8 << 20
+
+// requestDecoders create readers which decode request bodies for each
+// supported `Content-Encoding`. Note that HTTP's `deflate` is zlib-wrapped.
+var requestDecoders = map[string]func(io.Reader) (io.Reader, error){
+	gzipEncoding: func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	},
+	"x-gzip": func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	},
+	brotliEncoding: func(r io.Reader) (io.Reader, error) {
+		return brotli.NewReader(r), nil
+	},
+	"deflate": func(r io.Reader) (io.Reader, error) {
+		return zlib.NewReader(r)
+	},
+	"zstd": func(r io.Reader) (io.Reader, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// requestEncodings is sent in `Accept-Encoding` when a request body uses an
+// unsupported encoding, as described in RFC 9110 section 12.5.3.
+const requestEncodings = "br, deflate, gzip, zstd"
+
+// RequestEncodingModel describes a request body which was decompressed.
+type RequestEncodingModel struct {
+	ContentEncoding string `json:"content_encoding" doc:"The Content-Encoding request header as sent"`
+	CompressedSize  int    `json:"compressed_size" doc:"Size of the body as sent in bytes"`
+	Size            int    `json:"size" doc:"Size of the decompressed body in bytes"`
+}
+
+// GetRequestEncoding returns how the request body was decompressed, or nil
+// if it wasn't compressed.
+func GetRequestEncoding(ctx context.Context) *RequestEncodingModel {
+	encoding, _ := ctx.Value(requestEncodingKey).(*RequestEncodingModel)
+	return encoding
+}
+
+// decodeBody decodes a body which had the given codings applied in order,
+// reading no more than `limit` + 1 decompressed bytes.
+func decodeBody(codings []string, body []byte, limit int64) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(body)
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		if r, err = requestDecoders[codings[i]](r); err != nil {
+			return nil, fmt.Errorf("invalid %s request body: %w", codings[i], err)
+		}
+		// Some decoders like zstd hold resources until closed.
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+	}
+	decoded, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s request body: %w", strings.Join(codings, ", "), err)
+	}
+	return decoded, nil
+}
+
+// DecompressRequests transparently decodes request bodies sent with a
+// supported `Content-Encoding`, so handlers see the original body. Bodies
+// larger than `limit` bytes once decompressed are rejected, which protects
+// against decompression bombs. Unsupported encodings get a `415 Unsupported
+// Media Type` listing the supported ones. The API is passed by reference
+// since the middleware is created before it.
+func DecompressRequests(api *huma.API, limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Content-Encoding")
+			codings := []string{}
+			for _, c := range strings.Split(header, ",") {
+				if c = strings.ToLower(strings.TrimSpace(c)); c != "" && c != "identity" {
+					codings = append(codings, c)
+				}
+			}
+			if len(codings) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := humachi.NewContext(nil, r, w)
+			for _, c := range codings {
+				if requestDecoders[c] == nil {
+					w.Header().Set("Accept-Encoding", requestEncodings)
+					huma.WriteErr(*api, ctx, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported request content encoding %s, expected one of %s", c, requestEncodings))
+					return
+				}
+			}
+
+			// Compressed bodies should never be larger than they are decompressed,
+			// so the same limit is used for both.
+			body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+			if err != nil {
+				huma.WriteErr(*api, ctx, http.StatusBadRequest, "cannot read request body", err)
+				return
+			}
+			if int64(len(body)) > limit {
+				huma.WriteErr(*api, ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is too large limit=%d bytes", limit))
+				return
+			}
+			decoded, err := decodeBody(codings, body, limit)
+			if err != nil {
+				huma.WriteErr(*api, ctx, http.StatusBadRequest, err.Error())
+				return
+			}
+			if int64(len(decoded)) > limit {
+				huma.WriteErr(*api, ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("decompressed request body is too large limit=%d bytes", limit))
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), requestEncodingKey, &RequestEncodingModel{
+				ContentEncoding: header,
+				CompressedSize:  len(body),
+				Size:            len(decoded),
+			}))
+			r.Header.Del("Content-Encoding")
+			r.Header.Set("Content-Length", strconv.Itoa(len(decoded)))
+			r.ContentLength = int64(len(decoded))
+			r.Body = io.NopCloser(bytes.NewReader(decoded))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
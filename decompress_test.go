@@ -0,0 +1,66 @@
+package apibin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompressRequests(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	body := []byte(`{"hello": "world"}`)
+	gzipped := &bytes.Buffer{}
+	gz := gzip.NewWriter(gzipped)
+	gz.Write(body)
+	gz.Close()
+	enc, _ := zstd.NewWriter(nil)
+	zstded := enc.EncodeAll(body, nil)
+	bomb := enc.EncodeAll(bytes.Repeat([]byte("a"), 2<<20), nil)
+	enc.Close()
+
+	for _, tc := range []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+	}{
+		{"gzip", "gzip", gzipped.Bytes(), http.StatusOK},
+		{"zstd", "zstd", zstded, http.StatusOK},
+		{"zstd invalid", "zstd", body, http.StatusBadRequest},
+		{"zstd too large", "zstd", bomb, http.StatusRequestEntityTooLarge},
+		{"unsupported", "compress", body, http.StatusUnsupportedMediaType},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/", bytes.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", tc.encoding)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, resp.StatusCode)
+			}
+			if tc.status == http.StatusUnsupportedMediaType && !strings.Contains(resp.Header.Get("Accept-Encoding"), "zstd") {
+				t.Errorf("expected zstd in Accept-Encoding, got %q", resp.Header.Get("Accept-Encoding"))
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			var echo struct {
+				Body string `json:"body"`
+			}
+			json.NewDecoder(resp.Body).Decode(&echo)
+			if echo.Body != string(body) {
+				t.Errorf("expected the decompressed body to be echoed, got %q", echo.Body)
+			}
+		})
+	}
+}
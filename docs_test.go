@@ -0,0 +1,54 @@
+package apibin
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// featureBullet is a line of a feature list with its nesting depth.
+type featureBullet struct {
+	depth int
+	text  string
+}
+
+// featureList returns the bullets of the first list in the markdown, where
+// each level of nesting is indented by the given string.
+func featureList(markdown, indent string) []featureBullet {
+	bullets := []featureBullet{}
+	for _, line := range strings.Split(markdown, "\n") {
+		depth := 0
+		for strings.HasPrefix(line, indent) {
+			line = line[len(indent):]
+			depth++
+		}
+		if !strings.HasPrefix(line, "- ") {
+			if len(bullets) > 0 {
+				break
+			}
+			continue
+		}
+		bullets = append(bullets, featureBullet{depth, strings.TrimPrefix(line, "- ")})
+	}
+	return bullets
+}
+
+func TestDocsMatchReadme(t *testing.T) {
+	readme, err := os.ReadFile("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The README may add links to files in the repo, so only the start of
+	// each of its bullets needs to match.
+	want := featureList(string(readme), "  ")
+	got := featureList(docs, "\t")
+	for i := 0; i < len(want) || i < len(got); i++ {
+		if i >= len(want) || i >= len(got) {
+			t.Fatalf("expected %d features, got %d", len(want), len(got))
+		}
+		if got[i].depth != want[i].depth || !strings.HasPrefix(want[i].text, got[i].text) {
+			t.Fatalf("feature %d differs from the README:\n  got  (depth %d) %s\n  want (depth %d) %s", i+1, got[i].depth, got[i].text, want[i].depth, want[i].text)
+		}
+	}
+}
@@ -1,9 +1,11 @@
-package main
+package apibin
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"net/http"
 	"net/url"
 	"strings"
@@ -31,11 +33,20 @@ type EchoModel struct {
 	Method  string            `json:"method" doc:"HTTP method used"`
 	Headers map[string]string `json:"headers" doc:"HTTP headers"`
 	Host    string            `json:"host,omitempty" doc:"Hostname and optional port"`
+	IP      string            `json:"ip,omitempty" doc:"Client IP address, taking trusted proxies into account"`
 	URL     string            `json:"url" doc:"Full URL"`
 	Path    string            `json:"path" doc:"URL path"`
 	Query   map[string]string `json:"query,omitempty" doc:"URL query parameters"`
 	Body    interface{}       `json:"body,omitempty" doc:"Raw request body, either a UTF-8 string or bytes"`
 	Parsed  interface{}       `json:"parsed,omitempty" doc:"Parsed request body"`
+
+	BodySize   int    `json:"body_size,omitempty" doc:"Raw request body size in bytes, when using raw=base64"`
+	BodySHA256 string `json:"body_sha256,omitempty" doc:"Hex-encoded SHA-256 digest of the raw request body, when using raw=base64"`
+
+	Connection *ConnectionModel `json:"connection,omitempty" doc:"Details about the underlying connection"`
+	Access     *AccessModel     `json:"access,omitempty" doc:"Why the client IP was allowed, when allow or deny lists are configured"`
+
+	RequestEncoding *RequestEncodingModel `json:"request_encoding,omitempty" doc:"How the request body was decompressed, if it was sent with a Content-Encoding"`
 }
 
 func genETag(v interface{}) string {
@@ -58,22 +69,20 @@ type EchoResponse struct {
 	LastModified time.Time `header:"Last-Modified"`
 	Vary         string    `header:"Vary"`
 
+	ETagAlgorithm string `header:"X-Apibin-ETag-Algorithm"`
+
 	Body EchoModel
 }
 
-func (s *APIServer) echoHandler(ctx context.Context, input *struct {
-	RequestInfo
-	Status int `query:"status" default:"200" minimum:"100" maximum:"599" doc:"Status code to return"`
-	conditional.Params
-	Body    any
-	RawBody []byte
-}) (*EchoResponse, error) {
+// newEchoModel describes the incoming request, without its body. It is shared
+// by all the echo endpoints regardless of protocol.
+func newEchoModel(ctx huma.Context) EchoModel {
 	headers := map[string]string{}
-	input.ctx.EachHeader(func(name, value string) {
+	ctx.EachHeader(func(name, value string) {
 		headers[name] = value
 	})
 
-	reqURL := input.ctx.URL()
+	reqURL := ctx.URL()
 
 	query := map[string]string{}
 	values, _ := url.ParseQuery(reqURL.RawQuery)
@@ -81,13 +90,37 @@ func (s *APIServer) echoHandler(ctx context.Context, input *struct {
 		query[k] = values.Get(k)
 	}
 
-	scheme := "http"
-	if proto := input.ctx.Header("X-Forwarded-Proto"); proto != "" {
-		scheme = proto
+	client := GetClientInfo(ctx.Context())
+
+	return EchoModel{
+		Method:  ctx.Method(),
+		Headers: headers,
+		Host:    ctx.Host(),
+		IP:      client.IP,
+		URL:     client.Scheme + "://" + client.Host + reqURL.String(),
+		Path:    reqURL.Path,
+		Query:   query,
+
+		Connection: GetConnection(ctx.Context()),
+		Access:     GetAccess(ctx.Context()),
+
+		RequestEncoding: GetRequestEncoding(ctx.Context()),
 	}
+}
 
+func (s *APIServer) echoHandler(ctx context.Context, input *struct {
+	RequestInfo
+	Status int    `query:"status" default:"200" minimum:"100" maximum:"599" doc:"Status code to return"`
+	Raw    string `query:"raw" enum:"base64" doc:"Always return the raw body base64-encoded along with its size and digest"`
+	conditional.Params
+	ETagAlgorithmParams
+	Body    any
+	RawBody []byte
+}) (*EchoResponse, error) {
 	var rawBody any
-	if len(input.RawBody) > 0 {
+	if input.Raw == "base64" {
+		rawBody = base64.StdEncoding.EncodeToString(input.RawBody)
+	} else if len(input.RawBody) > 0 {
 		if utf8.Valid(input.RawBody) {
 			rawBody = string(input.RawBody)
 		} else {
@@ -96,19 +129,19 @@ func (s *APIServer) echoHandler(ctx context.Context, input *struct {
 	}
 
 	resp := &EchoResponse{}
-	resp.Body = EchoModel{
-		Method:  input.ctx.Method(),
-		Headers: headers,
-		Host:    input.ctx.Host(),
-		URL:     scheme + "://" + input.ctx.Host() + reqURL.String(),
-		Path:    reqURL.Path,
-		Query:   query,
-		Body:    rawBody,
-		Parsed:  input.Body,
+	resp.Body = newEchoModel(input.ctx)
+	resp.Body.Body = rawBody
+	resp.Body.Parsed = input.Body
+
+	if input.Raw == "base64" {
+		sum := sha256.Sum256(input.RawBody)
+		resp.Body.BodySize = len(input.RawBody)
+		resp.Body.BodySHA256 = hex.EncodeToString(sum[:])
 	}
 
 	lastModified, _ := time.Parse(time.RFC3339, "2022-02-01T12:34:56Z")
-	etag := genETag(resp)
+	algorithm := s.etagAlgorithm(input.ETagAlgorithmParams)
+	etag := hashETagValue(algorithm, resp)
 
 	if err := input.PreconditionFailed(etag, lastModified); err != nil {
 		return nil, err
@@ -120,6 +153,7 @@ func (s *APIServer) echoHandler(ctx context.Context, input *struct {
 	resp.Vary = "*"
 	resp.LastModified = lastModified
 	resp.ETag = etag
+	resp.ETagAlgorithm = algorithm
 
 	return resp, nil
 }
@@ -0,0 +1,104 @@
+package apibin
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	etagWeak   = "weak"
+	etagStrong = "strong"
+)
+
+// Hash algorithms for content-based ETags.
+const (
+	etagXXH3   = "xxh3"
+	etagSHA256 = "sha256"
+	etagMD5    = "md5"
+)
+
+// validateETagAlgorithm returns an error if the algorithm isn't supported.
+func validateETagAlgorithm(algorithm string) error {
+	switch algorithm {
+	case etagXXH3, etagSHA256, etagMD5:
+		return nil
+	}
+	return fmt.Errorf("invalid etag-algorithm %q, expected one of xxh3, sha256, or md5", algorithm)
+}
+
+// hashETag returns an opaque ETag value for the bytes. The cryptographic
+// digests are hex-encoded, while xxh3 keeps the original shorter format.
+func hashETag(algorithm string, b []byte) string {
+	switch algorithm {
+	case etagSHA256:
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:])
+	case etagMD5:
+		sum := md5.Sum(b)
+		return hex.EncodeToString(sum[:])
+	}
+	return genETagBytes(b)
+}
+
+// hashETagValue is like `hashETag` for a value, hashing its CBOR encoding so
+// the ETag is the same for every format the value can be sent in.
+func hashETagValue(algorithm string, v any) string {
+	m, _ := cbor.Marshal(v)
+	return hashETag(algorithm, m)
+}
+
+// ETagAlgorithmParams lets the client override the server's ETag algorithm.
+type ETagAlgorithmParams struct {
+	ETagAlgorithm string `query:"etag_algorithm" enum:"xxh3,sha256,md5" doc:"Hash used for the ETag, defaulting to the server's --etag-algorithm. The resource is hashed rather than the bytes sent, so the ETag is the same for every format. The one used is sent in X-Apibin-ETag-Algorithm."`
+}
+
+// etagAlgorithm returns the ETag algorithm for the request.
+func (s *APIServer) etagAlgorithm(p ETagAlgorithmParams) string {
+	if p.ETagAlgorithm != "" {
+		return p.ETagAlgorithm
+	}
+	if s.opts != nil && s.opts.ETagAlgorithm != "" {
+		return s.opts.ETagAlgorithm
+	}
+	return etagXXH3
+}
+
+// ETagParams lets the client pick between weak and strong ETag validators.
+type ETagParams struct {
+	ETag string `query:"etag" enum:"weak,strong" doc:"Send a weak (W/\"...\") or strong (\"...\") ETag validator. Weak validators only match using weak comparison, so they work with If-None-Match but never with If-Match or If-Range."`
+}
+
+// formatETag formats an opaque ETag value as the requested validator type.
+// When no type is given the value is returned as-is.
+func formatETag(value, kind string) string {
+	switch kind {
+	case etagWeak:
+		return `W/"` + strings.Trim(value, `"`) + `"`
+	case etagStrong:
+		return `"` + strings.Trim(value, `"`) + `"`
+	}
+	return value
+}
+
+// rejectWeakIfMatch enforces the strong comparison required for `If-Match`
+// by RFC 9110, where weak validators never match. The `conditional` package
+// uses weak comparison for everything, so this must run first.
+func rejectWeakIfMatch(values []string) huma.StatusError {
+	if len(values) == 0 {
+		return nil
+	}
+	for _, v := range values {
+		for _, tag := range strings.Split(v, ",") {
+			if !strings.HasPrefix(strings.TrimSpace(tag), "W/") {
+				return nil
+			}
+		}
+	}
+	return huma.Error412PreconditionFailed("If-Match uses strong comparison, so weak validators never match")
+}
@@ -0,0 +1,233 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/sse"
+)
+
+// maxBookEvents limits how many recent events are kept for replay when a
+// client reconnects with `Last-Event-ID`.
+const maxBookEvents = 100
+
+// maxSSEDuration limits the heartbeat interval & how long a stream lasts
+// before the server disconnects.
+const maxSSEDuration = time.Hour
+
+// SSEParams control the keep-alive & reconnection behavior of an event stream.
+type SSEParams struct {
+	Heartbeat string `query:"heartbeat" doc:"Send a comment line at this interval, like 15s, to keep idle connections open"`
+	Retry     int    `query:"retry" minimum:"0" maximum:"3600000" doc:"Reconnection time in milliseconds sent in the retry field when the stream opens"`
+	DropAfter string `query:"drop_after" doc:"Close the stream after this duration, like 60s, so clients reconnect with Last-Event-ID"`
+
+	heartbeat time.Duration
+	dropAfter time.Duration
+}
+
+// parseSSEDuration parses an optional duration query param.
+func parseSSEDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 || d > maxSSEDuration {
+		return 0, &huma.ErrorDetail{
+			Location: "query." + name,
+			Message:  "expected a duration greater than 0s and up to " + maxSSEDuration.String(),
+			Value:    value,
+		}
+	}
+	return d, nil
+}
+
+func (p *SSEParams) Resolve(ctx huma.Context) []error {
+	errs := []error{}
+	var err error
+	if p.heartbeat, err = parseSSEDuration("heartbeat", p.Heartbeat); err != nil {
+		errs = append(errs, err)
+	}
+	if p.dropAfter, err = parseSSEDuration("drop_after", p.DropAfter); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// BookEvent describes a change to a book.
+type BookEvent struct {
+	ID       string    `json:"id"`
+	URL      string    `json:"url"`
+	Version  string    `json:"version,omitempty"`
+	Modified time.Time `json:"modified"`
+}
+
+// Each event type needs its own Go type so it can be mapped to an SSE event
+// name.
+type (
+	BookCreatedEvent BookEvent
+	BookUpdatedEvent BookEvent
+	BookDeletedEvent BookEvent
+)
+
+// eventsMu protects the event ring buffer and subscribers.
+var eventsMu sync.Mutex
+var bookEvents []sse.Message
+var bookEventsNext = 1
+var bookEventSubs = map[chan sse.Message]struct{}{}
+
+// publishBookEvent records an event and sends it to all subscribers. Slow
+// subscribers drop events rather than block writes, and can catch up using
+// `Last-Event-ID` when reconnecting.
+func publishBookEvent(data any) {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+
+	msg := sse.Message{ID: bookEventsNext, Data: data}
+	bookEventsNext++
+
+	bookEvents = append(bookEvents, msg)
+	if len(bookEvents) > maxBookEvents {
+		bookEvents = bookEvents[len(bookEvents)-maxBookEvents:]
+	}
+
+	for ch := range bookEventSubs {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// newBookEvent creates the event data for a changed book, which is nil when
+// the book was deleted.
+func newBookEvent(id string, b *Book) BookEvent {
+	event := BookEvent{
+		ID:       id,
+		URL:      "/books/" + id,
+		Modified: time.Now(),
+	}
+	if b != nil {
+		event.Version = b.Version()
+		event.Modified = b.modified
+	}
+	return event
+}
+
+// bookEventsAfter returns the stored events after `lastID`. The events lock
+// must be held.
+func bookEventsAfter(lastID int) []sse.Message {
+	events := []sse.Message{}
+	for _, msg := range bookEvents {
+		if msg.ID > lastID {
+			events = append(events, msg)
+		}
+	}
+	return events
+}
+
+// bookEventsSince returns the stored events after `lastID`, which lets a
+// subscriber catch up on events dropped while it was busy.
+func bookEventsSince(lastID int) []sse.Message {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	return bookEventsAfter(lastID)
+}
+
+// subscribeBookEvents returns any stored events after `lastID` along with a
+// channel for new events. Call `unsubscribe` when finished.
+func subscribeBookEvents(lastID int) ([]sse.Message, chan sse.Message, func()) {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+
+	replay := []sse.Message{}
+	if lastID > 0 {
+		replay = bookEventsAfter(lastID)
+	}
+
+	ch := make(chan sse.Message, 16)
+	bookEventSubs[ch] = struct{}{}
+
+	return replay, ch, func() {
+		eventsMu.Lock()
+		defer eventsMu.Unlock()
+		delete(bookEventSubs, ch)
+	}
+}
+
+func (s *APIServer) RegisterBookEvents(api huma.API) {
+	sse.Register(api, huma.Operation{
+		OperationID: "list-book-events",
+		Method:      http.MethodGet,
+		Path:        "/books/events",
+		Description: "Stream an event for every book change, including simulated server-side updates. Reconnect with `Last-Event-ID` to replay recent missed events. Use `heartbeat` to get keep-alive comments, `retry` to set the client's reconnection time, and `drop_after` to have the server disconnect so reconnection can be tested.",
+		Tags:        []string{"Books"},
+		Metadata:    map[string]any{streamingKey: true},
+	}, map[string]any{
+		"created": BookCreatedEvent{},
+		"updated": BookUpdatedEvent{},
+		"deleted": BookDeletedEvent{},
+	}, func(ctx context.Context, input *struct {
+		RequestInfo
+		SSEParams
+		LastEventID int `header:"Last-Event-ID" doc:"ID of the last event received, to replay any missed events"`
+	}, send sse.Sender) {
+		replay, ch, unsubscribe := subscribeBookEvents(input.LastEventID)
+		defer unsubscribe()
+
+		// The sender only writes events, so the retry field & comments are
+		// written directly. Both are ignored by clients as events.
+		w := input.ctx.BodyWriter()
+		flush := func() {
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		if input.Retry > 0 {
+			fmt.Fprintf(w, "retry: %d\n\n", input.Retry)
+			flush()
+		}
+
+		var heartbeat, drop <-chan time.Time
+		if input.heartbeat > 0 {
+			ticker := time.NewTicker(input.heartbeat)
+			defer ticker.Stop()
+			heartbeat = ticker.C
+		}
+		if input.dropAfter > 0 {
+			timer := time.NewTimer(input.dropAfter)
+			defer timer.Stop()
+			drop = timer.C
+		}
+
+		for _, msg := range replay {
+			if err := send(msg); err != nil {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-drop:
+				return
+			case <-heartbeat:
+				if d, ok := w.(interface{ SetWriteDeadline(time.Time) error }); ok {
+					d.SetWriteDeadline(time.Now().Add(sse.WriteTimeout))
+				}
+				if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+					return
+				}
+				flush()
+			case msg := <-ch:
+				if err := send(msg); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
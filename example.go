@@ -1,13 +1,17 @@
-package main
+package apibin
 
 import (
 	"context"
 	_ "embed"
 	"encoding/json"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/conditional"
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/exp/slices"
 )
 
 type Resume struct {
@@ -22,8 +26,8 @@ type Resume struct {
 type Basics struct {
 	Name     string    `json:"name"`
 	Label    string    `json:"label,omitempty" example:"Web Developer"`
-	Email    string    `json:"email,omitempty" example:"thomas@gmail.com"`
-	Image    string    `json:"image,omitempty" doc:"URL (as per RFC 3986) to a image in JPEG or PNG format"`
+	Email    string    `json:"email,omitempty" format:"email" example:"thomas@gmail.com"`
+	Image    string    `json:"image,omitempty" format:"uri" doc:"URL (as per RFC 3986) to a image in JPEG or PNG format"`
 	Phone    string    `json:"phone,omitempty" doc:"Phone numbers are stored as strings so use any format you like, e.g. 712-117-2923"`
 	URL      string    `json:"url,omitempty" format:"uri" doc:"URL (as per RFC 3986) to your website, e.g. personal homepage"`
 	Summary  string    `json:"summary,omitempty" doc:"Write a short 2-3 sentence biography about yourself"`
@@ -37,7 +41,7 @@ type Location struct {
 	Address     string `json:"address,omitempty" doc:"To add multiple address lines, use \n. For example, 1234 Glücklichkeit Straße\nHinterhaus 5. Etage li."`
 	PostalCode  string `json:"postalCode,omitempty"`
 	City        string `json:"city,omitempty"`
-	CountryCode string `json:"countryCode,omitempty" doc:"code as per ISO-3166-1 ALPHA-2, e.g. US, AU, IN"`
+	CountryCode string `json:"countryCode,omitempty" pattern:"^[A-Z]{2}$" doc:"code as per ISO-3166-1 ALPHA-2, e.g. US, AU, IN"`
 	Region      string `json:"region,omitempty" doc:"The general region where you live. Can be a US state, or a province, for instance."`
 }
 
@@ -62,7 +66,7 @@ type Work struct {
 type Volunteer struct {
 	Organization string     `json:"organization,omitempty"`
 	Position     string     `json:"position,omitempty"`
-	URL          string     `json:"url,omitempty"`
+	URL          string     `json:"url,omitempty" format:"uri"`
 	StartDate    *time.Time `json:"startDate,omitempty"`
 	EndDate      *time.Time `json:"endDate,omitempty"`
 	Summary      string     `json:"summary,omitempty"`
@@ -94,32 +98,77 @@ var example Resume
 
 //go:embed example.json
 var exampleBytes []byte
-var exampleEtag = genETagBytes(exampleBytes)
 
-//go:embed images/dragonfly.jpg
-var exampleJPEG []byte
+// exampleImages maps each sample image type to its file, which is shared with
+// the static file server.
+var exampleImages = map[string]string{
+	"jpeg": "images/dragonfly.jpg",
+	"webp": "images/origami.webp",
+	"gif":  "images/soup.gif",
+	"png":  "images/station.png",
+	"heic": "images/glass.heic",
+}
+
+func init() {
+	if err := json.Unmarshal(exampleBytes, &example); err != nil {
+		panic(err)
+	}
+}
+
+// maxSandboxes limits how many sandboxes have a stored resume. The least
+// recently written is dropped first.
+const maxSandboxes = 1000
 
-//go:embed images/origami.webp
-var exampleWEBP []byte
+// storedResume is a resume written to a sandbox.
+type storedResume struct {
+	resume   Resume
+	data     []byte
+	modified time.Time
+}
 
-//go:embed images/soup.gif
-var exampleGIF []byte
+// etag returns the resume's ETag using the given algorithm. The embedded
+// example hashes its file while written resumes hash their CBOR encoding.
+func (r storedResume) etag(algorithm string) string {
+	return hashETag(algorithm, r.data)
+}
 
-//go:embed images/station.png
-var examplePNG []byte
+// embeddedResume is the resume in sandboxes which haven't written one.
+func embeddedResume() storedResume {
+	return storedResume{resume: example, data: exampleBytes, modified: staticModified}
+}
 
-//go:embed images/glass.heic
-var exampleHeic []byte
+// sandboxResumes holds the resumes written via `PUT /example`, keyed by the
+// sandbox header so clients don't overwrite each other's.
+var sandboxResumes = struct {
+	sync.Mutex
+	resumes map[string]*storedResume
+	order   []string
+}{resumes: map[string]*storedResume{}}
 
-func init() {
-	if err := json.Unmarshal(exampleBytes, &example); err != nil {
-		panic(err)
+// sandboxResume returns the resume for a sandbox, which is the embedded
+// example until one is written. The embedded data is fixed when the server is
+// built, like the static files, so it shares their modification time.
+func sandboxResume(sandbox string) storedResume {
+	sandboxResumes.Lock()
+	defer sandboxResumes.Unlock()
+	if stored := sandboxResumes.resumes[sandbox]; sandbox != "" && stored != nil {
+		return *stored
 	}
+	return embeddedResume()
+}
+
+// SandboxParams selects a sandbox holding a separate copy of a resource.
+type SandboxParams struct {
+	Sandbox string `header:"X-Apibin-Sandbox" maxLength:"64" pattern:"^[A-Za-z0-9_.-]*$" doc:"Sandbox holding a separate copy of the resume, e.g. a random ID per client. Without one, the embedded example is used."`
 }
 
 type ExampleResponse struct {
-	ETag string `header:"ETag"`
-	Body Resume
+	CacheControl  string    `header:"Cache-Control"`
+	ETag          string    `header:"ETag"`
+	ETagAlgorithm string    `header:"X-Apibin-ETag-Algorithm"`
+	LastModified  time.Time `header:"Last-Modified"`
+	Vary          string    `header:"Vary"`
+	Body          Resume
 }
 
 func (s *APIServer) RegisterExample(api huma.API) {
@@ -127,12 +176,107 @@ func (s *APIServer) RegisterExample(api huma.API) {
 		OperationID: "get-example",
 		Method:      http.MethodGet,
 		Path:        "/example",
-		Description: "Example large structured data response",
+		Description: "Example large structured data response: a [JSON Resume](https://jsonresume.org/) with nested objects, arrays, dates, and URLs. Conditional requests using the `ETag` or `Last-Modified` get a `304 Not Modified`. Pass a sandbox to get the resume written to it, if any.",
 		Tags:        []string{"Example"},
-	}, func(ctx context.Context, i *struct{}) (*ExampleResponse, error) {
+	}, func(ctx context.Context, input *struct {
+		RequestInfo
+		conditional.Params
+		ETagParams
+		ETagAlgorithmParams
+		FilterParams
+		SandboxParams
+	}) (*ExampleResponse, error) {
+		if err := input.CheckFilter(); err != nil {
+			return nil, err
+		}
+
+		current := sandboxResume(input.Sandbox)
+		algorithm := s.etagAlgorithm(input.ETagAlgorithmParams)
+		etag := formatETag(current.etag(algorithm), input.ETag)
+		if err := input.PreconditionFailed(current.etag(algorithm), current.modified); err != nil {
+			// A 304 should have the same validators and caching headers as a 200.
+			input.ctx.SetHeader("ETag", etag)
+			input.ctx.SetHeader("X-Apibin-ETag-Algorithm", algorithm)
+			input.ctx.SetHeader("Cache-Control", exampleCacheControl(input.Sandbox))
+			return nil, err
+		}
+
 		return &ExampleResponse{
-			ETag: exampleEtag,
-			Body: example,
+			CacheControl:  exampleCacheControl(input.Sandbox),
+			ETag:          etag,
+			ETagAlgorithm: algorithm,
+			LastModified:  current.modified,
+			Vary:          "Accept, Accept-Encoding, X-Apibin-Sandbox",
+			Body:          current.resume,
 		}, nil
 	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-example",
+		Method:      http.MethodPut,
+		Path:        "/example",
+		Description: "Replace the resume in a sandbox, validating it against the schema including dates, URIs, emails, and ISO 3166-1 country codes. The stored resume is returned. Use `If-Match` with the current `ETag` to avoid overwriting someone else's changes, or `If-None-Match: *` to only write if nothing has been written to the sandbox yet.",
+		Tags:        []string{"Example"},
+	}, func(ctx context.Context, input *struct {
+		conditional.Params
+		ETagParams
+		ETagAlgorithmParams
+		Sandbox string `header:"X-Apibin-Sandbox" required:"true" minLength:"1" maxLength:"64" pattern:"^[A-Za-z0-9_.-]*$" doc:"Sandbox to write to, so writes don't affect other clients"`
+		Body    Resume
+	}) (*ExampleResponse, error) {
+		sandboxResumes.Lock()
+		defer sandboxResumes.Unlock()
+
+		algorithm := s.etagAlgorithm(input.ETagAlgorithmParams)
+		existing := sandboxResumes.resumes[input.Sandbox]
+		if input.HasConditionalParams() {
+			if err := rejectWeakIfMatch(input.IfMatch); err != nil {
+				return nil, err
+			}
+			current := embeddedResume()
+			if existing != nil {
+				current = *existing
+			} else {
+				// Reads of an unwritten sandbox fall back to the embedded example,
+				// but there's no stored resume for `*` to match.
+				input.IfNoneMatch = slices.DeleteFunc(input.IfNoneMatch, func(v string) bool { return v == "*" })
+			}
+			if err := input.PreconditionFailed(current.etag(algorithm), current.modified); err != nil {
+				return nil, err
+			}
+		}
+
+		data, _ := cbor.Marshal(input.Body)
+		stored := &storedResume{
+			resume:   input.Body,
+			data:     data,
+			modified: time.Now().UTC().Truncate(time.Second),
+		}
+		if existing != nil {
+			sandboxResumes.order = slices.DeleteFunc(sandboxResumes.order, func(v string) bool { return v == input.Sandbox })
+		}
+		sandboxResumes.resumes[input.Sandbox] = stored
+		sandboxResumes.order = append(sandboxResumes.order, input.Sandbox)
+		if len(sandboxResumes.order) > maxSandboxes {
+			delete(sandboxResumes.resumes, sandboxResumes.order[0])
+			sandboxResumes.order = sandboxResumes.order[1:]
+		}
+
+		return &ExampleResponse{
+			CacheControl:  "no-cache",
+			ETag:          formatETag(stored.etag(algorithm), input.ETag),
+			ETagAlgorithm: algorithm,
+			LastModified:  stored.modified,
+			Body:          stored.resume,
+		}, nil
+	})
+}
+
+// exampleCacheControl lets the embedded example be cached, while sandboxes
+// must be revalidated since they can change at any time.
+func exampleCacheControl(sandbox string) string {
+	if sandbox != "" {
+		return "no-cache"
+	}
+	return "public, max-age=3600"
 }
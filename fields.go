@@ -0,0 +1,113 @@
+package apibin
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// FieldsParams lets the client request a sparse fieldset, returning only the
+// named fields of each resource in the response.
+type FieldsParams struct {
+	Fields string `query:"fields" example:"title,author" doc:"Comma-separated list of fields to include in the response. Unknown fields result in a validation error."`
+}
+
+// names returns the requested field names.
+func (p FieldsParams) names() []string {
+	names := []string{}
+	for _, name := range strings.Split(p.Fields, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CheckFields returns a validation error if any requested field is not a JSON
+// field of the given struct value.
+func (p FieldsParams) CheckFields(v any) error {
+	allowed := map[string]bool{}
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.IsExported() && name != "-" {
+			if name == "" {
+				name = f.Name
+			}
+			allowed[name] = true
+		}
+	}
+
+	errs := []error{}
+	for _, name := range p.names() {
+		if !allowed[name] {
+			errs = append(errs, &huma.ErrorDetail{
+				Message:  "unknown field " + name,
+				Location: "query.fields",
+				Value:    p.Fields,
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return huma.Error422UnprocessableEntity("validation failed", errs...)
+	}
+	return nil
+}
+
+// hasQueryParam returns whether the operation declares a query param.
+func hasQueryParam(op *huma.Operation, name string) bool {
+	if op == nil {
+		return false
+	}
+	for _, p := range op.Parameters {
+		if p.In == "query" && p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// projectFields removes all but the named fields (and `$schema`) from an
+// object, or from each object in an array.
+func projectFields(v any, names []string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		projected := map[string]any{}
+		for _, name := range append(names, "$schema") {
+			if value, ok := t[name]; ok {
+				projected[name] = value
+			}
+		}
+		return projected
+	case []any:
+		for i := range t {
+			t[i] = projectFields(t[i], names)
+		}
+	}
+	return v
+}
+
+// SparseFields is a response transformer which applies `?fields=` to
+// successful responses of operations that declare the parameter. Operations
+// are responsible for validating the requested fields via `CheckFields`.
+func SparseFields(ctx huma.Context, status string, v any) (any, error) {
+	fields := FieldsParams{Fields: ctx.Query("fields")}
+	names := fields.names()
+	if len(names) == 0 || !strings.HasPrefix(status, "2") || !hasQueryParam(ctx.Operation(), "fields") {
+		return v, nil
+	}
+
+	// Round-trip through JSON to get the field names as they will be sent.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return v, err
+	}
+	return projectFields(generic, names), nil
+}
@@ -0,0 +1,62 @@
+package apibin
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/shorthand/v2"
+)
+
+// FilterParams lets the client filter and project the response on the server
+// using a shorthand query, the same syntax Restish uses for client-side
+// filtering.
+type FilterParams struct {
+	Filter string `query:"filter" example:"{title, author}" doc:"Shorthand query to filter and project the response, see https://github.com/danielgtaylor/shorthand#querying"`
+}
+
+// CheckFilter returns a validation error if the query syntax is invalid. This
+// must be called by the operation, since errors can't be returned once the
+// response is being transformed.
+func (p FilterParams) CheckFilter() error {
+	if p.Filter == "" {
+		return nil
+	}
+	if _, _, err := shorthand.GetPath(p.Filter, map[string]any{}, shorthand.GetOptions{}); err != nil {
+		return huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+			Message:  err.Error(),
+			Location: "query.filter",
+			Value:    p.Filter,
+		})
+	}
+	return nil
+}
+
+// FilterResponse is a response transformer which applies `?filter=` to
+// successful responses of operations that declare the parameter. If nothing
+// matches then `null` is returned.
+func FilterResponse(ctx huma.Context, status string, v any) (any, error) {
+	filter := ctx.Query("filter")
+	if filter == "" || !strings.HasPrefix(status, "2") || !hasQueryParam(ctx.Operation(), "filter") {
+		return v, nil
+	}
+
+	// Round-trip through JSON so the query uses the field names as they will
+	// be sent.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return v, err
+	}
+
+	result, _, serr := shorthand.GetPath(filter, generic, shorthand.GetOptions{})
+	if serr != nil {
+		// Only syntax errors are possible and those are caught by `CheckFilter`,
+		// so just send the unfiltered response.
+		return v, nil
+	}
+	return result, nil
+}
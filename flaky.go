@@ -0,0 +1,113 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// flakyExpiry is how long a key's attempts are remembered after its last
+	// request, so it fails again when retried much later.
+	flakyExpiry = 5 * time.Minute
+
+	// maxFlakyKeys limits how many keys are tracked. The least recently used
+	// are removed first.
+	maxFlakyKeys = 10000
+)
+
+// flakyKey tracks the requests made for a key.
+type flakyKey struct {
+	attempts int
+	last     time.Time
+}
+
+// flakyMu protects the attempts for each key.
+var flakyMu = sync.Mutex{}
+var flakyKeys = map[string]*flakyKey{}
+var flakyOrder = []string{}
+
+// flakyAttempt records a request for a key, returning which attempt it is.
+func flakyAttempt(key string, now time.Time) int {
+	flakyMu.Lock()
+	defer flakyMu.Unlock()
+
+	k := flakyKeys[key]
+	if k == nil || now.Sub(k.last) > flakyExpiry {
+		k = &flakyKey{}
+		flakyKeys[key] = k
+	}
+	k.attempts++
+	k.last = now
+
+	flakyOrder = slices.DeleteFunc(flakyOrder, func(v string) bool { return v == key })
+	flakyOrder = append(flakyOrder, key)
+	for len(flakyOrder) > maxFlakyKeys {
+		delete(flakyKeys, flakyOrder[0])
+		flakyOrder = flakyOrder[1:]
+	}
+	return k.attempts
+}
+
+// FlakyModel describes a successful request to `/flaky`.
+type FlakyModel struct {
+	Key      string `json:"key"`
+	Attempt  int    `json:"attempt" doc:"Which request for the key this was, starting at 1"`
+	Failures int    `json:"failures" doc:"Number of requests which failed before succeeding"`
+}
+
+type FlakyResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Attempt      int    `header:"X-Flaky-Attempt"`
+	Body         FlakyModel
+}
+
+func (s *APIServer) RegisterFlaky(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-flaky",
+		Method:      http.MethodGet,
+		Path:        "/flaky",
+		Description: fmt.Sprintf("Fail the first requests for a key with an error status, then succeed, for testing retries with backoff. Each response includes the attempt number in `X-Flaky-Attempt`. A key's attempts are forgotten %s after its last request, so use a new key for each test run.", flakyExpiry),
+		Tags:        []string{"Flaky"},
+		Responses: map[string]*huma.Response{
+			"503": {Description: "Service Unavailable"},
+		},
+	}, func(ctx context.Context, input *struct {
+		RequestInfo
+		Failures   int    `query:"failures" default:"3" minimum:"0" maximum:"100" doc:"Number of requests which fail before succeeding"`
+		Status     int    `query:"status" default:"503" minimum:"400" maximum:"599" doc:"Status code of the failed requests"`
+		Key        string `query:"key" maxLength:"256" doc:"Key to count attempts for. Defaults to the client IP."`
+		RetryAfter int    `query:"retry-after" minimum:"0" maximum:"3600" doc:"Send a Retry-After header with this many seconds on failures"`
+	}) (*FlakyResponse, error) {
+		key := input.Key
+		if key == "" {
+			key = GetClientInfo(ctx).IP
+		}
+		attempt := flakyAttempt(key, time.Now())
+
+		if attempt <= input.Failures {
+			input.ctx.SetHeader("Cache-Control", "no-store")
+			input.ctx.SetHeader("X-Flaky-Attempt", strconv.Itoa(attempt))
+			if input.RetryAfter > 0 {
+				input.ctx.SetHeader("Retry-After", strconv.Itoa(input.RetryAfter))
+			}
+			return nil, huma.NewError(input.Status, fmt.Sprintf("attempt %d of %d failing for key %s", attempt, input.Failures, key))
+		}
+
+		return &FlakyResponse{
+			CacheControl: "no-store",
+			Attempt:      attempt,
+			Body: FlakyModel{
+				Key:      key,
+				Attempt:  attempt,
+				Failures: input.Failures,
+			},
+		}, nil
+	})
+}
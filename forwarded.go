@@ -0,0 +1,233 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type contextKey string
+
+var clientInfoKey contextKey = "apibin/client-info"
+
+// ClientInfo describes the original client request, taking any trusted
+// proxies into account.
+type ClientInfo struct {
+	// IP is the client's IP address.
+	IP string
+
+	// Scheme is the URL scheme the client used, e.g. `http` or `https`.
+	Scheme string
+
+	// Host is the host the client requested.
+	Host string
+}
+
+// GetClientInfo returns the client info for a request context. If the
+// `ForwardedHeaders` middleware did not run, then the zero value is returned.
+func GetClientInfo(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey).(ClientInfo)
+	return info
+}
+
+// parseCIDRs parses a comma-separated list of CIDRs. Plain IP addresses are
+// treated as a single-address range.
+func parseCIDRs(value string) ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", part)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// containsIP returns whether any of the networks contain the given IP.
+func containsIP(nets []*net.IPNet, value string) bool {
+	return matchIP(nets, value) != nil
+}
+
+// matchIP returns the first network containing the given IP, or nil if none
+// do.
+func matchIP(nets []*net.IPNet, value string) *net.IPNet {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return n
+		}
+	}
+	return nil
+}
+
+// forwardedHop is a single proxy hop, as described by either an RFC 7239
+// `Forwarded` element or the `X-Forwarded-*` headers.
+type forwardedHop struct {
+	For   string
+	Proto string
+	Host  string
+}
+
+// stripPort removes brackets and an optional port from a node identifier,
+// e.g. `[2001:db8::1]:4711` becomes `2001:db8::1`.
+func stripPort(node string) string {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return strings.Trim(node, "[]")
+}
+
+// splitQuoted splits on a separator, ignoring any within double quotes.
+func splitQuoted(value string, sep rune) []string {
+	parts := []string{}
+	quoted := false
+	start := 0
+	for i, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// parseForwarded parses RFC 7239 `Forwarded` headers into hops, ordered from
+// the client to the nearest proxy.
+func parseForwarded(values []string) []forwardedHop {
+	hops := []forwardedHop{}
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			hop := forwardedHop{}
+			for _, pair := range splitQuoted(element, ';') {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				v = strings.Trim(v, "\"")
+				switch strings.ToLower(k) {
+				case "for":
+					hop.For = stripPort(v)
+				case "proto":
+					hop.Proto = strings.ToLower(v)
+				case "host":
+					hop.Host = v
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseXForwarded converts `X-Forwarded-For`, `X-Forwarded-Proto`, and
+// `X-Forwarded-Host` headers into hops. Since there is no standard way to
+// associate the protocol & host with a hop, the last values, which were set
+// by the nearest proxy, are attached to the last hop. Earlier values may
+// have come from the client.
+func parseXForwarded(h http.Header) []forwardedHop {
+	hops := []forwardedHop{}
+	for _, value := range h.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(value, ",") {
+			hops = append(hops, forwardedHop{For: stripPort(strings.TrimSpace(ip))})
+		}
+	}
+
+	last := func(name string) string {
+		values := h.Values(name)
+		if len(values) == 0 {
+			return ""
+		}
+		value := values[len(values)-1]
+		return strings.TrimSpace(value[strings.LastIndex(value, ",")+1:])
+	}
+
+	if len(hops) == 0 {
+		hops = append(hops, forwardedHop{})
+	}
+	hops[len(hops)-1].Proto = strings.ToLower(last("X-Forwarded-Proto"))
+	hops[len(hops)-1].Host = last("X-Forwarded-Host")
+	return hops
+}
+
+// resolveClient determines the original client info for a request. Proxy
+// headers are only honored when they were added by a trusted proxy, walking
+// from the nearest hop back toward the client until an untrusted address is
+// found. This prevents clients from spoofing their address or scheme.
+func resolveClient(r *http.Request, trusted []*net.IPNet) ClientInfo {
+	info := ClientInfo{
+		IP:     stripPort(r.RemoteAddr),
+		Scheme: "http",
+		Host:   r.Host,
+	}
+	if r.TLS != nil {
+		info.Scheme = "https"
+	}
+
+	if !containsIP(trusted, info.IP) {
+		return info
+	}
+
+	var hops []forwardedHop
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		hops = parseForwarded(values)
+	} else {
+		hops = parseXForwarded(r.Header)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if hop.For != "" {
+			info.IP = hop.For
+		}
+		if hop.Proto != "" {
+			info.Scheme = hop.Proto
+		}
+		if hop.Host != "" {
+			info.Host = hop.Host
+		}
+		if !containsIP(trusted, info.IP) {
+			break
+		}
+	}
+
+	return info
+}
+
+// ForwardedHeaders resolves the original client IP, scheme, and host for each
+// request and stores them in the request context. See `GetClientInfo`.
+func ForwardedHeaders(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := resolveClient(r, trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientInfoKey, info)))
+		})
+	}
+}
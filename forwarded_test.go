@@ -0,0 +1,33 @@
+package apibin
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResolveClient(t *testing.T) {
+	trusted, _ := parseCIDRs("10.0.0.0/8")
+
+	for _, tc := range []struct {
+		name   string
+		remote string
+		header map[string]string
+		want   ClientInfo
+	}{
+		{"untrusted", "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https"}, ClientInfo{"192.0.2.1", "http", "example.com"}},
+		{"x-forwarded", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"}, ClientInfo{"198.51.100.1", "https", "api.example.com"}},
+		{"x-forwarded spoofed", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "evil.example.com, api.example.com"}, ClientInfo{"198.51.100.1", "http", "api.example.com"}},
+		{"forwarded", "10.0.0.1:1234", map[string]string{"Forwarded": `for=198.51.100.1;proto=https, for="10.0.0.2"`}, ClientInfo{"198.51.100.1", "https", "example.com"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+			r.RemoteAddr = tc.remote
+			for name, value := range tc.header {
+				r.Header.Set(name, value)
+			}
+			if got := resolveClient(r, trusted); got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
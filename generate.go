@@ -0,0 +1,304 @@
+package apibin
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Word lists used to generate fake data. They are deliberately small but
+// varied enough to produce realistic looking records.
+var (
+	fakeFirstNames = []string{"Ada", "Alan", "Amara", "Ana", "Arjun", "Beatriz", "Carlos", "Chen", "Chloe", "Dmitri", "Elena", "Emeka", "Fatima", "Grace", "Hana", "Hiroshi", "Ines", "Ivan", "Jamal", "Julia", "Kai", "Lars", "Leila", "Liam", "Lucia", "Mateo", "Mei", "Nadia", "Noah", "Olga", "Omar", "Priya", "Rafael", "Sofia", "Sven", "Tariq", "Yara", "Yusuf", "Zoe", "Zanele"}
+	fakeLastNames  = []string{"Abebe", "Andersson", "Bauer", "Chen", "Costa", "Dubois", "Garcia", "Gonzalez", "Hansen", "Ibrahim", "Ivanova", "Jensen", "Kim", "Kowalski", "Kumar", "Lopez", "Martin", "Moreau", "Müller", "Nakamura", "Nguyen", "Novak", "Okafor", "Olsen", "Patel", "Petrov", "Rossi", "Santos", "Schmidt", "Silva", "Smith", "Suzuki", "Tanaka", "Taylor", "Wang", "Williams", "Yilmaz"}
+	fakeStreets    = []string{"Maple", "Oak", "Pine", "Cedar", "Elm", "Birch", "Willow", "Lake", "Hill", "River", "Park", "Sunset", "Highland", "Meadow", "Forest", "Church", "Mill", "Station", "Harbor", "Spring"}
+	fakeSuffixes   = []string{"St", "Ave", "Rd", "Blvd", "Ln", "Way", "Ct", "Dr", "Pl", "Ter"}
+	fakeCities     = []struct {
+		City, Region, Country string
+		Lat, Lng              float64
+	}{
+		{"Seattle", "WA", "US", 47.61, -122.33},
+		{"Austin", "TX", "US", 30.27, -97.74},
+		{"Boston", "MA", "US", 42.36, -71.06},
+		{"Denver", "CO", "US", 39.74, -104.99},
+		{"Portland", "OR", "US", 45.52, -122.68},
+		{"Toronto", "ON", "CA", 43.65, -79.38},
+		{"Vancouver", "BC", "CA", 49.28, -123.12},
+		{"London", "England", "GB", 51.51, -0.13},
+		{"Manchester", "England", "GB", 53.48, -2.24},
+		{"Berlin", "Berlin", "DE", 52.52, 13.4},
+		{"Munich", "Bavaria", "DE", 48.14, 11.58},
+		{"Paris", "Île-de-France", "FR", 48.86, 2.35},
+		{"Lyon", "Auvergne-Rhône-Alpes", "FR", 45.76, 4.84},
+		{"Madrid", "Madrid", "ES", 40.42, -3.7},
+		{"Lisbon", "Lisboa", "PT", 38.72, -9.14},
+		{"Amsterdam", "North Holland", "NL", 52.37, 4.9},
+		{"Stockholm", "Stockholm", "SE", 59.33, 18.07},
+		{"Tokyo", "Tokyo", "JP", 35.68, 139.69},
+		{"Osaka", "Osaka", "JP", 34.69, 135.5},
+		{"Sydney", "NSW", "AU", -33.87, 151.21},
+		{"Melbourne", "VIC", "AU", -37.81, 144.96},
+		{"São Paulo", "SP", "BR", -23.55, -46.63},
+		{"Mexico City", "CDMX", "MX", 19.43, -99.13},
+		{"Lagos", "Lagos", "NG", 6.52, 3.38},
+		{"Nairobi", "Nairobi", "KE", -1.29, 36.82},
+	}
+	fakeCompanyWords    = []string{"Acme", "Apex", "Blue", "Bright", "Cloud", "Crest", "Delta", "Echo", "Falcon", "Granite", "Harbor", "Iron", "Lumen", "Nimbus", "North", "Orbit", "Pioneer", "Quantum", "Summit", "Vertex"}
+	fakeCompanySuffixes = []string{"Inc.", "LLC", "Ltd.", "GmbH", "Group", "Labs", "Systems", "Partners", "Co.", "Industries"}
+	fakeIndustries      = []string{"Software", "Finance", "Healthcare", "Retail", "Logistics", "Energy", "Education", "Media", "Manufacturing", "Hospitality"}
+	fakeDomains         = []string{"example.com", "example.org", "example.net"}
+)
+
+// pick returns a random item from a list.
+func pick[T any](r *rand.Rand, items []T) T {
+	return items[r.Intn(len(items))]
+}
+
+// FakePerson is a generated person.
+type FakePerson struct {
+	ID        int         `json:"id"`
+	FirstName string      `json:"first_name"`
+	LastName  string      `json:"last_name"`
+	Email     string      `json:"email" format:"email"`
+	Phone     string      `json:"phone"`
+	Birthday  string      `json:"birthday" format:"date"`
+	Address   FakeAddress `json:"address"`
+}
+
+// FakeAddress is a generated postal address.
+type FakeAddress struct {
+	Street     string  `json:"street"`
+	City       string  `json:"city"`
+	Region     string  `json:"region"`
+	PostalCode string  `json:"postal_code"`
+	Country    string  `json:"country" doc:"ISO 3166-1 alpha-2 country code"`
+	Latitude   float64 `json:"latitude" minimum:"-90" maximum:"90"`
+	Longitude  float64 `json:"longitude" minimum:"-180" maximum:"180"`
+}
+
+// FakeCompany is a generated company.
+type FakeCompany struct {
+	ID        int         `json:"id"`
+	Name      string      `json:"name"`
+	Industry  string      `json:"industry"`
+	Website   string      `json:"website" format:"uri"`
+	Employees int         `json:"employees"`
+	Founded   int         `json:"founded"`
+	Address   FakeAddress `json:"address"`
+}
+
+func fakeAddress(r *rand.Rand) FakeAddress {
+	c := pick(r, fakeCities)
+	return FakeAddress{
+		Street:     fmt.Sprintf("%d %s %s", 1+r.Intn(9999), pick(r, fakeStreets), pick(r, fakeSuffixes)),
+		City:       c.City,
+		Region:     c.Region,
+		PostalCode: fmt.Sprintf("%05d", r.Intn(100000)),
+		Country:    c.Country,
+		Latitude:   math.Round((c.Lat+float64(r.Intn(200)-100)/1000)*1000) / 1000,
+		Longitude:  math.Round((c.Lng+float64(r.Intn(200)-100)/1000)*1000) / 1000,
+	}
+}
+
+func fakePerson(r *rand.Rand, id int) FakePerson {
+	first := pick(r, fakeFirstNames)
+	last := pick(r, fakeLastNames)
+	birthday := time.Date(1940+r.Intn(65), time.Month(1+r.Intn(12)), 1+r.Intn(28), 0, 0, 0, 0, time.UTC)
+	return FakePerson{
+		ID:        id,
+		FirstName: first,
+		LastName:  last,
+		Email:     authorID(first) + "." + authorID(last) + "@" + pick(r, fakeDomains),
+		Phone:     fmt.Sprintf("+1-555-%03d-%04d", r.Intn(1000), r.Intn(10000)),
+		Birthday:  birthday.Format("2006-01-02"),
+		Address:   fakeAddress(r),
+	}
+}
+
+func fakeCompany(r *rand.Rand, id int) FakeCompany {
+	name := pick(r, fakeCompanyWords) + " " + pick(r, fakeCompanyWords)
+	return FakeCompany{
+		ID:        id,
+		Name:      name + " " + pick(r, fakeCompanySuffixes),
+		Industry:  pick(r, fakeIndustries),
+		Website:   "https://" + authorID(name) + "." + pick(r, fakeDomains),
+		Employees: 1 + r.Intn(50000),
+		Founded:   1900 + r.Intn(124),
+		Address:   fakeAddress(r),
+	}
+}
+
+// GenerateParams controls how many records are generated and from which seed.
+type GenerateParams struct {
+	Count int   `query:"count" default:"10" minimum:"1" maximum:"1000" doc:"Number of records to generate"`
+	Seed  int64 `query:"seed" doc:"Seed for the generator. The same seed always produces the same records. Defaults to a random seed, which is returned in the X-Seed header."`
+}
+
+// rand returns a generator for the params and the seed used.
+func (p GenerateParams) rand() (*rand.Rand, int64) {
+	seed := p.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed)), seed
+}
+
+type GenerateResponse[T any] struct {
+	Seed string `header:"X-Seed"`
+	Body []T
+}
+
+// registerGenerator registers an operation generating a list of fake records.
+func registerGenerator[T any](api huma.API, kind string, generate func(r *rand.Rand, id int) T) {
+	huma.Register(api, huma.Operation{
+		OperationID: "generate-" + kind,
+		Method:      http.MethodGet,
+		Path:        "/generate/" + kind,
+		Description: "Generate realistic fake " + kind + ". Records are deterministic for a given seed.",
+		Tags:        []string{"Generate"},
+	}, func(ctx context.Context, input *GenerateParams) (*GenerateResponse[T], error) {
+		r, seed := input.rand()
+		l := make([]T, 0, input.Count)
+		for i := 1; i <= input.Count; i++ {
+			l = append(l, generate(r, i))
+		}
+		return &GenerateResponse[T]{Seed: strconv.FormatInt(seed, 10), Body: l}, nil
+	})
+}
+
+// GenerateStreamParams controls how many records are streamed and how often
+// they are flushed to the client.
+type GenerateStreamParams struct {
+	Count      int   `query:"count" default:"1000" minimum:"1" maximum:"1000000" doc:"Number of records to generate"`
+	Seed       int64 `query:"seed" doc:"Seed for the generator. The same seed always produces the same records, which match the non-streaming operation. Defaults to a random seed, which is returned in the X-Seed header."`
+	FlushEvery int   `query:"flush-every" default:"100" minimum:"1" maximum:"100000" doc:"Flush the response after this many records"`
+}
+
+// registerGeneratorStream registers an operation streaming a JSON array of
+// fake records. Each record is encoded directly to the response as it is
+// generated, so memory use doesn't grow with the count.
+func registerGeneratorStream[T any](api huma.API, kind string, generate func(r *rand.Rand, id int) T) {
+	huma.Register(api, huma.Operation{
+		OperationID: "generate-" + kind + "-stream",
+		Method:      http.MethodGet,
+		Path:        "/generate/" + kind + "/stream",
+		Description: "Stream up to a million realistic fake " + kind + " as a JSON array, flushing periodically, for testing large responses and time to first byte. Only JSON is supported.",
+		Tags:        []string{"Generate"},
+		Metadata:    map[string]any{streamingKey: true},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "OK",
+				Content: map[string]*huma.MediaType{
+					"application/json": {Schema: api.OpenAPI().Components.Schemas.Schema(reflect.TypeOf([]T{}), true, "")},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *GenerateStreamParams) (*huma.StreamResponse, error) {
+		r, seed := GenerateParams{Seed: input.Seed}.rand()
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				ctx.SetHeader("Content-Type", "application/json")
+				ctx.SetHeader("X-Seed", strconv.FormatInt(seed, 10))
+				ctx.SetStatus(http.StatusOK)
+
+				w := ctx.BodyWriter()
+				flusher, _ := w.(http.Flusher)
+				enc := json.NewEncoder(w)
+				w.Write([]byte("["))
+				for i := 1; i <= input.Count; i++ {
+					if i > 1 {
+						w.Write([]byte(","))
+					}
+					if err := enc.Encode(generate(r, i)); err != nil {
+						return
+					}
+					if i%input.FlushEvery == 0 && flusher != nil {
+						if ctx.Context().Err() != nil {
+							// The client went away, so stop generating.
+							return
+						}
+						flusher.Flush()
+					}
+				}
+				w.Write([]byte("]\n"))
+			},
+		}, nil
+	})
+}
+
+func (s *APIServer) RegisterGenerate(api huma.API) {
+	registerGenerator(api, "people", fakePerson)
+	registerGenerator(api, "addresses", func(r *rand.Rand, id int) FakeAddress {
+		return fakeAddress(r)
+	})
+	registerGenerator(api, "companies", fakeCompany)
+
+	registerGeneratorStream(api, "people", fakePerson)
+	registerGeneratorStream(api, "addresses", func(r *rand.Rand, id int) FakeAddress {
+		return fakeAddress(r)
+	})
+	registerGeneratorStream(api, "companies", fakeCompany)
+}
+
+// TokenResult contains cryptographically random tokens.
+type TokenResult struct {
+	Bytes    int      `json:"bytes" doc:"Number of random bytes in each token"`
+	Encoding string   `json:"encoding"`
+	Tokens   []string `json:"tokens"`
+}
+
+type GenerateTokenResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Body         TokenResult
+}
+
+func (s *APIServer) RegisterGenerateToken(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "generate-token",
+		Method:      http.MethodGet,
+		Path:        "/generate/token",
+		Description: "Generate cryptographically random tokens suitable for secrets. Unlike the other generators these can't be seeded, and responses are never cached.",
+		Tags:        []string{"Generate"},
+	}, func(ctx context.Context, input *struct {
+		Bytes    int    `query:"bytes" default:"32" minimum:"1" maximum:"1024" doc:"Number of random bytes per token"`
+		Encoding string `query:"encoding" enum:"hex,base64,base64url" default:"hex" doc:"Token encoding. base64url omits padding."`
+		Count    int    `query:"count" default:"1" minimum:"1" maximum:"100" doc:"Number of tokens to generate"`
+	}) (*GenerateTokenResponse, error) {
+		encode := hex.EncodeToString
+		switch input.Encoding {
+		case "base64":
+			encode = base64.StdEncoding.EncodeToString
+		case "base64url":
+			encode = base64.RawURLEncoding.EncodeToString
+		}
+
+		tokens := make([]string, input.Count)
+		buf := make([]byte, input.Bytes)
+		for i := range tokens {
+			if _, err := crand.Read(buf); err != nil {
+				return nil, huma.Error500InternalServerError("unable to read random bytes", err)
+			}
+			tokens[i] = encode(buf)
+		}
+
+		return &GenerateTokenResponse{
+			CacheControl: "no-store",
+			Body: TokenResult{
+				Bytes:    input.Bytes,
+				Encoding: input.Encoding,
+				Tokens:   tokens,
+			},
+		}, nil
+	})
+}
@@ -0,0 +1,128 @@
+package apibin
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// endpointGroup returns the group an operation belongs to, which is its first
+// tag in lowercase, e.g. `books` or `images`.
+func endpointGroup(op *huma.Operation) string {
+	if len(op.Tags) == 0 {
+		return ""
+	}
+	return strings.ToLower(op.Tags[0])
+}
+
+// parseGroups parses a comma-separated list of endpoint groups.
+func parseGroups(value string) map[string]bool {
+	groups := map[string]bool{}
+	for _, g := range strings.Split(value, ",") {
+		if g = strings.ToLower(strings.TrimSpace(g)); g != "" {
+			groups[g] = true
+		}
+	}
+	return groups
+}
+
+// endpointGroups decides which endpoint groups are mounted. Operations without
+// a group are always mounted.
+type endpointGroups struct {
+	enabled  map[string]bool
+	disabled map[string]bool
+	seen     map[string]bool
+}
+
+func newEndpointGroups(enable, disable string) *endpointGroups {
+	g := &endpointGroups{
+		disabled: parseGroups(disable),
+		seen:     map[string]bool{},
+	}
+	if enable != "" {
+		g.enabled = parseGroups(enable)
+	}
+	return g
+}
+
+// allows returns whether an operation's group should be mounted.
+func (g *endpointGroups) allows(op *huma.Operation) bool {
+	group := endpointGroup(op)
+	if group == "" {
+		return true
+	}
+	g.seen[group] = true
+	if g.enabled != nil && !g.enabled[group] {
+		return false
+	}
+	return !g.disabled[group]
+}
+
+// validate returns an error for any groups given which don't exist. It must
+// be called after registering the operations.
+func (g *endpointGroups) validate() error {
+	known := make([]string, 0, len(g.seen))
+	for group := range g.seen {
+		known = append(known, group)
+	}
+	sort.Strings(known)
+
+	for _, given := range []map[string]bool{g.enabled, g.disabled} {
+		for group := range given {
+			if !g.seen[group] {
+				return fmt.Errorf("unknown endpoint group %s, expected one of %s", group, strings.Join(known, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// remove deletes the documentation for operations which weren't mounted.
+func (g *endpointGroups) remove(oapi *huma.OpenAPI) {
+	for path, item := range oapi.Paths {
+		for _, op := range []**huma.Operation{&item.Get, &item.Head, &item.Post, &item.Put, &item.Patch, &item.Delete, &item.Options} {
+			if *op != nil && !g.allows(*op) {
+				*op = nil
+			}
+		}
+		if item.Get == nil && item.Head == nil && item.Post == nil && item.Put == nil && item.Patch == nil && item.Delete == nil && item.Options == nil {
+			delete(oapi.Paths, path)
+		}
+	}
+}
+
+// groupAdapter only mounts operations in enabled endpoint groups.
+type groupAdapter struct {
+	huma.Adapter
+	groups *endpointGroups
+}
+
+func (a *groupAdapter) Handle(op *huma.Operation, handler func(ctx huma.Context)) {
+	if a.groups.allows(op) {
+		a.Adapter.Handle(op, handler)
+	}
+}
+
+func (a *groupAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.Adapter.ServeHTTP(w, r)
+}
+
+// groupAPI registers operations through a `groupAdapter`. Huma documents an
+// operation before mounting it, so `remove` must be called afterward.
+type groupAPI struct {
+	huma.API
+	adapter *groupAdapter
+}
+
+func (a *groupAPI) Adapter() huma.Adapter {
+	return a.adapter
+}
+
+// withGroups wraps an API so that only operations in enabled endpoint groups
+// are mounted when registered.
+func withGroups(api huma.API, groups *endpointGroups) huma.API {
+	return &groupAPI{API: api, adapter: &groupAdapter{Adapter: api.Adapter(), groups: groups}}
+}
@@ -0,0 +1,606 @@
+package apibin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// harFlushInterval is how often recorded entries are appended to the file.
+const harFlushInterval = 5 * time.Second
+
+// harFlushEntries is how many entries are held in memory before they're
+// appended to the file without waiting for the next flush.
+const harFlushEntries = 100
+
+// harMaxEntries limits the entries written to the file, after which no more
+// are recorded.
+const harMaxEntries = 10000
+
+// harSensitiveParams are query & form parameters, as well as JSON body
+// fields, whose values are redacted.
+var harSensitiveParams = map[string]bool{
+	"access_token":  true,
+	"api_key":       true,
+	"client_secret": true,
+	"code":          true,
+	"csrf_token":    true,
+	"id_token":      true,
+	"password":      true,
+	"refresh_token": true,
+	"secret":        true,
+	"token":         true,
+}
+
+// HAR is an HTTP Archive as described at http://www.softwareishard.com/blog/har-12-spec/.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+
+	// Encoding is a custom field set to `base64` for binary bodies, mirroring
+	// the response content's encoding.
+	Encoding string `json:"_encoding,omitempty"`
+
+	// Truncated is a custom field set when the body is over the record limit,
+	// or was dropped because it couldn't be decoded.
+	Truncated bool `json:"_truncated,omitempty"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+
+	// Truncated is a custom field set when the body is over the record limit,
+	// or was dropped because it couldn't be decoded.
+	Truncated bool `json:"_truncated,omitempty"`
+}
+
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harHeaders converts headers, redacting those which may contain credentials.
+func harHeaders(h http.Header) []HARNameValue {
+	values := []HARNameValue{}
+	for name, vs := range h {
+		redact := traceSensitive[name] || name == "Proxy-Authorization" || name == "Set-Cookie"
+		for _, v := range vs {
+			if redact {
+				v = traceRedacted
+			}
+			values = append(values, HARNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
+}
+
+// harParams converts query or form parameters, redacting sensitive values.
+func harParams(params url.Values) []HARNameValue {
+	values := []HARNameValue{}
+	for name, vs := range params {
+		for _, v := range vs {
+			if harSensitiveParams[strings.ToLower(name)] {
+				v = traceRedacted
+			}
+			values = append(values, HARNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
+}
+
+// redactURL redacts sensitive query parameter values in a URL.
+func redactURL(u url.URL) string {
+	u.RawQuery = redactForm(u.RawQuery, u.Query())
+	return u.String()
+}
+
+// redactForm redacts sensitive values in a URL-encoded form body or query,
+// which is only re-encoded if there is something to redact.
+func redactForm(body string, form url.Values) string {
+	changed := false
+	for name := range form {
+		if harSensitiveParams[strings.ToLower(name)] {
+			form.Set(name, traceRedacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return body
+	}
+	return form.Encode()
+}
+
+// redactJSON redacts sensitive field values anywhere in a JSON body, which is
+// only re-encoded if there is something to redact.
+func redactJSON(body string) string {
+	var value any
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return body
+	}
+	if !redactJSONValue(value) {
+		return body
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return string(redacted)
+}
+
+// redactJSONValue redacts sensitive fields in place, returning whether any
+// were found.
+func redactJSONValue(value any) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]any:
+		for name, item := range v {
+			if harSensitiveParams[strings.ToLower(name)] {
+				v[name] = traceRedacted
+				changed = true
+			} else if redactJSONValue(item) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if redactJSONValue(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// redactBody redacts sensitive values in a recorded text body based on its
+// content type. Truncated bodies can't be parsed, so are dropped if they
+// mention any sensitive field.
+func redactBody(text, contentType string, truncated bool) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	isForm := mediaType == "application/x-www-form-urlencoded"
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	if truncated && (isForm || isJSON) {
+		lower := strings.ToLower(text)
+		for name := range harSensitiveParams {
+			if strings.Contains(lower, name) {
+				return ""
+			}
+		}
+		return text
+	}
+	if isForm {
+		if form, err := url.ParseQuery(text); err == nil {
+			return redactForm(text, form)
+		}
+	}
+	if isJSON {
+		return redactJSON(text)
+	}
+	return text
+}
+
+// harBody converts a captured body to text & its HAR encoding, decoding any
+// content encoding up to `limit` bytes, and returns whether the text is
+// incomplete. Binary bodies are base64 encoded. Bodies which can't be decoded,
+// e.g. because the capture was truncated, can't be redacted either, so they
+// are dropped and only their size is recorded.
+func harBody(body []byte, contentEncoding string, limit int) (string, string, bool) {
+	if contentEncoding != "" {
+		codings := []string{}
+		for _, c := range strings.Split(contentEncoding, ",") {
+			if c = strings.ToLower(strings.TrimSpace(c)); c != "" && c != "identity" {
+				codings = append(codings, c)
+			}
+		}
+		for _, c := range codings {
+			if requestDecoders[c] == nil {
+				return "", "", true
+			}
+		}
+		decoded, err := decodeBody(codings, body, int64(limit))
+		if err != nil {
+			return "", "", true
+		}
+		truncated := false
+		if len(decoded) > limit {
+			decoded = decoded[:limit]
+			truncated = true
+		}
+		text, encoding, _ := harBody(decoded, "", limit)
+		return text, encoding, truncated
+	}
+	if utf8.Valid(body) {
+		return string(body), "", false
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64", false
+}
+
+// harCapture stores up to `limit` bytes written to it while counting them all.
+type harCapture struct {
+	buf   bytes.Buffer
+	limit int
+	size  int64
+}
+
+func (c *harCapture) Write(data []byte) (int, error) {
+	c.size += int64(len(data))
+	if room := c.limit - c.buf.Len(); room > 0 {
+		if len(data) > room {
+			c.buf.Write(data[:room])
+		} else {
+			c.buf.Write(data)
+		}
+	}
+	return len(data), nil
+}
+
+func (c *harCapture) truncated() bool {
+	return c.size > int64(c.buf.Len())
+}
+
+// harRequestBody captures a request body as it's read by the handler.
+type harRequestBody struct {
+	io.ReadCloser
+	capture *harCapture
+}
+
+func (b *harRequestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.capture.Write(p[:n])
+	return n, err
+}
+
+// harResponseWriter captures the status & body of a response.
+type harResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	capture *harCapture
+}
+
+func (w *harResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *harResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.capture.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *harResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer, which allows the use of
+// `http.ResponseController`.
+func (w *harResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// SetWriteDeadline sets the write deadline of the underlying connection, which
+// some streaming writers check for directly instead of using a controller.
+func (w *harResponseWriter) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(w.ResponseWriter).SetWriteDeadline(deadline)
+}
+
+// Hijack takes over the connection, e.g. for WebSockets. Only the upgrade
+// is recorded.
+func (w *harResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// harHeader & harFooter surround the entries in a recorded file, matching the
+// indentation of `json.MarshalIndent` so appended entries line up.
+const (
+	harHeader = "{\n  \"log\": {\n    \"version\": \"1.2\",\n    \"creator\": {\n      \"name\": \"apibin\",\n      \"version\": \"1.0.0\"\n    },\n    \"entries\": ["
+	harFooter = "\n    ]\n  }\n}\n"
+)
+
+// harRecorder collects entries and periodically appends them to a file,
+// which is a valid HAR after each flush.
+type harRecorder struct {
+	mu        sync.Mutex
+	path      string
+	bodyLimit int
+	pending   []HAREntry
+	file      *os.File
+	written   int
+	dropped   bool
+	kick      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// parseRecord parses a `--record` value like `har:traffic.har`.
+func parseRecord(value string) (string, error) {
+	format, path, ok := strings.Cut(value, ":")
+	if !ok || format != "har" || path == "" {
+		return "", fmt.Errorf("invalid record %q, expected har:path", value)
+	}
+	return path, nil
+}
+
+// newHARRecorder creates a recorder appending to the path every few seconds,
+// keeping up to `bodyLimit` bytes of each request & response body. It must
+// be closed to write the last entries.
+func newHARRecorder(path string, bodyLimit int) (*harRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.WriteString(harHeader + harFooter); err != nil {
+		file.Close()
+		return nil, err
+	}
+	rec := &harRecorder{
+		path:      path,
+		bodyLimit: bodyLimit,
+		file:      file,
+		kick:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go rec.run()
+	return rec, nil
+}
+
+// run flushes periodically, or early once enough entries are pending, until
+// the recorder is closed.
+func (rec *harRecorder) run() {
+	defer close(rec.done)
+	ticker := time.NewTicker(harFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rec.stop:
+			return
+		case <-ticker.C:
+		case <-rec.kick:
+		}
+		if err := rec.flush(); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to write HAR:", err)
+		}
+	}
+}
+
+// Close stops flushing, writes any pending entries, and closes the file.
+func (rec *harRecorder) Close() error {
+	close(rec.stop)
+	<-rec.done
+	err := rec.flush()
+	if cerr := rec.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (rec *harRecorder) add(entry HAREntry) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.written+len(rec.pending) >= harMaxEntries {
+		if !rec.dropped {
+			rec.dropped = true
+			fmt.Fprintf(os.Stderr, "Recorded %d entries to %s, no more will be recorded\n", harMaxEntries, rec.path)
+		}
+		return
+	}
+	rec.pending = append(rec.pending, entry)
+	if len(rec.pending) >= harFlushEntries {
+		select {
+		case rec.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush appends the pending entries to the file by overwriting its footer,
+// so only entries recorded since the last flush are held in memory.
+func (rec *harRecorder) flush() error {
+	rec.mu.Lock()
+	entries := rec.pending
+	rec.pending = nil
+	rec.mu.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	buf := bytes.Buffer{}
+	for _, entry := range entries {
+		data, err := json.MarshalIndent(entry, "      ", "  ")
+		if err != nil {
+			return err
+		}
+		if rec.written > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString("\n      ")
+		buf.Write(data)
+		rec.written++
+	}
+	buf.WriteString(harFooter)
+
+	info, err := rec.file.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = rec.file.WriteAt(buf.Bytes(), info.Size()-int64(len(harFooter)))
+	return err
+}
+
+// Middleware records each request & response as sent, with credentials
+// redacted and bodies truncated to the limit. It must run after
+// `ForwardedHeaders` so the URL reflects what the client requested.
+func (rec *harRecorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		reqBody := &harCapture{limit: rec.bodyLimit}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &harRequestBody{ReadCloser: r.Body, capture: reqBody}
+		}
+		// Headers are copied first since handlers & middleware may modify them.
+		reqHeaders := harHeaders(r.Header)
+		contentType := r.Header.Get("Content-Type")
+		contentEncoding := r.Header.Get("Content-Encoding")
+
+		hw := &harResponseWriter{ResponseWriter: w, capture: &harCapture{limit: rec.bodyLimit}}
+		next.ServeHTTP(hw, r)
+		if hw.status == 0 {
+			hw.status = http.StatusOK
+		}
+		elapsed := float64(time.Since(started).Microseconds()) / 1000
+
+		info := GetClientInfo(r.Context())
+		u := *r.URL
+		u.Scheme, u.Host = info.Scheme, info.Host
+		if u.Scheme == "" {
+			u.Scheme = "http"
+		}
+		if u.Host == "" {
+			u.Host = r.Host
+		}
+
+		entry := HAREntry{
+			StartedDateTime: started.UTC(),
+			Time:            elapsed,
+			Request: HARRequest{
+				Method:      r.Method,
+				URL:         redactURL(u),
+				HTTPVersion: r.Proto,
+				Cookies:     []HARNameValue{},
+				Headers:     reqHeaders,
+				QueryString: harParams(r.URL.Query()),
+				HeadersSize: -1,
+				BodySize:    reqBody.size,
+			},
+			Response: HARResponse{
+				Status:      hw.status,
+				StatusText:  http.StatusText(hw.status),
+				HTTPVersion: r.Proto,
+				Cookies:     []HARNameValue{},
+				Headers:     harHeaders(w.Header()),
+				Content: HARContent{
+					MimeType: w.Header().Get("Content-Type"),
+				},
+				RedirectURL: w.Header().Get("Location"),
+				HeadersSize: -1,
+				BodySize:    hw.capture.size,
+			},
+			Timings: HARTimings{Wait: elapsed},
+		}
+
+		truncated := false
+		if reqBody.size > 0 {
+			text, encoding, cut := harBody(reqBody.buf.Bytes(), contentEncoding, rec.bodyLimit)
+			cut = cut || reqBody.truncated()
+			if encoding == "" {
+				text = redactBody(text, contentType, cut)
+			}
+			entry.Request.PostData = &HARPostData{MimeType: contentType, Text: text, Encoding: encoding, Truncated: cut}
+			truncated = cut
+		}
+
+		if hw.capture.size > 0 {
+			content := &entry.Response.Content
+			var cut bool
+			content.Text, content.Encoding, cut = harBody(hw.capture.buf.Bytes(), w.Header().Get("Content-Encoding"), rec.bodyLimit)
+			content.Truncated = cut || hw.capture.truncated()
+			if content.Encoding == "" {
+				content.Text = redactBody(content.Text, content.MimeType, content.Truncated)
+			}
+			truncated = truncated || content.Truncated
+			content.Size = int64(len(content.Text))
+			if content.Encoding == "base64" {
+				content.Size = int64(base64.StdEncoding.DecodedLen(len(content.Text)))
+			}
+		}
+
+		if truncated {
+			entry.Comment = fmt.Sprintf("bodies truncated to %d bytes, or dropped if they couldn't be decoded", rec.bodyLimit)
+		}
+
+		rec.add(entry)
+	})
+}
@@ -0,0 +1,159 @@
+package apibin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		text        string
+		contentType string
+		truncated   bool
+		want        string
+	}{
+		{"form", "grant_type=password&password=secret", "application/x-www-form-urlencoded", false, "grant_type=password&password=%5Bredacted%5D"},
+		{"form unchanged", "a=1&b=2", "application/x-www-form-urlencoded", false, "a=1&b=2"},
+		{"json", `{"access_token":"abc","expires_in":3600}`, "application/json", false, `{"access_token":"[redacted]","expires_in":3600}`},
+		{"json nested", `[{"auth":{"Secret":"x"}}]`, "application/json; charset=utf-8", false, `[{"auth":{"Secret":"[redacted]"}}]`},
+		{"json suffix", `{"token":"abc"}`, "application/problem+json", false, `{"token":"[redacted]"}`},
+		{"json unchanged", `{ "title": "Dune" }`, "application/json", false, `{ "title": "Dune" }`},
+		{"truncated", `{"id_token":"ab`, "application/json", true, ""},
+		{"truncated safe", `{"title":"Du`, "application/json", true, `{"title":"Du`},
+		{"text", "password=secret", "text/plain", false, "password=secret"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := redactBody(tc.text, tc.contentType, tc.truncated); got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHARRecorderClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.har")
+	rec, err := newHARRecorder(path, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := rec.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"abc"}`))
+	}))
+	for i := 0; i < 3; i++ {
+		r, _ := http.NewRequest(http.MethodPost, "http://example.com/oauth/token", strings.NewReader("client_secret=shh"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("expected a valid HAR, got %v:\n%s", err, data)
+	}
+	if len(har.Log.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(har.Log.Entries))
+	}
+	if strings.Contains(string(data), "abc") || strings.Contains(string(data), "shh") {
+		t.Errorf("expected credentials to be redacted:\n%s", data)
+	}
+}
+
+func TestHARRecorderCompressed(t *testing.T) {
+	gzipJSON := func(padding int) []byte {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		json.NewEncoder(gz).Encode(map[string]string{"access_token": "hunter2", "padding": strings.Repeat("a", padding)})
+		gz.Close()
+		return buf.Bytes()
+	}
+
+	for _, tc := range []struct {
+		name     string
+		response bool
+		body     []byte
+		limit    int
+		redacted bool
+	}{
+		{"request", false, gzipJSON(0), 1024, true},
+		{"request over limit", false, gzipJSON(4096), 1024, false},
+		{"request cut", false, gzipJSON(0), 16, false},
+		{"response", true, gzipJSON(0), 1024, true},
+		{"response over limit", true, gzipJSON(4096), 1024, false},
+		{"response cut", true, gzipJSON(0), 16, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "traffic.har")
+			rec, err := newHARRecorder(path, tc.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := rec.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.ReadAll(r.Body)
+				if tc.response {
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("Content-Encoding", "gzip")
+					w.Write(tc.body)
+				}
+			}))
+			var body io.Reader = http.NoBody
+			if !tc.response {
+				body = bytes.NewReader(tc.body)
+			}
+			r, _ := http.NewRequest(http.MethodPost, "http://example.com/login", body)
+			r.Header.Set("Content-Type", "application/json")
+			if !tc.response {
+				r.Header.Set("Content-Encoding", "gzip")
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if err := rec.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var har HAR
+			if err := json.Unmarshal(data, &har); err != nil {
+				t.Fatalf("expected a valid HAR, got %v:\n%s", err, data)
+			}
+			entry := har.Log.Entries[0]
+			text, encoding, truncated := entry.Response.Content.Text, entry.Response.Content.Encoding, entry.Response.Content.Truncated
+			if !tc.response {
+				text, encoding, truncated = entry.Request.PostData.Text, entry.Request.PostData.Encoding, entry.Request.PostData.Truncated
+			}
+
+			if encoding != "" {
+				t.Errorf("expected compressed bodies not to be stored, got %s", encoding)
+			}
+			if strings.Contains(text, "hunter2") {
+				t.Errorf("expected the token to be redacted, got %s", text)
+			}
+			if tc.redacted && !strings.Contains(text, traceRedacted) {
+				t.Errorf("expected the redacted body to be recorded, got %s", text)
+			}
+			if !tc.redacted && (text != "" || !truncated) {
+				t.Errorf("expected the body to be dropped & marked truncated, got %q", text)
+			}
+		})
+	}
+}
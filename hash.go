@@ -0,0 +1,166 @@
+package apibin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/zeebo/xxh3"
+)
+
+// hashAlgorithms creates a new hash for each supported algorithm.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"md5":    md5.New,
+	"xxh3":   func() hash.Hash { return xxh3.New() },
+}
+
+// hmacAlgorithms creates a new hash for each algorithm supported for HMAC
+// signatures. Non-cryptographic hashes like xxh3 are not included.
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"md5":    md5.New,
+}
+
+// anyRequestBody documents that any content, including none, is accepted.
+var anyRequestBody = &huma.RequestBody{
+	Description: "Any content, which may be empty",
+	Content: map[string]*huma.MediaType{
+		"application/octet-stream": {Schema: &huma.Schema{Type: huma.TypeString, Format: "binary"}},
+	},
+}
+
+// HashResult is the digest of a request body.
+type HashResult struct {
+	Algorithm string `json:"algorithm"`
+	Bytes     int    `json:"bytes" doc:"Number of bytes hashed"`
+	Hex       string `json:"hex"`
+	Base64    string `json:"base64"`
+}
+
+func (s *APIServer) RegisterHash(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "post-hash",
+		Method:      http.MethodPost,
+		Path:        "/hash",
+		Description: "Compute the digest of the request body exactly as received by the server, which can be used to verify what a client actually sent.",
+		Tags:        []string{"Hash"},
+		RequestBody: anyRequestBody,
+	}, func(ctx context.Context, input *struct {
+		Algorithm string `query:"alg" enum:"sha256,sha512,md5,xxh3" default:"sha256" doc:"Hash algorithm. xxh3 is the 64-bit variant."`
+		RawBody   []byte
+	}) (*struct{ Body HashResult }, error) {
+		h := hashAlgorithms[input.Algorithm]()
+		h.Write(input.RawBody)
+		sum := h.Sum(nil)
+
+		return &struct{ Body HashResult }{Body: HashResult{
+			Algorithm: input.Algorithm,
+			Bytes:     len(input.RawBody),
+			Hex:       hex.EncodeToString(sum),
+			Base64:    base64.StdEncoding.EncodeToString(sum),
+		}}, nil
+	})
+}
+
+// HMACParams are shared by the HMAC sign and verify operations.
+type HMACParams struct {
+	Algorithm string `query:"alg" enum:"sha1,sha256,sha512,md5" default:"sha256" doc:"Hash algorithm"`
+	Key       string `query:"key" required:"true" minLength:"1" doc:"Secret key"`
+}
+
+// sign computes the HMAC of a body.
+func (p HMACParams) sign(body []byte) []byte {
+	mac := hmac.New(hmacAlgorithms[p.Algorithm], []byte(p.Key))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// HMACResult is the HMAC signature of a request body.
+type HMACResult struct {
+	Algorithm string `json:"algorithm"`
+	Bytes     int    `json:"bytes" doc:"Number of bytes signed"`
+	Hex       string `json:"hex"`
+	Base64    string `json:"base64"`
+	Header    string `json:"header" doc:"Signature in the common webhook header format, e.g. sha256=..."`
+}
+
+// HMACVerifyResult describes whether a signature matches.
+type HMACVerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Expected string `json:"expected" doc:"Expected signature in hex"`
+	Received string `json:"received" doc:"Received signature decoded and re-encoded in hex, empty if it could not be decoded"`
+}
+
+// decodeSignature decodes a hex or base64 signature, with an optional
+// algorithm prefix like `sha256=`.
+func decodeSignature(alg, signature string) []byte {
+	signature = strings.TrimPrefix(signature, alg+"=")
+	if b, err := hex.DecodeString(signature); err == nil {
+		return b
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(signature); err == nil {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *APIServer) RegisterHMAC(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "post-hmac",
+		Method:      http.MethodPost,
+		Path:        "/hmac",
+		Description: "Compute the HMAC signature of the request body exactly as received by the server. Useful as a reference when debugging webhook signature mismatches.",
+		Tags:        []string{"Hash"},
+		RequestBody: anyRequestBody,
+	}, func(ctx context.Context, input *struct {
+		HMACParams
+		RawBody []byte
+	}) (*struct{ Body HMACResult }, error) {
+		sum := input.sign(input.RawBody)
+
+		return &struct{ Body HMACResult }{Body: HMACResult{
+			Algorithm: input.Algorithm,
+			Bytes:     len(input.RawBody),
+			Hex:       hex.EncodeToString(sum),
+			Base64:    base64.StdEncoding.EncodeToString(sum),
+			Header:    input.Algorithm + "=" + hex.EncodeToString(sum),
+		}}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-hmac-verify",
+		Method:      http.MethodPost,
+		Path:        "/hmac/verify",
+		Description: "Verify an HMAC signature of the request body using a constant-time comparison.",
+		Tags:        []string{"Hash"},
+		RequestBody: anyRequestBody,
+	}, func(ctx context.Context, input *struct {
+		HMACParams
+		Signature string `query:"signature" required:"true" doc:"Signature to check in hex or base64, optionally prefixed with the algorithm like sha256=..."`
+		RawBody   []byte
+	}) (*struct{ Body HMACVerifyResult }, error) {
+		expected := input.sign(input.RawBody)
+		received := decodeSignature(input.Algorithm, input.Signature)
+
+		return &struct{ Body HMACVerifyResult }{Body: HMACVerifyResult{
+			Valid:    hmac.Equal(expected, received),
+			Expected: hex.EncodeToString(expected),
+			Received: hex.EncodeToString(received),
+		}}, nil
+	})
+}
@@ -0,0 +1,153 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/go-chi/chi/v5"
+)
+
+var headKey contextKey = "apibin/head"
+
+// headWriter discards the body of a HEAD response while counting it, so the
+// `Content-Length` matches what the equivalent GET would send.
+type headWriter struct {
+	http.ResponseWriter
+	status      int
+	length      int64
+	wroteHeader bool
+	streaming   bool
+	cancel      context.CancelFunc
+}
+
+func (w *headWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *headWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.length += int64(len(data))
+	return len(data), nil
+}
+
+// sendHeader sends the headers, which can only happen once.
+func (w *headWriter) sendHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// Flush sends the headers without a length, as a streaming GET response
+// would. The handler is then cancelled since the rest of the body would be
+// discarded anyway, which stops endless streams like server-sent events.
+func (w *headWriter) Flush() {
+	w.sendHeader()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+	w.cancel()
+}
+
+// finish sends the headers with the counted length if nothing was flushed.
+func (w *headWriter) finish() {
+	h := w.Header()
+	if !w.wroteHeader && !w.streaming && h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified && w.status >= 200 {
+		h.Set("Content-Length", strconv.FormatInt(w.length, 10))
+	}
+	w.sendHeader()
+}
+
+// Unwrap returns the underlying response writer, which allows the use of
+// `http.ResponseController`.
+func (w *headWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// SetWriteDeadline sets the write deadline of the underlying connection, which
+// some streaming writers check for directly instead of using a controller.
+func (w *headWriter) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(w.ResponseWriter).SetWriteDeadline(deadline)
+}
+
+// HeadRequests answers HEAD requests for any route without its own HEAD
+// handler using the GET handler, sending the same headers without a body as
+// required by RFC 9110. It must run before `ContentEncoding` so that the
+// length reflects any compression.
+func HeadRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rctx := chi.RouteContext(r.Context())
+		if rctx == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if rctx.Routes.Match(chi.NewRouteContext(), http.MethodHead, routePath(rctx, r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Route as a GET while keeping the HEAD method, so handlers which check
+		// it like `http.ServeContent` can skip generating the body.
+		rctx.RouteMethod = http.MethodGet
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		hw := &headWriter{ResponseWriter: w, cancel: cancel}
+		next.ServeHTTP(hw, r.WithContext(context.WithValue(ctx, headKey, hw)))
+		hw.finish()
+	})
+}
+
+// HeadStreaming cancels streaming operations as soon as they start for HEAD
+// requests, since they may otherwise wait indefinitely before writing
+// anything, e.g. for the next server-sent event. Like the streamed GET
+// response, no length is sent unless the operation sets one.
+func HeadStreaming(ctx huma.Context, next func(huma.Context)) {
+	hw, _ := ctx.Context().Value(headKey).(*headWriter)
+	if op := ctx.Operation(); hw != nil && op != nil && op.Metadata[streamingKey] == true {
+		hw.streaming = true
+		hw.cancel()
+	}
+	next(ctx)
+}
+
+// addHeadOperations documents a HEAD operation for each GET operation which
+// doesn't have one, matching its responses without any content.
+func addHeadOperations(api huma.API) {
+	oapi := api.OpenAPI()
+	for _, item := range oapi.Paths {
+		get := item.Get
+		if get == nil || item.Head != nil {
+			continue
+		}
+
+		head := *get
+		head.Method = http.MethodHead
+		head.OperationID = "head-" + strings.TrimPrefix(get.OperationID, "get-")
+		head.Description = "Get the headers for the equivalent GET request without a body."
+		head.Responses = map[string]*huma.Response{}
+		for status, resp := range get.Responses {
+			r := *resp
+			r.Content = nil
+			head.Responses[status] = &r
+		}
+		oapi.AddOperation(&head)
+	}
+}
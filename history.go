@@ -0,0 +1,123 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/conditional"
+)
+
+// maxRevisions limits how many revisions are kept for each book. The oldest
+// are dropped first.
+const maxRevisions = 10
+
+// BookRevision describes a single stored revision of a book.
+type BookRevision struct {
+	Revision int       `json:"revision"`
+	URL      string    `json:"url"`
+	Version  string    `json:"version" doc:"The book's ETag at this revision"`
+	Modified time.Time `json:"modified"`
+}
+
+type bookRevision struct {
+	BookRevision
+	book Book
+}
+
+// bookHistory tracks the latest revisions of each book, oldest first. It is
+// protected by `booksMu`.
+var bookHistory = map[string][]bookRevision{}
+
+// recordRevision stores a snapshot of a book as its newest revision. The
+// caller must hold the write lock.
+func recordRevision(id string, b *Book) {
+	revisions := bookHistory[id]
+	next := 1
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+
+	snapshot := *b
+	snapshot.Embedded = nil
+	revisions = append(revisions, bookRevision{
+		BookRevision: BookRevision{
+			Revision: next,
+			URL:      "/books/" + id + "/history/" + strconv.Itoa(next),
+			Version:  snapshot.Version(),
+			Modified: snapshot.modified,
+		},
+		book: snapshot,
+	})
+	if len(revisions) > maxRevisions {
+		revisions = revisions[len(revisions)-maxRevisions:]
+	}
+	bookHistory[id] = revisions
+}
+
+func (s *APIServer) RegisterListBookHistory(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-book-history",
+		Method:      http.MethodGet,
+		Path:        "/books/{book-id}/history",
+		Description: "List the latest revisions of a book, newest first. A revision is recorded for each write, but not for simulated server-side updates.",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"book-id"`
+	}) (*struct{ Body []BookRevision }, error) {
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		revisions := bookHistory[input.ID]
+		if len(revisions) == 0 {
+			return nil, huma.Error404NotFound(input.ID + " not found")
+		}
+
+		l := make([]BookRevision, 0, len(revisions))
+		for i := len(revisions) - 1; i >= 0; i-- {
+			l = append(l, revisions[i].BookRevision)
+		}
+
+		return &struct{ Body []BookRevision }{Body: l}, nil
+	})
+}
+
+func (s *APIServer) RegisterGetBookRevision(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-book-revision",
+		Method:      http.MethodGet,
+		Path:        "/books/{book-id}/history/{revision}",
+		Description: "Get an old representation of a book. Revisions never change, so they may be cached indefinitely.",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		conditional.Params
+		ID       string `path:"book-id"`
+		Revision int    `path:"revision" minimum:"1"`
+	}) (*GetBookResponse, error) {
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		for _, r := range bookHistory[input.ID] {
+			if r.Revision != input.Revision {
+				continue
+			}
+
+			if err := input.PreconditionFailed(r.Version, r.Modified); err != nil {
+				return nil, err
+			}
+
+			b := r.book
+			return &GetBookResponse{
+				CacheControl: "public, max-age=31536000, immutable",
+				ETag:         r.Version,
+				LastModified: r.Modified,
+				Vary:         "Accept, Accept-Encoding, Origin",
+				Body:         &b,
+			}, nil
+		}
+
+		return nil, huma.Error404NotFound(input.ID + " revision " + strconv.Itoa(input.Revision) + " not found")
+	})
+}
@@ -0,0 +1,138 @@
+package apibin
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/conditional"
+)
+
+// randomImage draws a gradient between two random colors overlaid with random
+// translucent blocks. The output only depends on the generator's seed.
+func randomImage(r *rand.Rand, width, height int) *image.RGBA {
+	randomColor := func() color.RGBA {
+		return color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255}
+	}
+	from, to := randomColor(), randomColor()
+	lerp := func(a, b uint8, t float64) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*t)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			t := float64(x+y) / float64(width+height)
+			img.SetRGBA(x, y, color.RGBA{lerp(from.R, to.R, t), lerp(from.G, to.G, t), lerp(from.B, to.B, t), 255})
+		}
+	}
+
+	for i := 0; i < 8+r.Intn(16); i++ {
+		c := randomColor()
+		x0, y0 := r.Intn(width), r.Intn(height)
+		x1, y1 := x0+1+r.Intn(width/2+1), y0+1+r.Intn(height/2+1)
+		for y := y0; y < y1 && y < height; y++ {
+			for x := x0; x < x1 && x < width; x++ {
+				p := img.RGBAAt(x, y)
+				img.SetRGBA(x, y, color.RGBA{lerp(p.R, c.R, 0.5), lerp(p.G, c.G, 0.5), lerp(p.B, c.B, 0.5), 255})
+			}
+		}
+	}
+
+	return img
+}
+
+// webSafe converts an image to the web-safe palette for GIF encoding. The
+// palette index is computed directly, which is much faster than the
+// encoder's default nearest-color search and dithering for large images.
+func webSafe(img *image.RGBA) *image.Paletted {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, palette.WebSafe)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			r, g, b := (int(c.R)+25)/51, (int(c.G)+25)/51, (int(c.B)+25)/51
+			paletted.SetColorIndex(x, y, uint8(r*36+g*6+b))
+		}
+	}
+	return paletted
+}
+
+type GetRandomImageResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	ETag         string `header:"ETag"`
+	LastModified string `header:"Last-Modified"`
+	ContentType  string `header:"Content-Type"`
+	Seed         string `header:"X-Seed"`
+	Body         []byte
+}
+
+func (s *APIServer) RegisterGetRandomImage(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-random-image",
+		Method:      http.MethodGet,
+		Path:        "/images/random",
+		Description: "Generate a random image of any size. The same seed, format, and size always produce identical bytes, so seeded images are cacheable forever and support conditional requests. Without a seed every response is different and not cacheable.",
+		Tags:        []string{"Images"},
+	}, func(ctx context.Context, input *struct {
+		Seed   int64  `query:"seed" doc:"Seed for the generator. Defaults to a random seed, which is returned in the X-Seed header."`
+		Format string `query:"format" enum:"png,jpeg,gif" default:"png" doc:"Image format"`
+		Width  int    `query:"width" default:"256" minimum:"1" maximum:"2048" doc:"Width in pixels"`
+		Height int    `query:"height" default:"256" minimum:"1" maximum:"2048" doc:"Height in pixels"`
+		RequestInfo
+		conditional.Params
+	}) (*GetRandomImageResponse, error) {
+		etag := ""
+		if input.Seed != 0 {
+			// The parameters determine the exact bytes, so hashing them gives a
+			// strong ETag without having to generate the image first.
+			etag = `"` + genETag([]any{input.Seed, input.Format, input.Width, input.Height}) + `"`
+			if err := input.PreconditionFailed(strings.Trim(etag, `"`), staticModified); err != nil {
+				// A 304 should have the same validators and caching headers as a 200.
+				input.ctx.SetHeader("ETag", etag)
+				input.ctx.SetHeader("Cache-Control", "public, max-age=31536000, immutable")
+				return nil, err
+			}
+		}
+
+		r, seed := GenerateParams{Seed: input.Seed}.rand()
+		img := randomImage(r, input.Width, input.Height)
+
+		buf := bytes.Buffer{}
+		var err error
+		switch input.Format {
+		case "jpeg":
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		case "gif":
+			err = gif.Encode(&buf, webSafe(img), nil)
+		default:
+			err = png.Encode(&buf, img)
+		}
+		if err != nil {
+			return nil, huma.Error500InternalServerError("unable to encode image", err)
+		}
+
+		resp := &GetRandomImageResponse{
+			CacheControl: "no-store",
+			ContentType:  "image/" + input.Format,
+			Seed:         strconv.FormatInt(seed, 10),
+			Body:         buf.Bytes(),
+		}
+		if input.Seed != 0 {
+			resp.CacheControl = "public, max-age=31536000, immutable"
+			resp.ETag = etag
+			resp.LastModified = staticModified.Format(http.TimeFormat)
+		}
+		return resp, nil
+	})
+}
@@ -0,0 +1,165 @@
+package apibin
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// openConn describes an open connection and its current state.
+type openConn struct {
+	remoteAddr string
+	opened     time.Time
+	state      http.ConnState
+	changed    time.Time
+}
+
+// openConns tracks the server's open connections via `trackConnState`.
+var openConns = struct {
+	sync.Mutex
+	conns map[net.Conn]*openConn
+}{conns: map[net.Conn]*openConn{}}
+
+// trackConnState is used as the server's `ConnState` hook to track open
+// connections and whether they are active or idle.
+func trackConnState(c net.Conn, state http.ConnState) {
+	openConns.Lock()
+	defer openConns.Unlock()
+
+	switch state {
+	case http.StateNew:
+		now := time.Now()
+		openConns.conns[c] = &openConn{remoteAddr: c.RemoteAddr().String(), opened: now, state: state, changed: now}
+	case http.StateClosed, http.StateHijacked:
+		delete(openConns.conns, c)
+	default:
+		if conn := openConns.conns[c]; conn != nil {
+			conn.state = state
+			conn.changed = time.Now()
+		}
+	}
+}
+
+// inFlightRequest describes a request which is being handled.
+type inFlightRequest struct {
+	method     string
+	uri        string
+	proto      string
+	client     string
+	remoteAddr string
+	started    time.Time
+}
+
+// inFlight tracks requests which are being handled.
+var inFlight = struct {
+	sync.Mutex
+	next     atomic.Int64
+	requests map[int64]*inFlightRequest
+}{requests: map[int64]*inFlightRequest{}}
+
+// TrackInFlight records each request while it's being handled so it can be
+// listed at `/debug/connections`. It must run after `ForwardedHeaders` so
+// the client address takes trusted proxies into account.
+func TrackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := inFlight.next.Add(1)
+		req := &inFlightRequest{
+			method:     r.Method,
+			uri:        r.RequestURI,
+			proto:      r.Proto,
+			client:     GetClientInfo(r.Context()).IP,
+			remoteAddr: r.RemoteAddr,
+			started:    time.Now(),
+		}
+
+		inFlight.Lock()
+		inFlight.requests[id] = req
+		inFlight.Unlock()
+		defer func() {
+			inFlight.Lock()
+			delete(inFlight.requests, id)
+			inFlight.Unlock()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// InFlightRequestModel describes a request which is being handled.
+type InFlightRequestModel struct {
+	ID         int64     `json:"id"`
+	Method     string    `json:"method"`
+	URI        string    `json:"uri"`
+	Protocol   string    `json:"protocol"`
+	Client     string    `json:"client,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	Started    time.Time `json:"started"`
+	Duration   string    `json:"duration"`
+}
+
+// OpenConnectionModel describes an open connection.
+type OpenConnectionModel struct {
+	RemoteAddr string    `json:"remote_addr"`
+	State      string    `json:"state"`
+	Opened     time.Time `json:"opened"`
+	StateFor   string    `json:"state_for"`
+}
+
+// ConnectionsModel lists the open connections & in-flight requests.
+type ConnectionsModel struct {
+	Connections []OpenConnectionModel  `json:"connections"`
+	Requests    []InFlightRequestModel `json:"requests"`
+}
+
+// debugConnections lists the open connections and in-flight requests, oldest
+// first. Connections are only tracked by servers from `NewServer`.
+func debugConnections(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	model := ConnectionsModel{
+		Connections: []OpenConnectionModel{},
+		Requests:    []InFlightRequestModel{},
+	}
+
+	openConns.Lock()
+	for _, c := range openConns.conns {
+		model.Connections = append(model.Connections, OpenConnectionModel{
+			RemoteAddr: c.remoteAddr,
+			State:      c.state.String(),
+			Opened:     c.opened.UTC(),
+			StateFor:   now.Sub(c.changed).Round(time.Millisecond).String(),
+		})
+	}
+	openConns.Unlock()
+
+	inFlight.Lock()
+	for id, req := range inFlight.requests {
+		model.Requests = append(model.Requests, InFlightRequestModel{
+			ID:         id,
+			Method:     req.method,
+			URI:        req.uri,
+			Protocol:   req.proto,
+			Client:     req.client,
+			RemoteAddr: req.remoteAddr,
+			Started:    req.started.UTC(),
+			Duration:   now.Sub(req.started).Round(time.Millisecond).String(),
+		})
+	}
+	inFlight.Unlock()
+
+	sort.Slice(model.Connections, func(i, j int) bool {
+		return model.Connections[i].Opened.Before(model.Connections[j].Opened)
+	})
+	sort.Slice(model.Requests, func(i, j int) bool {
+		return model.Requests[i].ID < model.Requests[j].ID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(model)
+}
@@ -0,0 +1,343 @@
+package apibin
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// maxJWKSBytes limits the size of a fetched JWKS document.
+const maxJWKSBytes = 1 << 20
+
+// JWK is a single JSON Web Key. Only public key members are used.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey returns the key as an `*rsa.PublicKey`, `*ecdsa.PublicKey`, or
+// `ed25519.PublicKey`.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := func(name, value string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("invalid %s for key %q", name, k.Kid)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode("n", k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode("e", k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q for key %q", k.Crv, k.Kid)
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode("y", k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q for key %q", k.Crv, k.Kid)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid x for key %q", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q for key %q", k.Kty, k.Kid)
+}
+
+// jwtHashes maps the size suffix of a JWS algorithm to its hash.
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+var errJWTSignature = errors.New("signature does not match")
+
+// verifyJWT checks a JWS signature over the signing input using the given
+// public key, or HMAC secret for `HS*` algorithms.
+func verifyJWT(alg string, signingInput, signature []byte, key any) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type %T can't verify %s", key, alg)
+		}
+		if !ed25519.Verify(pub, signingInput, signature) {
+			return errJWTSignature
+		}
+		return nil
+	}
+
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h, ok := jwtHashes[alg[2:]]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	hasher := h.New()
+	hasher.Write(signingInput)
+	digest := hasher.Sum(nil)
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("a secret is required to verify %s", alg)
+		}
+		mac := hmac.New(h.New, secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errJWTSignature
+		}
+		return nil
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type %T can't verify %s", key, alg)
+		}
+		if alg[:2] == "PS" {
+			return rsa.VerifyPSS(pub, h, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(pub, h, digest, signature)
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type %T can't verify %s", key, alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errJWTSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errJWTSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+// fetchJWKS loads a key set from a URL.
+func fetchJWKS(ctx context.Context, client *http.Client, url string) (*JWKS, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "apibin-jwt")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected JWKS response status %d", resp.StatusCode)
+	}
+
+	jwks := &JWKS{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBytes)).Decode(jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	return jwks, nil
+}
+
+// JWTDecodeInput is a token to decode and optionally verify.
+type JWTDecodeInput struct {
+	Token   string `json:"token" minLength:"1" doc:"Compact serialized JWT"`
+	JWKSURL string `json:"jwks_url,omitempty" format:"uri" doc:"URL of a JWKS to verify asymmetric signatures against"`
+	Secret  string `json:"secret,omitempty" doc:"Shared secret to verify HS256, HS384, or HS512 signatures"`
+}
+
+// JWTVerification describes the result of checking a token's signature.
+type JWTVerification struct {
+	Verified bool   `json:"verified"`
+	KeyID    string `json:"kid,omitempty" doc:"ID of the key which verified the token"`
+	Error    string `json:"error,omitempty"`
+}
+
+// JWTDecodeResult is a decoded token.
+type JWTDecodeResult struct {
+	Header       map[string]any   `json:"header"`
+	Payload      map[string]any   `json:"payload"`
+	Signature    string           `json:"signature" doc:"Raw signature, base64url encoded"`
+	ExpiresAt    *time.Time       `json:"expires_at,omitempty"`
+	NotBefore    *time.Time       `json:"not_before,omitempty"`
+	IssuedAt     *time.Time       `json:"issued_at,omitempty"`
+	Issues       []string         `json:"issues" doc:"Problems found with the token's claims, if any"`
+	Verification *JWTVerification `json:"verification,omitempty" doc:"Present if a JWKS URL or secret was given"`
+}
+
+// numericDate reads a JWT NumericDate claim.
+func numericDate(claims map[string]any, name string) *time.Time {
+	v, ok := claims[name].(float64)
+	if !ok {
+		return nil
+	}
+	t := time.Unix(int64(v), 0).UTC()
+	return &t
+}
+
+func (s *APIServer) RegisterJWTDecode(api huma.API) {
+	client := newWebhookClient(s.opts.WebhooksAllowPrivate)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-jwt-decode",
+		Method:      http.MethodPost,
+		Path:        "/jwt/decode",
+		Description: "Decode a JWT and check its time-based claims. The signature is verified if a JWKS URL or shared secret is given. JWKS URLs follow the same private address rules as webhooks. Tokens are never stored or logged.",
+		Tags:        []string{"JWT"},
+	}, func(ctx context.Context, input *struct {
+		Body JWTDecodeInput
+	}) (*struct{ Body JWTDecodeResult }, error) {
+		invalid := func(msg string) error {
+			return huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Message:  msg,
+				Location: "body.token",
+			})
+		}
+
+		parts := strings.Split(strings.TrimSpace(input.Body.Token), ".")
+		if len(parts) != 3 {
+			return nil, invalid(fmt.Sprintf("expected 3 dot-separated parts but found %d", len(parts)))
+		}
+
+		result := JWTDecodeResult{Signature: parts[2], Issues: []string{}}
+		for i, target := range []*map[string]any{&result.Header, &result.Payload} {
+			name := []string{"header", "payload"}[i]
+			data, err := base64.RawURLEncoding.DecodeString(parts[i])
+			if err != nil {
+				return nil, invalid("invalid base64url " + name + ": " + err.Error())
+			}
+			if err := json.Unmarshal(data, target); err != nil {
+				return nil, invalid("invalid JSON " + name + ": " + err.Error())
+			}
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, invalid("invalid base64url signature: " + err.Error())
+		}
+
+		now := time.Now()
+		result.ExpiresAt = numericDate(result.Payload, "exp")
+		result.NotBefore = numericDate(result.Payload, "nbf")
+		result.IssuedAt = numericDate(result.Payload, "iat")
+		if result.ExpiresAt == nil {
+			result.Issues = append(result.Issues, "token has no expiration")
+		} else if !now.Before(*result.ExpiresAt) {
+			result.Issues = append(result.Issues, "token expired "+now.Sub(*result.ExpiresAt).Round(time.Second).String()+" ago")
+		}
+		if result.NotBefore != nil && now.Before(*result.NotBefore) {
+			result.Issues = append(result.Issues, "token is not valid for another "+result.NotBefore.Sub(now).Round(time.Second).String())
+		}
+		if result.IssuedAt != nil && now.Before(*result.IssuedAt) {
+			result.Issues = append(result.Issues, "token was issued in the future")
+		}
+
+		alg, _ := result.Header["alg"].(string)
+		if alg == "none" {
+			result.Issues = append(result.Issues, "token is unsigned")
+		}
+
+		if input.Body.JWKSURL != "" || input.Body.Secret != "" {
+			v := &JWTVerification{}
+			result.Verification = v
+			signingInput := []byte(parts[0] + "." + parts[1])
+
+			if strings.HasPrefix(alg, "HS") {
+				// HMAC tokens are only verified with a shared secret. An empty one
+				// would let anyone forge a token, and JWKS keys are never used.
+				if input.Body.Secret == "" {
+					v.Error = "a secret is required to verify " + alg
+				} else if err := verifyJWT(alg, signingInput, signature, []byte(input.Body.Secret)); err != nil {
+					v.Error = err.Error()
+				} else {
+					v.Verified = true
+				}
+			} else if input.Body.JWKSURL == "" {
+				v.Error = "a JWKS URL is required to verify " + alg
+			} else if jwks, err := fetchJWKS(ctx, client, input.Body.JWKSURL); err != nil {
+				v.Error = err.Error()
+			} else {
+				kid, _ := result.Header["kid"].(string)
+				v.Error = "no key found with kid " + kid
+				for _, k := range jwks.Keys {
+					if kid != "" && k.Kid != kid {
+						continue
+					}
+					key, err := k.PublicKey()
+					if err == nil {
+						err = verifyJWT(alg, signingInput, signature, key)
+					}
+					if err != nil {
+						v.Error = err.Error()
+						continue
+					}
+					v.Verified = true
+					v.KeyID = k.Kid
+					v.Error = ""
+					break
+				}
+			}
+		}
+
+		return &struct{ Body JWTDecodeResult }{Body: result}, nil
+	})
+}
@@ -0,0 +1,34 @@
+package apibin
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2/negotiation"
+)
+
+// landingPage is the embedded file served to browsers at `/`.
+const landingPage = "static/landing.html"
+
+// LandingPage serves a human-friendly landing page at `/` to clients which
+// prefer HTML, e.g. web browsers. Everyone else gets the echo response. JSON
+// wins ties so API clients listing both formats are unaffected.
+func LandingPage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept")
+		if negotiation.SelectQValueFast(r.Header.Get("Accept"), []string{"application/json", "application/cbor", "application/yaml", "text/html"}) != "text/html" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		landing, etag := selectPrecompressed(w, r, staticVariants[landingPage], staticETags[landingPage])
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		http.ServeContent(w, r, landingPage, staticModified, bytes.NewReader(landing))
+	})
+}
@@ -0,0 +1,90 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// MaxBodySize returns a middleware which rejects requests that declare a body
+// larger than `limit` bytes via `Content-Length` before reading any of it.
+// Bodies without a length are limited while being read, see `setMaxBodyBytes`.
+func MaxBodySize(api huma.API, limit int64) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		if cl, err := strconv.ParseInt(ctx.Header("Content-Length"), 10, 64); err == nil && cl > limit {
+			huma.WriteErr(api, ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is too large limit=%d bytes", limit))
+			return
+		}
+		next(ctx)
+	}
+}
+
+// setMaxBodyBytes sets the body size limit for every registered operation.
+// Huma rejects bodies which reach the limit, so one is added to allow bodies
+// of exactly `limit` bytes.
+func setMaxBodyBytes(api huma.API, limit int64) {
+	for _, item := range api.OpenAPI().Paths {
+		for _, op := range []*huma.Operation{item.Get, item.Put, item.Post, item.Patch, item.Delete} {
+			if op != nil {
+				op.MaxBodyBytes = limit + 1
+			}
+		}
+	}
+}
+
+// streamingKey is set in an operation's metadata to mark it as long-running,
+// which exempts it from the server's write timeout.
+const streamingKey = "apibin-streaming"
+
+// ExemptWriteTimeout removes the write deadline for operations marked as
+// streaming, since they may intentionally take longer than the server-wide
+// write timeout to complete.
+func ExemptWriteTimeout(ctx huma.Context, next func(huma.Context)) {
+	if op := ctx.Operation(); op != nil && op.Metadata[streamingKey] == true {
+		if w, ok := ctx.BodyWriter().(http.ResponseWriter); ok {
+			http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		}
+	}
+	next(ctx)
+}
+
+// LimitsModel describes the limits currently enforced by the server.
+type LimitsModel struct {
+	MaxBodyBytes int64 `json:"max_body_bytes" doc:"Maximum request body size in bytes"`
+	MaxBooks     int   `json:"max_books" doc:"Maximum number of stored books before the oldest are removed"`
+
+	ReadTimeout       string `json:"read_timeout" doc:"Maximum duration for reading an entire request"`
+	ReadHeaderTimeout string `json:"read_header_timeout" doc:"Maximum duration for reading request headers"`
+	WriteTimeout      string `json:"write_timeout" doc:"Maximum duration for writing a response, streaming operations are exempt"`
+	IdleTimeout       string `json:"idle_timeout" doc:"Maximum duration to wait for the next request on a keep-alive connection"`
+}
+
+type LimitsResponse struct {
+	Body LimitsModel
+}
+
+func (s *APIServer) RegisterLimits(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-limits",
+		Method:      http.MethodGet,
+		Path:        "/limits",
+		Description: "Get the limits currently enforced by the server",
+		Tags:        []string{"Limits"},
+	}, func(ctx context.Context, input *struct{}) (*LimitsResponse, error) {
+		return &LimitsResponse{
+			Body: LimitsModel{
+				MaxBodyBytes: s.opts.MaxBodySize,
+				MaxBooks:     maxBooks,
+
+				ReadTimeout:       s.opts.ReadTimeout,
+				ReadHeaderTimeout: s.opts.ReadHeaderTimeout,
+				WriteTimeout:      s.opts.WriteTimeout,
+				IdleTimeout:       s.opts.IdleTimeout,
+			},
+		}, nil
+	})
+}
@@ -0,0 +1,132 @@
+package apibin
+
+import (
+	"context"
+	"html"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/negotiation"
+)
+
+var loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis
+nostrud exercitation ullamco laboris nisi aliquip ex ea commodo consequat duis
+aute irure in reprehenderit voluptate velit esse cillum fugiat nulla pariatur
+excepteur sint occaecat cupidatat non proident sunt culpa qui officia deserunt
+mollit anim id est laborum`)
+
+// loremFormats maps each text format to its content type. The first is the
+// default when nothing is requested or negotiation fails.
+var loremFormats = []struct{ Name, ContentType string }{
+	{"plain", "text/plain"},
+	{"markdown", "text/markdown"},
+	{"html", "text/html"},
+}
+
+// loremSentence generates a capitalized sentence of filler words.
+func loremSentence(r *rand.Rand) string {
+	words := make([]string, 4+r.Intn(12))
+	for i := range words {
+		words[i] = pick(r, loremWords)
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ") + "."
+}
+
+// loremParagraphs generates paragraphs of filler text, each a list of
+// sentences.
+func loremParagraphs(r *rand.Rand, count int) [][]string {
+	paragraphs := make([][]string, count)
+	for i := range paragraphs {
+		sentences := make([]string, 3+r.Intn(5))
+		for j := range sentences {
+			sentences[j] = loremSentence(r)
+		}
+		paragraphs[i] = sentences
+	}
+	return paragraphs
+}
+
+// renderLorem renders paragraphs in the given format. Markdown and HTML
+// include a heading and some inline emphasis so renderers have something to
+// do.
+func renderLorem(format string, paragraphs [][]string) string {
+	sb := strings.Builder{}
+	switch format {
+	case "markdown":
+		sb.WriteString("# Lorem Ipsum\n")
+		for _, p := range paragraphs {
+			p[0] = "**" + strings.TrimSuffix(p[0], ".") + "**."
+			sb.WriteString("\n" + strings.Join(p, " ") + "\n")
+		}
+	case "html":
+		sb.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>Lorem Ipsum</title></head><body>\n<h1>Lorem Ipsum</h1>\n")
+		for _, p := range paragraphs {
+			sb.WriteString("<p><strong>" + html.EscapeString(p[0]) + "</strong> " + html.EscapeString(strings.Join(p[1:], " ")) + "</p>\n")
+		}
+		sb.WriteString("</body></html>\n")
+	default:
+		for i, p := range paragraphs {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(strings.Join(p, " ") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+type GenerateTextResponse struct {
+	ContentType string `header:"Content-Type"`
+	Seed        string `header:"X-Seed"`
+	Vary        string `header:"Vary"`
+	Body        []byte
+}
+
+func (s *APIServer) RegisterGenerateText(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "generate-text",
+		Method:      http.MethodGet,
+		Path:        "/generate/text",
+		Description: "Generate lorem ipsum filler text. The format is negotiated via the `Accept` header unless set explicitly, defaulting to plain text.",
+		Tags:        []string{"Generate"},
+	}, func(ctx context.Context, input *struct {
+		Accept     string `header:"Accept"`
+		Paragraphs int    `query:"paragraphs" default:"5" minimum:"1" maximum:"1000" doc:"Number of paragraphs to generate"`
+		Format     string `query:"format" enum:"plain,markdown,html" doc:"Text format, overriding the Accept header"`
+		Seed       int64  `query:"seed" doc:"Seed for the generator. Defaults to a random seed, which is returned in the X-Seed header."`
+	}) (*GenerateTextResponse, error) {
+		format := input.Format
+		if format == "" {
+			allowed := make([]string, len(loremFormats))
+			for i, f := range loremFormats {
+				allowed[i] = f.ContentType
+			}
+			selected := negotiation.SelectQValueFast(input.Accept, allowed)
+			for _, f := range loremFormats {
+				if f.ContentType == selected {
+					format = f.Name
+				}
+			}
+		}
+
+		contentType := loremFormats[0].ContentType
+		for _, f := range loremFormats {
+			if f.Name == format {
+				contentType = f.ContentType
+			}
+		}
+
+		r, seed := GenerateParams{Seed: input.Seed}.rand()
+		return &GenerateTextResponse{
+			ContentType: contentType + "; charset=utf-8",
+			Seed:        strconv.FormatInt(seed, 10),
+			Vary:        "Accept",
+			Body:        []byte(renderLorem(format, loremParagraphs(r, input.Paragraphs))),
+		}, nil
+	})
+}
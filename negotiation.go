@@ -0,0 +1,328 @@
+package apibin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/negotiation"
+)
+
+// strictMediaType is the only media type offered and accepted by the strict
+// negotiation operations.
+const strictMediaType = "application/vnd.apibin.strict+json"
+
+// AcceptRange is a single media range from an `Accept` header, or a coding or
+// language range from the other `Accept-*` headers.
+type AcceptRange struct {
+	Value string  `json:"value"`
+	Q     float64 `json:"q"`
+}
+
+// parseAccept parses a comma-separated list of ranges with optional `q`
+// weights. Other parameters are ignored and invalid weights default to 1.
+func parseAccept(header string) []AcceptRange {
+	ranges := []AcceptRange{}
+	for _, part := range splitQuoted(header, ',') {
+		params := splitQuoted(part, ';')
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		r := AcceptRange{Value: value, Q: 1}
+		for _, param := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q >= 0 && q <= 1 {
+					r.Q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// mediaRangeSpecificity returns how specifically a media range matches a
+// media type, or zero if it doesn't match at all.
+func mediaRangeSpecificity(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 3
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 2
+	case mediaRange == "*/*":
+		return 1
+	}
+	return 0
+}
+
+// matchAccept returns the weight given to an offered value by the most
+// specific matching range, along with that range. If no range matches then
+// the weight is zero and the range is nil.
+func matchAccept(ranges []AcceptRange, offered string, specificity func(string, string) int) (float64, *AcceptRange) {
+	var best *AcceptRange
+	bestSpecificity := 0
+	for i := range ranges {
+		if s := specificity(ranges[i].Value, offered); s > bestSpecificity {
+			best = &ranges[i]
+			bestSpecificity = s
+		}
+	}
+	if best == nil {
+		return 0, nil
+	}
+	return best.Q, best
+}
+
+// selectMediaType picks the offered media type with the highest weight in an
+// `Accept` header, preferring earlier offers on ties. Unlike
+// `negotiation.SelectQValueFast`, wildcards are supported. An empty header
+// accepts anything, and an empty string is returned if nothing is acceptable.
+func selectMediaType(header string, offered []string) string {
+	if strings.TrimSpace(header) == "" {
+		return offered[0]
+	}
+	ranges := parseAccept(header)
+	best := ""
+	bestQ := 0.0
+	for _, o := range offered {
+		if q, _ := matchAccept(ranges, o, mediaRangeSpecificity); q > bestQ {
+			best = o
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// StrictModel is the body sent and accepted by the strict negotiation
+// operations.
+type StrictModel struct {
+	Message   string `json:"message"`
+	MediaType string `json:"media_type"`
+}
+
+type StrictResponse struct {
+	ContentType string `header:"Content-Type"`
+	Vary        string `header:"Vary"`
+	Body        StrictModel
+}
+
+func (s *APIServer) RegisterNegotiationStrict(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-negotiation-strict",
+		Method:      http.MethodGet,
+		Path:        "/negotiation/strict",
+		Description: "Only offer `" + strictMediaType + "`. Requests with an `Accept` header which doesn't allow it get a `406 Not Acceptable` listing the available types.",
+		Tags:        []string{"Negotiation"},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "OK",
+				Content: map[string]*huma.MediaType{
+					strictMediaType: {Schema: api.OpenAPI().Components.Schemas.Schema(reflect.TypeOf(StrictModel{}), true, "")},
+				},
+			},
+		},
+		Errors: []int{http.StatusNotAcceptable},
+	}, func(ctx context.Context, input *struct {
+		Accept string `header:"Accept"`
+	}) (*StrictResponse, error) {
+		if selectMediaType(input.Accept, []string{strictMediaType}) == "" {
+			return nil, huma.Error406NotAcceptable("none of the accepted types are available", &huma.ErrorDetail{
+				Message:  "available types are " + strictMediaType,
+				Location: "header.Accept",
+				Value:    input.Accept,
+			})
+		}
+		return &StrictResponse{
+			ContentType: strictMediaType,
+			Vary:        "Accept",
+			Body:        StrictModel{Message: "negotiated", MediaType: strictMediaType},
+		}, nil
+	})
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch} {
+		huma.Register(api, huma.Operation{
+			OperationID: strings.ToLower(method) + "-negotiation-strict",
+			Method:      method,
+			Path:        "/negotiation/strict",
+			Description: "Only accept `" + strictMediaType + "` request bodies. Other types get a `415 Unsupported Media Type` with `Accept-Post` and `Accept-Patch` headers listing the supported type.",
+			Tags:        []string{"Negotiation"},
+			RequestBody: &huma.RequestBody{
+				Required: true,
+				Content: map[string]*huma.MediaType{
+					strictMediaType: {Schema: &huma.Schema{Type: huma.TypeObject}},
+				},
+			},
+			Errors: []int{http.StatusBadRequest, http.StatusUnsupportedMediaType},
+		}, func(ctx context.Context, input *struct {
+			RequestInfo
+			ContentType string `header:"Content-Type"`
+			RawBody     []byte
+		}) (*StrictResponse, error) {
+			if mediaType, _, _ := mime.ParseMediaType(input.ContentType); mediaType != strictMediaType {
+				// Advertise the supported type, see RFC 5789 and the W3C LDP
+				// `Accept-Post` header.
+				input.ctx.SetHeader("Accept-Post", strictMediaType)
+				input.ctx.SetHeader("Accept-Patch", strictMediaType)
+				return nil, huma.Error415UnsupportedMediaType("content type should be " + strictMediaType)
+			}
+			if !json.Valid(input.RawBody) {
+				return nil, huma.Error400BadRequest("request body is not valid JSON")
+			}
+			return &StrictResponse{
+				ContentType: strictMediaType,
+				Body:        StrictModel{Message: "accepted", MediaType: strictMediaType},
+			}, nil
+		})
+	}
+}
+
+// tokenRangeSpecificity matches `Accept-Encoding` codings, where `*` matches
+// anything.
+func tokenRangeSpecificity(codingRange, coding string) int {
+	switch codingRange {
+	case coding:
+		return 2
+	case "*":
+		return 1
+	}
+	return 0
+}
+
+// languageRangeSpecificity matches `Accept-Language` ranges using RFC 4647
+// basic filtering, where `en` matches `en` & `en-US` and `*` matches anything.
+func languageRangeSpecificity(languageRange, tag string) int {
+	switch {
+	case languageRange == tag:
+		return 3
+	case strings.HasPrefix(tag, languageRange+"-"):
+		return 2
+	case languageRange == "*":
+		return 1
+	}
+	return 0
+}
+
+// NegotiationCandidate is an offered value and its weight from the request
+// header.
+type NegotiationCandidate struct {
+	Value     string  `json:"value"`
+	Q         float64 `json:"q" doc:"Weight from the most specific matching range, where zero means not acceptable"`
+	MatchedBy string  `json:"matched_by,omitempty" doc:"The most specific range matching this value, if any"`
+}
+
+// NegotiationResult explains how one of the `Accept` headers was handled.
+type NegotiationResult struct {
+	Header     string                 `json:"header,omitempty" doc:"The raw request header value"`
+	Ranges     []AcceptRange          `json:"ranges" doc:"Parsed ranges ranked by weight, highest first"`
+	Candidates []NegotiationCandidate `json:"candidates" doc:"Values offered by the server and their weights"`
+	Selected   string                 `json:"selected" doc:"The value the server actually uses"`
+	Reason     string                 `json:"reason" doc:"Why the value was selected"`
+	Compliant  string                 `json:"compliant" doc:"The value a fully RFC 9110 compliant server would select, including wildcard support. Empty if nothing is acceptable, which could result in a 406."`
+}
+
+// newNegotiationResult ranks the ranges in a header and weighs each offered
+// value. The selection itself is left to the caller.
+func newNegotiationResult(header string, offered []string, specificity func(string, string) int) NegotiationResult {
+	result := NegotiationResult{
+		Header:     header,
+		Ranges:     parseAccept(header),
+		Candidates: []NegotiationCandidate{},
+	}
+	sort.SliceStable(result.Ranges, func(i, j int) bool {
+		return result.Ranges[i].Q > result.Ranges[j].Q
+	})
+
+	bestQ := 0.0
+	for _, o := range offered {
+		c := NegotiationCandidate{Value: o, Q: 1}
+		if strings.TrimSpace(header) != "" {
+			q, r := matchAccept(result.Ranges, o, specificity)
+			c.Q = q
+			if r != nil {
+				c.MatchedBy = r.Value
+			}
+		}
+		if c.Q > bestQ {
+			result.Compliant = o
+			bestQ = c.Q
+		}
+		result.Candidates = append(result.Candidates, c)
+	}
+	return result
+}
+
+// NegotiationDebug explains content negotiation for a request.
+type NegotiationDebug struct {
+	Accept         NegotiationResult `json:"accept"`
+	AcceptEncoding NegotiationResult `json:"accept_encoding"`
+	AcceptLanguage NegotiationResult `json:"accept_language"`
+}
+
+func (s *APIServer) RegisterNegotiationDebug(api huma.API) {
+	formats := []string{}
+	for _, f := range []string{"application/json", "application/cbor", "application/yaml"} {
+		if _, err := api.Negotiate(f); err == nil {
+			formats = append(formats, f)
+		}
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-negotiation-debug",
+		Method:      http.MethodGet,
+		Path:        "/negotiation/debug",
+		Description: "Explain how the `Accept`, `Accept-Encoding`, and `Accept-Language` request headers are parsed, how each offered value is weighted, and which the server selects and why. The server matches formats and encodings exactly, so wildcards and `q=0` exclusions like `*/*;q=0` may select differently from a fully compliant server, which is also shown.",
+		Tags:        []string{"Negotiation"},
+	}, func(ctx context.Context, input *struct {
+		Accept         string `header:"Accept"`
+		AcceptEncoding string `header:"Accept-Encoding"`
+		AcceptLanguage string `header:"Accept-Language"`
+	}) (*struct {
+		Vary string `header:"Vary"`
+		Body NegotiationDebug
+	}, error) {
+		debug := NegotiationDebug{
+			Accept:         newNegotiationResult(input.Accept, formats, mediaRangeSpecificity),
+			AcceptEncoding: newNegotiationResult(input.AcceptEncoding, supportedEncodings, tokenRangeSpecificity),
+			AcceptLanguage: newNegotiationResult(input.AcceptLanguage, []string{"en"}, languageRangeSpecificity),
+		}
+
+		a := &debug.Accept
+		if exact := negotiation.SelectQValueFast(input.Accept, formats); exact != "" {
+			a.Selected = exact
+			a.Reason = "highest weighted exact match"
+		} else {
+			a.Selected = formats[0]
+			a.Reason = "no exact match for an offered format, so the default is used"
+		}
+
+		e := &debug.AcceptEncoding
+		if input.AcceptEncoding == "" {
+			e.Selected = "identity"
+			e.Reason = "no Accept-Encoding header, so responses are not compressed"
+		} else if exact := negotiation.SelectQValueFast(input.AcceptEncoding, supportedEncodings); exact != "" {
+			e.Selected = exact
+			e.Reason = fmt.Sprintf("highest weighted exact match, used for responses of at least %d bytes which aren't already compressed", s.opts.CompressMinBytes)
+		} else {
+			e.Selected = "identity"
+			e.Reason = "no exact match for an offered encoding, so responses are not compressed"
+		}
+
+		l := &debug.AcceptLanguage
+		l.Selected = "en"
+		l.Reason = "responses are only available in English"
+
+		return &struct {
+			Vary string `header:"Vary"`
+			Body NegotiationDebug
+		}{Vary: "Accept, Accept-Encoding, Accept-Language", Body: debug}, nil
+	})
+}
@@ -0,0 +1,38 @@
+package apibin
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// humaContext lets `huma.Context` be embedded without its field name clashing
+// with the `Context()` method.
+type humaContext = huma.Context
+
+// notModifiedContext discards the body written for a `304 Not Modified`,
+// which isn't allowed to have one.
+type notModifiedContext struct {
+	humaContext
+	status int
+}
+
+func (c *notModifiedContext) SetStatus(code int) {
+	c.status = code
+	c.humaContext.SetStatus(code)
+}
+
+func (c *notModifiedContext) BodyWriter() io.Writer {
+	if c.status == http.StatusNotModified {
+		return io.Discard
+	}
+	return c.humaContext.BodyWriter()
+}
+
+// NotModifiedWithoutBody stops Huma from writing an error body for a
+// `304 Not Modified` returned by a failed precondition, which otherwise fails
+// with `http.ErrBodyNotAllowed` when the response isn't being compressed.
+func NotModifiedWithoutBody(ctx huma.Context, next func(huma.Context)) {
+	next(&notModifiedContext{humaContext: ctx})
+}
@@ -0,0 +1,36 @@
+package apibin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+)
+
+func TestNotModifiedWithoutBody(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		body   string
+	}{
+		{http.StatusNotModified, ""},
+		{http.StatusPreconditionFailed, "error"},
+		{http.StatusOK, "error"},
+	} {
+		t.Run(http.StatusText(tc.status), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			NotModifiedWithoutBody(humachi.NewContext(nil, r, w), func(ctx huma.Context) {
+				ctx.SetStatus(tc.status)
+				ctx.BodyWriter().Write([]byte("error"))
+			})
+			if w.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, w.Code)
+			}
+			if got := w.Body.String(); got != tc.body {
+				t.Errorf("expected body %q, got %q", tc.body, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,594 @@
+package apibin
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	// oauthTokenLifetime is how long issued access & ID tokens are valid.
+	oauthTokenLifetime = time.Hour
+
+	// oauthCodeLifetime is how long an authorization code can be exchanged.
+	oauthCodeLifetime = time.Minute
+
+	// maxOAuthCodes limits the number of outstanding authorization codes.
+	maxOAuthCodes = 1000
+
+	// minOAuthKeyRotation is the shortest key rotation interval, since each
+	// rotation generates a new RSA key.
+	minOAuthKeyRotation = time.Minute
+
+	// maxOAuthKeys limits the number of published signing keys, which is
+	// enough to keep every key for its tokens' lifetime at the shortest
+	// rotation interval.
+	maxOAuthKeys = 64
+)
+
+// oauthUser is the demo user every authorization is granted for.
+var oauthUser = map[string]any{
+	"sub":                sessionUsername,
+	"name":               "Demo User",
+	"preferred_username": sessionUsername,
+	"email":              "demo@example.com",
+	"email_verified":     true,
+}
+
+// oauthKey is an RSA key used to sign tokens.
+type oauthKey struct {
+	ID      string
+	Private *rsa.PrivateKey
+	Created time.Time
+
+	// Retired is when a newer key replaced this one for signing, which is
+	// zero while it's still in use.
+	Retired time.Time
+}
+
+// newOAuthKey generates a new signing key with a random key ID.
+func newOAuthKey() *oauthKey {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &oauthKey{
+		ID:      base64.RawURLEncoding.EncodeToString(id),
+		Private: private,
+		Created: time.Now(),
+	}
+}
+
+// JWK returns the public key in JWK format.
+func (k *oauthKey) JWK() JWK {
+	return JWK{
+		Kty: "RSA",
+		Kid: k.ID,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(k.Private.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.Private.E)).Bytes()),
+	}
+}
+
+// Sign creates an RS256 JWT with the given claims.
+func (k *oauthKey) Sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": k.ID})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.Private, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// oauthKeySet holds a server's signing keys, newest first. The first key is
+// only generated once it's needed, since that is slow.
+type oauthKeySet struct {
+	mu   sync.RWMutex
+	keys []*oauthKey
+}
+
+// Keys returns the signing keys, newest first, generating the first key if
+// needed.
+func (s *oauthKeySet) Keys() []*oauthKey {
+	s.mu.RLock()
+	keys := s.keys
+	s.mu.RUnlock()
+	if len(keys) > 0 {
+		return keys
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.keys) == 0 {
+		s.keys = []*oauthKey{newOAuthKey()}
+	}
+	return s.keys
+}
+
+// SigningKey returns the key currently used to sign new tokens.
+func (s *oauthKeySet) SigningKey() *oauthKey {
+	return s.Keys()[0]
+}
+
+// Rotate adds a new signing key. Previous keys are kept until every token
+// they signed has expired, so issued tokens can still be verified, up to
+// `maxOAuthKeys` in total. Nothing is done until the first key is used.
+func (s *oauthKeySet) Rotate() {
+	s.mu.RLock()
+	unused := len(s.keys) == 0
+	s.mu.RUnlock()
+	if unused {
+		return
+	}
+
+	key := newOAuthKey()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[0].Retired = key.Created
+	keys := []*oauthKey{key}
+	for _, k := range s.keys {
+		if len(keys) < maxOAuthKeys && key.Created.Sub(k.Retired) < oauthTokenLifetime {
+			keys = append(keys, k)
+		}
+	}
+	s.keys = keys
+}
+
+// RotateEvery rotates the signing key on an interval until the context is
+// done.
+func (s *oauthKeySet) RotateEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Rotate()
+		}
+	}
+}
+
+// Verify checks a token issued by this server and returns its claims.
+func (s *oauthKeySet) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errOAuthMalformed
+	}
+	header := map[string]any{}
+	claims := map[string]any{}
+	for i, target := range []*map[string]any{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			return nil, err
+		}
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range s.Keys() {
+		if k.ID != header["kid"] {
+			continue
+		}
+		if err := verifyJWT("RS256", []byte(parts[0]+"."+parts[1]), signature, &k.Private.PublicKey); err != nil {
+			return nil, err
+		}
+		if exp := numericDate(claims, "exp"); exp == nil || time.Now().After(*exp) {
+			return nil, errOAuthExpired
+		}
+		return claims, nil
+	}
+	return nil, errOAuthUnknownKey
+}
+
+// oauthCode is an outstanding authorization code.
+type oauthCode struct {
+	ClientID      string
+	RedirectURI   string
+	Scope         string
+	Nonce         string
+	Challenge     string
+	ChallengeType string
+	Expires       time.Time
+}
+
+// oauthCodesMu protects the outstanding authorization codes.
+var oauthCodesMu = sync.Mutex{}
+var oauthCodes = map[string]*oauthCode{}
+var oauthCodesOrder = []string{}
+
+// OAuthError is an RFC 6749 error response, which OAuth 2.0 clients expect
+// instead of the usual structured errors.
+type OAuthError struct {
+	status      int
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func (e *OAuthError) GetStatus() int {
+	return e.status
+}
+
+func newOAuthError(status int, code, description string) *OAuthError {
+	return &OAuthError{status: status, Code: code, Description: description}
+}
+
+var (
+	errOAuthMalformed  = newOAuthError(http.StatusUnauthorized, "invalid_token", "token is malformed")
+	errOAuthExpired    = newOAuthError(http.StatusUnauthorized, "invalid_token", "token has expired")
+	errOAuthUnknownKey = newOAuthError(http.StatusUnauthorized, "invalid_token", "token was not signed by a known key")
+)
+
+// issuer returns this server's OIDC issuer URL for a request.
+func issuer(ctx context.Context) string {
+	info := GetClientInfo(ctx)
+	return info.Scheme + "://" + info.Host
+}
+
+// OIDCConfiguration is the OpenID Connect discovery document.
+type OIDCConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// OAuthToken is a successful token response.
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+	IDToken     string `json:"id_token,omitempty"`
+}
+
+type OAuthTokenResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Pragma       string `header:"Pragma"`
+	Body         OAuthToken
+}
+
+type JWKSResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Body         JWKS
+}
+
+type OAuthRedirectResponse struct {
+	Status   int
+	Location string `header:"Location"`
+}
+
+// issueTokens creates an access token, plus an ID token for user grants with
+// the `openid` scope.
+func issueTokens(ctx context.Context, key *oauthKey, clientID, subject, scope, nonce string) *OAuthTokenResponse {
+	now := time.Now()
+	jti := make([]byte, 12)
+	rand.Read(jti)
+
+	access := map[string]any{
+		"iss":       issuer(ctx),
+		"sub":       subject,
+		"aud":       clientID,
+		"client_id": clientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(oauthTokenLifetime).Unix(),
+		"jti":       base64.RawURLEncoding.EncodeToString(jti),
+	}
+	if scope != "" {
+		access["scope"] = scope
+	}
+
+	resp := &OAuthTokenResponse{
+		CacheControl: "no-store",
+		Pragma:       "no-cache",
+		Body: OAuthToken{
+			AccessToken: key.Sign(access),
+			TokenType:   "Bearer",
+			ExpiresIn:   int(oauthTokenLifetime.Seconds()),
+			Scope:       scope,
+		},
+	}
+
+	if subject == oauthUser["sub"] && strings.Contains(" "+scope+" ", " openid ") {
+		id := map[string]any{
+			"iss":       issuer(ctx),
+			"aud":       clientID,
+			"iat":       now.Unix(),
+			"exp":       now.Add(oauthTokenLifetime).Unix(),
+			"auth_time": now.Unix(),
+		}
+		for k, v := range oauthUser {
+			id[k] = v
+		}
+		if nonce != "" {
+			id["nonce"] = nonce
+		}
+		resp.Body.IDToken = key.Sign(id)
+	}
+
+	return resp
+}
+
+func (s *APIServer) RegisterOAuth(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-openid-configuration",
+		Method:      http.MethodGet,
+		Path:        "/.well-known/openid-configuration",
+		Description: "OpenID Connect discovery document for the mock OAuth 2.0 server, so standard OIDC client libraries can be pointed at apibin.",
+		Tags:        []string{"OAuth"},
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body OIDCConfiguration }, error) {
+		iss := issuer(ctx)
+		return &struct{ Body OIDCConfiguration }{Body: OIDCConfiguration{
+			Issuer:                            iss,
+			AuthorizationEndpoint:             iss + "/oauth/authorize",
+			TokenEndpoint:                     iss + "/oauth/token",
+			UserinfoEndpoint:                  iss + "/oauth/userinfo",
+			JWKSURI:                           iss + "/oauth/jwks",
+			ResponseTypesSupported:            []string{"code"},
+			GrantTypesSupported:               []string{"authorization_code", "client_credentials", "password"},
+			SubjectTypesSupported:             []string{"public"},
+			IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+			ScopesSupported:                   []string{"openid", "profile", "email"},
+			ClaimsSupported:                   []string{"sub", "name", "preferred_username", "email", "email_verified"},
+			TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+			CodeChallengeMethodsSupported:     []string{"S256", "plain"},
+		}}, nil
+	})
+
+	rotate := mustParseDuration("jwks-rotate-interval", s.opts.JWKSRotateInterval)
+	if rotate > 0 {
+		go s.oauthKeys.RotateEvery(s.ctx, rotate)
+	}
+
+	jwksOperations := []struct{ id, path string }{
+		{"get-oauth-jwks", "/oauth/jwks"},
+		{"get-well-known-jwks", "/.well-known/jwks.json"},
+	}
+	for _, op := range jwksOperations {
+		huma.Register(api, huma.Operation{
+			OperationID: op.id,
+			Method:      http.MethodGet,
+			Path:        op.path,
+			Description: fmt.Sprintf("Public keys used to sign tokens issued by the mock OAuth 2.0 server. When key rotation is enabled, a new signing key is added on each interval and previous keys stay published for %s after being replaced, until every token they signed has expired. Responses may be cached for half the rotation interval.", oauthTokenLifetime),
+			Tags:        []string{"OAuth"},
+		}, func(ctx context.Context, input *struct{}) (*JWKSResponse, error) {
+			resp := &JWKSResponse{
+				CacheControl: "no-cache",
+				Body:         JWKS{Keys: []JWK{}},
+			}
+			if rotate > 0 {
+				resp.CacheControl = fmt.Sprintf("public, max-age=%d", int(rotate.Seconds()/2))
+			}
+			for _, k := range s.oauthKeys.Keys() {
+				resp.Body.Keys = append(resp.Body.Keys, k.JWK())
+			}
+			return resp, nil
+		})
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-oauth-authorize",
+		Method:      http.MethodGet,
+		Path:        "/oauth/authorize",
+		Description: "Authorization endpoint which immediately approves the request for the demo user and redirects back to the client with a code. Any client ID and redirect URI are accepted. Supports PKCE.",
+		Tags:        []string{"OAuth"},
+	}, func(ctx context.Context, input *struct {
+		ResponseType        string `query:"response_type" required:"true"`
+		ClientID            string `query:"client_id" required:"true"`
+		RedirectURI         string `query:"redirect_uri" required:"true" format:"uri"`
+		Scope               string `query:"scope"`
+		State               string `query:"state"`
+		Nonce               string `query:"nonce"`
+		CodeChallenge       string `query:"code_challenge"`
+		CodeChallengeMethod string `query:"code_challenge_method" enum:"plain,S256"`
+	}) (*OAuthRedirectResponse, error) {
+		redirect, err := url.Parse(input.RedirectURI)
+		if err != nil || !redirect.IsAbs() {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Message:  "expected an absolute redirect URI",
+				Location: "query.redirect_uri",
+				Value:    input.RedirectURI,
+			})
+		}
+
+		query := redirect.Query()
+		if input.State != "" {
+			query.Set("state", input.State)
+		}
+
+		if input.ResponseType != "code" {
+			query.Set("error", "unsupported_response_type")
+		} else {
+			b := make([]byte, 16)
+			rand.Read(b)
+			code := base64.RawURLEncoding.EncodeToString(b)
+
+			method := input.CodeChallengeMethod
+			if method == "" && input.CodeChallenge != "" {
+				method = "plain"
+			}
+
+			oauthCodesMu.Lock()
+			oauthCodes[code] = &oauthCode{
+				ClientID:      input.ClientID,
+				RedirectURI:   input.RedirectURI,
+				Scope:         input.Scope,
+				Nonce:         input.Nonce,
+				Challenge:     input.CodeChallenge,
+				ChallengeType: method,
+				Expires:       time.Now().Add(oauthCodeLifetime),
+			}
+			oauthCodesOrder = append(oauthCodesOrder, code)
+			for len(oauthCodesOrder) > maxOAuthCodes {
+				delete(oauthCodes, oauthCodesOrder[0])
+				oauthCodesOrder = oauthCodesOrder[1:]
+			}
+			oauthCodesMu.Unlock()
+
+			query.Set("code", code)
+		}
+
+		redirect.RawQuery = query.Encode()
+		return &OAuthRedirectResponse{
+			Status:   http.StatusFound,
+			Location: redirect.String(),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-oauth-token",
+		Method:      http.MethodPost,
+		Path:        "/oauth/token",
+		Description: "Token endpoint supporting the `authorization_code`, `client_credentials`, and `password` grants. Client secrets are not checked. The password grant accepts the demo credentials `demo` / `demo`.",
+		Tags:        []string{"OAuth"},
+		RequestBody: &huma.RequestBody{
+			Required: true,
+			Content: map[string]*huma.MediaType{
+				"application/x-www-form-urlencoded": {Schema: &huma.Schema{
+					Type:     huma.TypeObject,
+					Required: []string{"grant_type"},
+					Properties: map[string]*huma.Schema{
+						"grant_type":    {Type: huma.TypeString, Enum: []any{"authorization_code", "client_credentials", "password"}},
+						"code":          {Type: huma.TypeString},
+						"redirect_uri":  {Type: huma.TypeString},
+						"code_verifier": {Type: huma.TypeString},
+						"client_id":     {Type: huma.TypeString},
+						"client_secret": {Type: huma.TypeString},
+						"username":      {Type: huma.TypeString},
+						"password":      {Type: huma.TypeString},
+						"scope":         {Type: huma.TypeString},
+					},
+				}},
+			},
+		},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization"`
+		ContentType   string `header:"Content-Type"`
+		RawBody       []byte
+	}) (*OAuthTokenResponse, error) {
+		if mediaType, _, _ := mime.ParseMediaType(input.ContentType); mediaType != "application/x-www-form-urlencoded" {
+			return nil, newOAuthError(http.StatusBadRequest, "invalid_request", "expected a form-encoded body")
+		}
+		form, err := url.ParseQuery(string(input.RawBody))
+		if err != nil {
+			return nil, newOAuthError(http.StatusBadRequest, "invalid_request", err.Error())
+		}
+
+		// Clients may authenticate via basic auth or the form.
+		clientID := form.Get("client_id")
+		if clientID == "" {
+			r := http.Request{Header: http.Header{"Authorization": {input.Authorization}}}
+			clientID, _, _ = r.BasicAuth()
+		}
+		if clientID == "" {
+			return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "client_id is required")
+		}
+
+		switch form.Get("grant_type") {
+		case "client_credentials":
+			return issueTokens(ctx, s.oauthKeys.SigningKey(), clientID, clientID, form.Get("scope"), ""), nil
+		case "password":
+			user := subtle.ConstantTimeCompare([]byte(form.Get("username")), []byte(sessionUsername))
+			pass := subtle.ConstantTimeCompare([]byte(form.Get("password")), []byte(sessionPassword))
+			if user&pass != 1 {
+				return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "invalid username or password")
+			}
+			return issueTokens(ctx, s.oauthKeys.SigningKey(), clientID, sessionUsername, form.Get("scope"), ""), nil
+		case "authorization_code":
+			oauthCodesMu.Lock()
+			code := oauthCodes[form.Get("code")]
+			delete(oauthCodes, form.Get("code"))
+			oauthCodesMu.Unlock()
+
+			if code == nil || time.Now().After(code.Expires) {
+				return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "unknown or expired code")
+			}
+			if code.ClientID != clientID || code.RedirectURI != form.Get("redirect_uri") {
+				return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "client_id or redirect_uri does not match the authorization request")
+			}
+			if code.Challenge != "" {
+				verifier := form.Get("code_verifier")
+				if code.ChallengeType == "S256" {
+					sum := sha256.Sum256([]byte(verifier))
+					verifier = base64.RawURLEncoding.EncodeToString(sum[:])
+				}
+				if verifier == "" || subtle.ConstantTimeCompare([]byte(verifier), []byte(code.Challenge)) != 1 {
+					return nil, newOAuthError(http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code_challenge")
+				}
+			}
+			return issueTokens(ctx, s.oauthKeys.SigningKey(), clientID, sessionUsername, code.Scope, code.Nonce), nil
+		}
+
+		return nil, newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "grant_type must be one of authorization_code, client_credentials, or password")
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-oauth-userinfo",
+		Method:      http.MethodGet,
+		Path:        "/oauth/userinfo",
+		Description: "Get the claims about the user an access token was issued for. Tokens from the client credentials grant only include the subject.",
+		Tags:        []string{"OAuth"},
+		Errors:      []int{http.StatusUnauthorized},
+	}, func(ctx context.Context, input *struct {
+		Authorization string `header:"Authorization"`
+	}) (*struct{ Body map[string]any }, error) {
+		token, ok := strings.CutPrefix(input.Authorization, "Bearer ")
+		if !ok {
+			return nil, newOAuthError(http.StatusUnauthorized, "invalid_token", "a bearer token is required")
+		}
+		claims, err := s.oauthKeys.Verify(token)
+		if err != nil {
+			if oauthErr, ok := err.(*OAuthError); ok {
+				return nil, oauthErr
+			}
+			return nil, newOAuthError(http.StatusUnauthorized, "invalid_token", err.Error())
+		}
+
+		if claims["sub"] == oauthUser["sub"] {
+			return &struct{ Body map[string]any }{Body: oauthUser}, nil
+		}
+		return &struct{ Body map[string]any }{Body: map[string]any{"sub": claims["sub"]}}, nil
+	})
+}
@@ -0,0 +1,46 @@
+package apibin
+
+import (
+	"testing"
+)
+
+func TestOAuthKeySet(t *testing.T) {
+	keys := &oauthKeySet{}
+
+	// Nothing is generated until a key is used.
+	keys.Rotate()
+	if len(keys.keys) != 0 {
+		t.Fatalf("expected no keys before first use, got %d", len(keys.keys))
+	}
+
+	first := keys.SigningKey()
+	token := first.Sign(map[string]any{"sub": "test", "exp": 9999999999})
+	keys.Rotate()
+	if keys.SigningKey() == first {
+		t.Fatal("expected a new signing key after rotating")
+	}
+	if _, err := keys.Verify(token); err != nil {
+		t.Errorf("expected tokens signed by the previous key to verify, got %v", err)
+	}
+
+	// Pretend the keys were retired long ago, so they're dropped.
+	for _, k := range keys.keys[1:] {
+		k.Retired = k.Retired.Add(-2 * oauthTokenLifetime)
+	}
+	keys.Rotate()
+	if len(keys.keys) != 2 {
+		t.Errorf("expected only the new & previous keys, got %d", len(keys.keys))
+	}
+}
+
+func TestOAuthKeySetLimit(t *testing.T) {
+	keys := &oauthKeySet{}
+	key := keys.SigningKey()
+	for i := 0; i < maxOAuthKeys+1; i++ {
+		keys.keys = append(keys.keys, &oauthKey{ID: key.ID, Private: key.Private, Created: key.Created, Retired: key.Created})
+	}
+	keys.Rotate()
+	if len(keys.keys) != maxOAuthKeys {
+		t.Errorf("expected %d keys, got %d", maxOAuthKeys, len(keys.keys))
+	}
+}
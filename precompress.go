@@ -0,0 +1,199 @@
+package apibin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/danielgtaylor/huma/v2/negotiation"
+	"golang.org/x/exp/slices"
+)
+
+// maxPrecompressed limits how many response bodies have their precompressed
+// variants kept. The least recently used are removed first.
+const maxPrecompressed = 32
+
+// precompressedPaths are generated responses which are large but rarely
+// change, so are worth sending precompressed.
+var precompressedPaths = map[string]bool{
+	"/example":      true,
+	"/docs":         true,
+	"/books/export": true,
+}
+
+// precompress returns the data encoded with each supported encoding at the
+// best compression level, keyed by encoding. The unencoded data is stored
+// with an empty key. Encodings which don't make the data smaller are skipped.
+func precompress(data []byte) (map[string][]byte, error) {
+	variants := map[string][]byte{"": data}
+
+	gzBuf := &bytes.Buffer{}
+	gz, _ := gzip.NewWriterLevel(gzBuf, gzip.BestCompression)
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if gzBuf.Len() < len(data) {
+		variants[gzipEncoding] = gzBuf.Bytes()
+	}
+
+	brBuf := &bytes.Buffer{}
+	br := brotli.NewWriterLevel(brBuf, brotli.BestCompression)
+	if _, err := br.Write(data); err != nil {
+		return nil, err
+	}
+	if err := br.Close(); err != nil {
+		return nil, err
+	}
+	if brBuf.Len() < len(data) {
+		variants[brotliEncoding] = brBuf.Bytes()
+	}
+
+	return variants, nil
+}
+
+// selectPrecompressed picks the precompressed variant to send for a request,
+// setting the `Content-Encoding` and `Vary` headers. The ETag gets the
+// encoding as a suffix, since each encoding is a different representation.
+// This prevents the `ContentEncoding` middleware from compressing it again.
+func selectPrecompressed(w http.ResponseWriter, r *http.Request, variants map[string][]byte, etag string) ([]byte, string) {
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	offered := []string{}
+	for _, encoding := range supportedEncodings {
+		if variants[encoding] != nil {
+			offered = append(offered, encoding)
+		}
+	}
+
+	encoding := ""
+	if ac := r.Header.Get("Accept-Encoding"); ac != "" && len(offered) > 0 {
+		encoding = negotiation.SelectQValueFast(ac, offered)
+	}
+	if encoding == "" {
+		return variants[""], etag
+	}
+
+	w.Header().Set("Content-Encoding", encoding)
+	if r.Method == http.MethodHead && r.Header.Get("Range") == "" {
+		// `http.ServeContent` only sets a length for unencoded content, since
+		// it doesn't know whether ranges apply to the encoded content. Without
+		// a body the length can't otherwise be determined, so set it here.
+		w.Header().Set("Content-Length", strconv.Itoa(len(variants[encoding])))
+	}
+	if strings.HasSuffix(etag, `"`) {
+		etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+	}
+	return variants[encoding], etag
+}
+
+// precompressedCache keeps the precompressed variants of recently sent
+// response bodies, keyed by a hash of the body.
+type precompressedCache struct {
+	mu       sync.Mutex
+	variants map[string]map[string][]byte
+	order    []string
+}
+
+// get returns the variants for a body, compressing it if it wasn't sent
+// recently.
+func (c *precompressedCache) get(body []byte) (map[string][]byte, error) {
+	key := genETagBytes(body)
+
+	c.mu.Lock()
+	variants := c.variants[key]
+	c.mu.Unlock()
+	if variants == nil {
+		var err error
+		if variants, err = precompress(body); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.variants[key] = variants
+	c.order = slices.DeleteFunc(c.order, func(v string) bool { return v == key })
+	c.order = append(c.order, key)
+	for len(c.order) > maxPrecompressed {
+		delete(c.variants, c.order[0])
+		c.order = c.order[1:]
+	}
+	return variants, nil
+}
+
+// precompressWriter buffers a response so it can be sent precompressed.
+type precompressWriter struct {
+	http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *precompressWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *precompressWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *precompressWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+// Flush is a no-op since the whole body is needed to pick a variant.
+func (w *precompressWriter) Flush() {}
+
+// Unwrap returns the underlying response writer, which allows the use of
+// `http.ResponseController`.
+func (w *precompressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// PrecompressedResponses sends successful `GET` responses for the large,
+// rarely changing `precompressedPaths` as precompressed variants when the
+// client accepts them. Each response is still generated so it reflects the
+// current state and handles conditional requests, but a body is only
+// compressed the first time it's seen. The ETag is kept as-is, just like
+// when `ContentEncoding` compresses a response, which it must run after.
+func PrecompressedResponses(next http.Handler) http.Handler {
+	cache := &precompressedCache{variants: map[string]map[string][]byte{}}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !precompressedPaths[r.URL.Path] || r.Header.Get("Accept-Encoding") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		pw := &precompressWriter{ResponseWriter: w, header: http.Header{}}
+		next.ServeHTTP(pw, r)
+		if pw.status == 0 {
+			pw.status = http.StatusOK
+		}
+
+		for name, values := range pw.header {
+			w.Header()[name] = values
+		}
+		body := pw.body.Bytes()
+		if pw.status == http.StatusOK && pw.header.Get("Content-Encoding") == "" {
+			if variants, err := cache.get(body); err == nil {
+				body, _ = selectPrecompressed(w, r, variants, "")
+				w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			}
+		}
+		w.WriteHeader(pw.status)
+		w.Write(body)
+	})
+}
@@ -0,0 +1,46 @@
+package apibin
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestPrecompressedResponses(t *testing.T) {
+	server := newTestServer(t, nil)
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	for _, path := range []string{"/example", "/docs", "/books/export"} {
+		t.Run(path, func(t *testing.T) {
+			resp, err := client.Get(server.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			// The second request should use the cached variant.
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+				req.Header.Set("Accept-Encoding", "br")
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ce := resp.Header.Get("Content-Encoding"); ce != "br" {
+					t.Fatalf("expected br content encoding, got %q", ce)
+				}
+				got, err := io.ReadAll(brotli.NewReader(resp.Body))
+				resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != string(want) {
+					t.Fatalf("expected the decoded body to match the uncompressed one")
+				}
+			}
+		})
+	}
+}
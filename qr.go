@@ -0,0 +1,81 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrLevels maps error correction level names to the encoder's levels.
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"low":     qrcode.Low,
+	"medium":  qrcode.Medium,
+	"high":    qrcode.High,
+	"highest": qrcode.Highest,
+}
+
+// qrSVG renders a QR code as an SVG document with one path for all of the
+// dark modules, scaled to the given size.
+func qrSVG(q *qrcode.QRCode, size int) []byte {
+	bitmap := q.Bitmap()
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, len(bitmap), len(bitmap))
+	fmt.Fprintf(&sb, `<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="`)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&sb, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	sb.WriteString(`"/></svg>`)
+	return []byte(sb.String())
+}
+
+type GetQRResponse struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+func (s *APIServer) RegisterGetQR(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-qr",
+		Method:      http.MethodGet,
+		Path:        "/qr",
+		Description: "Render a QR code for the given text. Text which is too long for the error correction level results in a validation error.",
+		Tags:        []string{"Images"},
+	}, func(ctx context.Context, input *struct {
+		Data   string `query:"data" required:"true" minLength:"1" maxLength:"4096" doc:"Text to encode"`
+		Size   int    `query:"size" default:"256" minimum:"21" maximum:"2048" doc:"Width & height in pixels"`
+		Format string `query:"format" enum:"png,svg" default:"png" doc:"Image format"`
+		Level  string `query:"level" enum:"low,medium,high,highest" default:"medium" doc:"Error correction level. Higher levels survive more damage but hold less data."`
+	}) (*GetQRResponse, error) {
+		q, err := qrcode.New(input.Data, qrLevels[input.Level])
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Message:  fmt.Sprintf("unable to encode %d bytes at %s error correction: %s", len(input.Data), input.Level, err),
+				Location: "query.data",
+			})
+		}
+
+		if input.Format == "svg" {
+			return &GetQRResponse{
+				ContentType: "image/svg+xml",
+				Body:        qrSVG(q, input.Size),
+			}, nil
+		}
+
+		body, err := q.PNG(input.Size)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("unable to encode image", err)
+		}
+		return &GetQRResponse{
+			ContentType: "image/png",
+			Body:        body,
+		}, nil
+	})
+}
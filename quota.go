@@ -0,0 +1,183 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+)
+
+var apiKeyKey contextKey = "apibin/api-key"
+
+// apiKeyHeader carries the API key when API key auth is enabled.
+const apiKeyHeader = "X-Api-Key"
+
+// apiKeyScheme names the API key security scheme in the OpenAPI.
+const apiKeyScheme = "apiKey"
+
+// quotaPath reports usage without counting against the quota.
+const quotaPath = "/quota"
+
+// keyUsage tracks the requests made with an API key on a given UTC day.
+type keyUsage struct {
+	day   string
+	count int
+}
+
+// apiKeyQuotas counts requests per API key, resetting at midnight UTC.
+type apiKeyQuotas struct {
+	mu    sync.Mutex
+	limit int
+	usage map[string]*keyUsage
+}
+
+// QuotaModel describes the usage of an API key's daily quota.
+type QuotaModel struct {
+	Limit     int       `json:"limit" doc:"Requests allowed per day"`
+	Used      int       `json:"used" doc:"Requests made today, not counting requests to /quota"`
+	Remaining int       `json:"remaining" doc:"Requests left today"`
+	Reset     time.Time `json:"reset" doc:"When the quota resets, at midnight UTC"`
+}
+
+// quotaReset returns when the quota for the day containing `now` resets.
+func quotaReset(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// use records a request with the key, returning the updated usage and whether
+// the request is within the quota. Rejected requests aren't counted.
+func (q *apiKeyQuotas) use(key string, count bool) (QuotaModel, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	day := now.UTC().Format(time.DateOnly)
+	u := q.usage[key]
+	if u == nil || u.day != day {
+		u = &keyUsage{day: day}
+		q.usage[key] = u
+	}
+
+	allowed := u.count < q.limit
+	if allowed && count {
+		u.count++
+	}
+	return QuotaModel{
+		Limit:     q.limit,
+		Used:      u.count,
+		Remaining: q.limit - u.count,
+		Reset:     quotaReset(now),
+	}, allowed
+}
+
+// GetQuota returns the quota usage for the request's API key, or nil if API
+// key auth is disabled.
+func GetQuota(ctx context.Context) *QuotaModel {
+	quota, _ := ctx.Value(apiKeyKey).(*QuotaModel)
+	return quota
+}
+
+// apiKeyExempt returns whether a path can be used without an API key, which
+// lets clients discover the API via its docs, OpenAPI, & schemas.
+func apiKeyExempt(path string) bool {
+	for _, version := range apiVersions {
+		path = strings.TrimPrefix(path, "/"+version)
+	}
+	return path == "/docs" || strings.HasPrefix(path, "/openapi") || strings.HasPrefix(path, "/schemas/")
+}
+
+// APIKeyQuotas requires a known API key in the `X-Api-Key` header, allowing
+// each key `limit` requests per day. Every response includes the quota in
+// `X-RateLimit-*` headers, and requests over it get a `429 Too Many Requests`
+// until midnight UTC. CORS preflights & discovery paths don't need a key. The
+// API is passed by reference since the middleware is created before it.
+func APIKeyQuotas(api *huma.API, keys []string, limit int) func(http.Handler) http.Handler {
+	known := map[string]bool{}
+	for _, key := range keys {
+		known[key] = true
+	}
+	quotas := &apiKeyQuotas{limit: limit, usage: map[string]*keyUsage{}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions || apiKeyExempt(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := humachi.NewContext(nil, r, w)
+			key := r.Header.Get(apiKeyHeader)
+			if key == "" || !known[key] {
+				msg := "missing API key, send one in the " + apiKeyHeader + " header"
+				if key != "" {
+					msg = "unknown API key"
+				}
+				huma.WriteErr(*api, ctx, http.StatusUnauthorized, msg)
+				return
+			}
+
+			counted := r.URL.Path != quotaPath
+			quota, allowed := quotas.use(key, counted)
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(quota.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(quota.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(quota.Reset.Unix(), 10))
+			if !allowed && counted {
+				w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(quota.Reset).Seconds())+1))
+				huma.WriteErr(*api, ctx, http.StatusTooManyRequests, fmt.Sprintf("daily quota of %d requests exceeded, resets at %s", quota.Limit, quota.Reset.Format(time.RFC3339)))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyKey, &quota)))
+		})
+	}
+}
+
+// documentAPIKeys adds the API key security scheme to an API's config, which
+// applies to every operation.
+func documentAPIKeys(config *huma.Config) {
+	if config.Components.SecuritySchemes == nil {
+		config.Components.SecuritySchemes = map[string]*huma.SecurityScheme{}
+	}
+	config.Components.SecuritySchemes[apiKeyScheme] = &huma.SecurityScheme{
+		Type:        "apiKey",
+		In:          "header",
+		Name:        apiKeyHeader,
+		Description: "API key with a daily quota, see `/quota`",
+	}
+	config.Security = []map[string][]string{{apiKeyScheme: {}}}
+}
+
+type QuotaResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Body         QuotaModel
+}
+
+func (s *APIServer) RegisterQuota(api huma.API) {
+	if s.opts.APIKeys == "" {
+		return
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-quota",
+		Method:      http.MethodGet,
+		Path:        quotaPath,
+		Description: "Get the daily request quota usage for the API key. Requests to this operation don't count against the quota.",
+		Tags:        []string{"Quota"},
+	}, func(ctx context.Context, input *struct{}) (*QuotaResponse, error) {
+		quota := GetQuota(ctx)
+		if quota == nil {
+			return nil, huma.Error401Unauthorized("missing API key")
+		}
+		return &QuotaResponse{
+			CacheControl: "no-store",
+			Body:         *quota,
+		}, nil
+	})
+}
@@ -0,0 +1,29 @@
+package apibin
+
+import (
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+)
+
+// readOnlyDocs is appended to the API description in read-only mode.
+const readOnlyDocs = "\n\n**This server is read-only.** Requests using methods other than `GET`, `HEAD`, or `OPTIONS` are rejected with a `403 Forbidden` error."
+
+// ReadOnly rejects requests with methods which may change state, allowing
+// only `GET`, `HEAD`, and `OPTIONS`, so that public instances have no shared
+// mutable state. The API is passed by reference since the middleware is
+// created before it.
+func ReadOnly(api *huma.API) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := humachi.NewContext(nil, r, w)
+			huma.WriteErr(*api, ctx, http.StatusForbidden, "this server is read-only, so "+r.Method+" requests are not allowed")
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// redirectMethods are the methods accepted by redirect operations, so that
+// clients can check which are preserved when following each status code.
+var redirectMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+type RedirectResponse struct {
+	Status   int
+	Location string `header:"Location"`
+}
+
+// RedirectParams configures the redirect status code.
+type RedirectParams struct {
+	Status string `query:"status" enum:"301,302,303,307,308" default:"302" doc:"Redirect status code. 301, 302, and 303 let clients switch to GET, while 307 and 308 require the method and body to be preserved. 301 and 308 are cacheable by default."`
+}
+
+// status returns the redirect status code.
+func (p RedirectParams) status() int {
+	code, _ := strconv.Atoi(p.Status)
+	return code
+}
+
+// query returns the query string to pass along to the next hop, if any.
+func (p RedirectParams) query() string {
+	if p.Status == "302" {
+		return ""
+	}
+	return "?status=" + p.Status
+}
+
+// redirectInput is the input for redirect chains.
+type redirectInput struct {
+	N int `path:"n" minimum:"1" maximum:"20" doc:"Number of redirects"`
+	RedirectParams
+}
+
+// redirectChains describe the redirect chain variants. Each returns the
+// Location for the next hop.
+var redirectChains = []struct {
+	Name        string
+	Description string
+	Location    func(ctx context.Context, input *redirectInput) string
+}{
+	{
+		Name:        "redirect",
+		Description: "Redirect `n` times with the given status code before ending at the echo endpoint at `/`, which shows the method and body that arrived after following the chain.",
+		Location: func(ctx context.Context, input *redirectInput) string {
+			if input.N == 1 {
+				return "/"
+			}
+			return "/redirect/" + strconv.Itoa(input.N-1) + input.query()
+		},
+	},
+	{
+		Name:        "relative-redirect",
+		Description: "Redirect `n` times using path-relative `Location` values like `2` and `../`, which clients must resolve against the current URL, before ending at the echo endpoint at `/`.",
+		Location: func(ctx context.Context, input *redirectInput) string {
+			if input.N == 1 {
+				return "../"
+			}
+			return strconv.Itoa(input.N-1) + input.query()
+		},
+	},
+	{
+		Name:        "absolute-redirect",
+		Description: "Redirect `n` times using fully-qualified `Location` values, built from the scheme and host the client used including via trusted proxies, before ending at the echo endpoint at `/`.",
+		Location: func(ctx context.Context, input *redirectInput) string {
+			client := GetClientInfo(ctx)
+			base := client.Scheme + "://" + client.Host
+			if input.N == 1 {
+				return base + "/"
+			}
+			return base + "/absolute-redirect/" + strconv.Itoa(input.N-1) + input.query()
+		},
+	},
+}
+
+func (s *APIServer) RegisterRedirect(api huma.API) {
+	for _, chain := range redirectChains {
+		chain := chain
+		for _, method := range redirectMethods {
+			huma.Register(api, huma.Operation{
+				OperationID: strings.ToLower(method) + "-" + chain.Name,
+				Method:      method,
+				Path:        "/" + chain.Name + "/{n}",
+				Description: chain.Description,
+				Tags:        []string{"Redirects"},
+			}, func(ctx context.Context, input *redirectInput) (*RedirectResponse, error) {
+				return &RedirectResponse{Status: input.status(), Location: chain.Location(ctx, input)}, nil
+			})
+		}
+	}
+}
+
+func (s *APIServer) RegisterRedirectLoop(api huma.API) {
+	for _, method := range redirectMethods {
+		huma.Register(api, huma.Operation{
+			OperationID: strings.ToLower(method) + "-redirect-loop",
+			Method:      method,
+			Path:        "/redirect/loop",
+			Description: "Redirect forever, either to the same URL or around a cycle of two or three URLs, to check that clients detect loops or give up after too many redirects. Use `vary` to make every hop's URL unique, which defeats loop detection based on seen URLs.",
+			Tags:        []string{"Redirects"},
+		}, func(ctx context.Context, input *struct {
+			Cycle int  `query:"cycle" default:"1" minimum:"1" maximum:"3" doc:"Number of distinct URLs in the loop"`
+			Hop   int  `query:"hop" minimum:"0" doc:"Current position in the loop"`
+			Vary  bool `query:"vary" doc:"Add an increasing hop count to each URL so that none repeat"`
+			RedirectParams
+		}) (*RedirectResponse, error) {
+			query := url.Values{}
+			if input.Status != "302" {
+				query.Set("status", input.Status)
+			}
+			if input.Cycle > 1 {
+				query.Set("cycle", strconv.Itoa(input.Cycle))
+			}
+			if input.Vary {
+				query.Set("vary", "true")
+			}
+			if input.Cycle > 1 || input.Vary {
+				hop := input.Hop + 1
+				if !input.Vary {
+					hop %= input.Cycle
+				}
+				query.Set("hop", strconv.Itoa(hop))
+			}
+
+			location := "/redirect/loop"
+			if len(query) > 0 {
+				location += "?" + query.Encode()
+			}
+			return &RedirectResponse{Status: input.status(), Location: location}, nil
+		})
+	}
+}
+
+// CrossCheckResult reports which credentials arrived after following a
+// cross-origin redirect.
+type CrossCheckResult struct {
+	Passed        bool     `json:"passed" doc:"Whether all the credentials listed in strip were removed"`
+	Origin        string   `json:"origin" doc:"Origin the request arrived at"`
+	Authorization bool     `json:"authorization" doc:"Whether an Authorization header was sent"`
+	Cookie        bool     `json:"cookie" doc:"Whether a Cookie header was sent"`
+	Leaked        []string `json:"leaked" doc:"Credentials which should have been stripped but were sent"`
+}
+
+func (s *APIServer) RegisterRedirectCross(api huma.API) {
+	for _, method := range redirectMethods {
+		huma.Register(api, huma.Operation{
+			OperationID: strings.ToLower(method) + "-redirect-cross",
+			Method:      method,
+			Path:        "/redirect/cross",
+			Description: "Redirect to any `http` or `https` URL, e.g. another host or a downgrade from `https` to `http`. Point it at `/redirect/cross/check` on a different origin for the same server, like `127.0.0.1` instead of `localhost`, to check that a client drops credentials when following the redirect.",
+			Tags:        []string{"Redirects"},
+		}, func(ctx context.Context, input *struct {
+			To string `query:"to" required:"true" format:"uri" doc:"Absolute URL to redirect to"`
+			RedirectParams
+		}) (*RedirectResponse, error) {
+			to, err := url.Parse(input.To)
+			if err != nil || (to.Scheme != "http" && to.Scheme != "https") || to.Host == "" {
+				return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+					Message:  "expected an absolute http or https URL",
+					Location: "query.to",
+					Value:    input.To,
+				})
+			}
+			return &RedirectResponse{Status: input.status(), Location: to.String()}, nil
+		})
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-redirect-cross-check",
+		Method:      http.MethodGet,
+		Path:        "/redirect/cross/check",
+		Description: "Report whether `Authorization` and `Cookie` headers were sent. Use it as the target of a cross-origin redirect from `/redirect/cross`, after which clients should have stripped them.",
+		Tags:        []string{"Redirects"},
+	}, func(ctx context.Context, input *struct {
+		Strip         []string `query:"strip" enum:"auth,cookie" default:"auth,cookie" doc:"Credentials which should have been stripped"`
+		Authorization string   `header:"Authorization"`
+		Cookie        string   `header:"Cookie"`
+	}) (*struct{ Body CrossCheckResult }, error) {
+		client := GetClientInfo(ctx)
+		result := CrossCheckResult{
+			Origin:        client.Scheme + "://" + client.Host,
+			Authorization: input.Authorization != "",
+			Cookie:        input.Cookie != "",
+			Leaked:        []string{},
+		}
+		for _, strip := range input.Strip {
+			if (strip == "auth" && result.Authorization) || (strip == "cookie" && result.Cookie) {
+				result.Leaked = append(result.Leaked, strip)
+			}
+		}
+		result.Passed = len(result.Leaked) == 0
+		return &struct{ Body CrossCheckResult }{Body: result}, nil
+	})
+}
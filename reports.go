@@ -0,0 +1,232 @@
+package apibin
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// xlsxContentType is the media type of Excel workbooks.
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// reportHeader lists the report's columns, which are meant for people rather
+// than for re-importing like the export's.
+var reportHeader = []string{"ID", "Title", "Author", "Published", "Ratings", "Average Rating", "Recent Ratings"}
+
+// reportCell is a value in a report, which keeps its type so spreadsheets
+// get numbers & dates rather than text.
+type reportCell struct {
+	text   string
+	number float64
+	date   time.Time
+	kind   string // one of text, integer, decimal, or date
+}
+
+// reportRows returns the header & a row for each book.
+func reportRows(ids []string, snapshot []Book) [][]reportCell {
+	rows := [][]reportCell{}
+	header := []reportCell{}
+	for _, name := range reportHeader {
+		header = append(header, reportCell{text: name, kind: "text"})
+	}
+	rows = append(rows, header)
+
+	for i, b := range snapshot {
+		rows = append(rows, []reportCell{
+			{text: ids[i], kind: "text"},
+			{text: b.Title, kind: "text"},
+			{text: b.Author, kind: "text"},
+			{date: b.Published, kind: "date"},
+			{number: float64(b.Ratings), kind: "integer"},
+			{number: b.RatingAverage, kind: "decimal"},
+			{number: float64(len(b.RecentRatings)), kind: "integer"},
+		})
+	}
+	return rows
+}
+
+// csvFormulaPrefixes start text which spreadsheets would run as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// String formats the cell for CSV. Text which a spreadsheet would treat as a
+// formula is prefixed with a quote so it's shown as-is, see
+// https://owasp.org/www-community/attacks/CSV_Injection.
+func (c reportCell) String() string {
+	switch c.kind {
+	case "date":
+		if c.date.IsZero() {
+			return ""
+		}
+		return c.date.Format(time.DateOnly)
+	case "integer":
+		return strconv.FormatFloat(c.number, 'f', 0, 64)
+	case "decimal":
+		return strconv.FormatFloat(c.number, 'f', 2, 64)
+	}
+	if c.text != "" && strings.ContainsRune(csvFormulaPrefixes, rune(c.text[0])) {
+		return "'" + c.text
+	}
+	return c.text
+}
+
+// reportCSV writes the rows as CSV.
+func reportCSV(rows [][]reportCell) []byte {
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, c := range row {
+			record[i] = c.String()
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// xlsxParts are the static parts of a minimal workbook with a single sheet.
+// The styles are plain, bold for the header, a date, and two decimals.
+var xlsxParts = map[string]string{
+	"[Content_Types].xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`,
+	"_rels/.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`,
+	"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Books" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+	"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`,
+	"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs></styleSheet>`,
+}
+
+// xlsxEpoch is day zero for spreadsheet dates, accounting for the leap day
+// Lotus 1-2-3 wrongly included in 1900.
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxColumn returns the letters naming a zero-based column, e.g. A or AB.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// reportXLSX writes the rows as an Excel workbook, with the header in bold.
+func reportXLSX(rows [][]reportCell) ([]byte, error) {
+	sheet := bytes.Buffer{}
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, r+1)
+		for i, c := range row {
+			ref := xlsxColumn(i) + strconv.Itoa(r+1)
+			switch {
+			case c.kind == "date" && !c.date.IsZero():
+				days := c.date.UTC().Sub(xlsxEpoch).Hours() / 24
+				fmt.Fprintf(&sheet, `<c r="%s" s="2"><v>%s</v></c>`, ref, strconv.FormatFloat(days, 'f', -1, 64))
+			case c.kind == "integer":
+				fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(c.number, 'f', -1, 64))
+			case c.kind == "decimal":
+				fmt.Fprintf(&sheet, `<c r="%s" s="3"><v>%s</v></c>`, ref, strconv.FormatFloat(c.number, 'f', -1, 64))
+			case c.kind == "text" && c.text != "":
+				style := ""
+				if r == 0 {
+					style = ` s="1"`
+				}
+				fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, ref, style)
+				xml.EscapeText(&sheet, []byte(c.text))
+				sheet.WriteString(`</t></is></c>`)
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	buf := bytes.Buffer{}
+	z := zip.NewWriter(&buf)
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		f, err := z.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		f.Write([]byte(xlsxParts[name]))
+	}
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	f.Write(sheet.Bytes())
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type ReportResponse struct {
+	ContentType        string `header:"Content-Type"`
+	ContentDisposition string `header:"Content-Disposition"`
+	CacheControl       string `header:"Cache-Control"`
+	Body               []byte
+}
+
+func (s *APIServer) RegisterReports(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-books-report-csv",
+		Method:      http.MethodGet,
+		Path:        "/reports/books.csv",
+		Description: "Download a report of all books as CSV with a header row, dates as `YYYY-MM-DD`, and the number of recent ratings. Unlike the export this is meant to be opened in business tools rather than re-imported, so text which would run as a formula is prefixed with a `'`.",
+		Tags:        []string{"Books"},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "OK",
+				Content: map[string]*huma.MediaType{
+					"text/csv": {Schema: &huma.Schema{Type: huma.TypeString}},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *struct{}) (*ReportResponse, error) {
+		return &ReportResponse{
+			ContentType:        "text/csv; charset=utf-8",
+			ContentDisposition: `attachment; filename="books.csv"`,
+			CacheControl:       "no-cache",
+			Body:               reportCSV(reportRows(snapshotBooks())),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-books-report-xlsx",
+		Method:      http.MethodGet,
+		Path:        "/reports/books.xlsx",
+		Description: "Download the same report of all books as an Excel workbook, with a bold header row and typed number & date cells.",
+		Tags:        []string{"Books"},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "OK",
+				Content: map[string]*huma.MediaType{
+					xlsxContentType: {Schema: &huma.Schema{Type: huma.TypeString, Format: "binary"}},
+				},
+			},
+		},
+	}, func(ctx context.Context, input *struct{}) (*ReportResponse, error) {
+		body, err := reportXLSX(reportRows(snapshotBooks()))
+		if err != nil {
+			return nil, huma.Error500InternalServerError("unable to create workbook", err)
+		}
+		return &ReportResponse{
+			ContentType:        xlsxContentType,
+			ContentDisposition: `attachment; filename="books.xlsx"`,
+			CacheControl:       "no-cache",
+			Body:               body,
+		}, nil
+	})
+}
@@ -0,0 +1,24 @@
+package apibin
+
+import "testing"
+
+func TestReportCellString(t *testing.T) {
+	for _, tc := range []struct {
+		cell reportCell
+		want string
+	}{
+		{reportCell{text: "Dune", kind: "text"}, "Dune"},
+		{reportCell{text: "=HYPERLINK(\"http://example.com\")", kind: "text"}, "'=HYPERLINK(\"http://example.com\")"},
+		{reportCell{text: "+1", kind: "text"}, "'+1"},
+		{reportCell{text: "-1", kind: "text"}, "'-1"},
+		{reportCell{text: "@SUM(A1)", kind: "text"}, "'@SUM(A1)"},
+		{reportCell{text: "\tx", kind: "text"}, "'\tx"},
+		{reportCell{text: "\rx", kind: "text"}, "'\rx"},
+		{reportCell{text: "", kind: "text"}, ""},
+		{reportCell{number: -1.5, kind: "decimal"}, "-1.50"},
+	} {
+		if got := tc.cell.String(); got != tc.want {
+			t.Errorf("expected %q, got %q", tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package apibin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// roomAnnounceInterval is how often the server announces itself to each
+	// room with clients.
+	roomAnnounceInterval = 15 * time.Second
+
+	// maxRoomClients limits the clients connected to a single room.
+	maxRoomClients = 100
+
+	// maxRoomMessage limits the size of a message sent to a room in bytes.
+	maxRoomMessage = 4096
+
+	// roomClientBuffer is how many messages can be queued for a client before
+	// it's considered too slow and disconnected, so one slow client can't
+	// hold up the rest of the room.
+	roomClientBuffer = 32
+
+	// roomWriteTimeout limits how long sending a single message can take.
+	roomWriteTimeout = 10 * time.Second
+)
+
+// RoomMessage is sent to clients in a room.
+type RoomMessage struct {
+	Type    string    `json:"type" enum:"message,join,leave,announcement"`
+	Room    string    `json:"room"`
+	From    string    `json:"from,omitempty"`
+	Text    string    `json:"text,omitempty"`
+	Clients int       `json:"clients" doc:"Number of clients in the room"`
+	Time    time.Time `json:"time"`
+}
+
+// roomClient is a connected client with a queue of messages to send.
+type roomClient struct {
+	name string
+	send chan []byte
+}
+
+// room fans out messages to its connected clients.
+type room struct {
+	name    string
+	clients map[*roomClient]bool
+	stop    chan struct{}
+}
+
+// rooms holds the rooms with at least one client. Messages posted to other
+// rooms are dropped.
+var rooms = struct {
+	sync.Mutex
+	rooms map[string]*room
+}{rooms: map[string]*room{}}
+
+// roomGuests numbers clients which don't give a name.
+var roomGuests atomic.Int64
+
+// broadcast queues a message for every client in the room, returning how many
+// received it. Clients whose queue is full are disconnected. The rooms lock
+// must be held.
+func (rm *room) broadcast(msg RoomMessage) int {
+	msg.Room = rm.name
+	msg.Clients = len(rm.clients)
+	msg.Time = time.Now().UTC()
+	data, _ := json.Marshal(msg)
+
+	delivered := 0
+	for c := range rm.clients {
+		select {
+		case c.send <- data:
+			delivered++
+		default:
+			delete(rm.clients, c)
+			close(c.send)
+		}
+	}
+	return delivered
+}
+
+// announce periodically sends an announcement to the room until it's empty.
+func (rm *room) announce() {
+	ticker := time.NewTicker(roomAnnounceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rm.stop:
+			return
+		case <-ticker.C:
+			rooms.Lock()
+			rm.broadcast(RoomMessage{Type: "announcement", Text: fmt.Sprintf("%d connected to %s", len(rm.clients), rm.name)})
+			rooms.Unlock()
+		}
+	}
+}
+
+// joinRoom adds a client to a room, creating it if needed.
+func joinRoom(name string, c *roomClient) error {
+	rooms.Lock()
+	defer rooms.Unlock()
+
+	rm := rooms.rooms[name]
+	if rm == nil {
+		rm = &room{name: name, clients: map[*roomClient]bool{}, stop: make(chan struct{})}
+		rooms.rooms[name] = rm
+		go rm.announce()
+	}
+	if len(rm.clients) >= maxRoomClients {
+		return fmt.Errorf("room %s is full", name)
+	}
+	rm.clients[c] = true
+	rm.broadcast(RoomMessage{Type: "join", From: c.name})
+	return nil
+}
+
+// leaveRoom removes a client from a room, removing the room once empty.
+func leaveRoom(name string, c *roomClient) {
+	rooms.Lock()
+	defer rooms.Unlock()
+
+	rm := rooms.rooms[name]
+	if rm == nil {
+		return
+	}
+	// Clients which fell behind were already removed by the room.
+	left := rm.clients[c]
+	if left {
+		delete(rm.clients, c)
+		close(c.send)
+	}
+	if len(rm.clients) == 0 {
+		close(rm.stop)
+		delete(rooms.rooms, name)
+		return
+	}
+	if left {
+		rm.broadcast(RoomMessage{Type: "leave", From: c.name})
+	}
+}
+
+// postToRoom sends a message to everyone in a room, returning how many
+// clients received it.
+func postToRoom(name string, msg RoomMessage) int {
+	rooms.Lock()
+	defer rooms.Unlock()
+	if rm := rooms.rooms[name]; rm != nil {
+		return rm.broadcast(msg)
+	}
+	return 0
+}
+
+// roomText returns the text of a message from a client, which is either
+// plain text or a JSON object with a `text` field.
+func roomText(data string) string {
+	var msg struct {
+		Text string `json:"text"`
+	}
+	if strings.HasPrefix(strings.TrimSpace(data), "{") && json.Unmarshal([]byte(data), &msg) == nil {
+		return msg.Text
+	}
+	return data
+}
+
+// canHijack returns whether the response writer, or any writer it wraps,
+// can take over the connection. HTTP/2 connections can't be.
+func canHijack(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(http.Hijacker); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// roomHijacker hijacks the connection via a response controller, so it works
+// through middleware which only wraps the writer with `Unwrap`. The WebSocket
+// server asserts `http.Hijacker` directly, so needs this.
+type roomHijacker struct {
+	http.ResponseWriter
+}
+
+func (w roomHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// serveRoom relays messages between a WebSocket client and its room until
+// either side closes the connection.
+func serveRoom(ws *websocket.Conn, name string, c *roomClient) {
+	defer ws.Close()
+	ws.MaxPayloadBytes = maxRoomMessage
+
+	// The connection was hijacked with the server's deadlines for the
+	// handshake request still set, so they are cleared.
+	ws.SetDeadline(time.Time{})
+
+	if err := joinRoom(name, c); err != nil {
+		data, _ := json.Marshal(huma.ErrorModel{Status: http.StatusServiceUnavailable, Title: http.StatusText(http.StatusServiceUnavailable), Detail: err.Error()})
+		websocket.Message.Send(ws, string(data))
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for data := range c.send {
+			ws.SetWriteDeadline(time.Now().Add(roomWriteTimeout))
+			if err := websocket.Message.Send(ws, string(data)); err != nil {
+				ws.Close()
+				for range c.send {
+					// Drain until the room closes the queue.
+				}
+				return
+			}
+		}
+		// The room dropped the client, so close the connection to stop reads.
+		ws.Close()
+	}()
+
+	for {
+		var data string
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			break
+		}
+		if text := roomText(data); text != "" {
+			postToRoom(name, RoomMessage{Type: "message", From: c.name, Text: text})
+		}
+	}
+	leaveRoom(name, c)
+	<-done
+}
+
+type RoomPostResponse struct {
+	Body struct {
+		Delivered int `json:"delivered" doc:"Number of connected clients the message was sent to"`
+	}
+}
+
+func (s *APIServer) RegisterRooms(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "websocket-room",
+		Method:      http.MethodGet,
+		Path:        "/ws/rooms/{room}",
+		Description: fmt.Sprintf("Join a chat room over a WebSocket. Text sent by any client, either as plain text or a JSON object with a `text` field, is sent to everyone in the room as a JSON `message`, including the sender. Clients also get `join` & `leave` messages and an `announcement` from the server every %s. Up to %d clients can join a room, and clients which fall too far behind are disconnected. Requests which aren't a WebSocket upgrade over HTTP/1.1 get a `426 Upgrade Required`.", roomAnnounceInterval, maxRoomClients),
+		Tags:        []string{"WebSocket"},
+		Metadata:    map[string]any{streamingKey: true},
+		Responses: map[string]*huma.Response{
+			"101": {Description: "Switching Protocols to a WebSocket sending JSON messages"},
+			"426": {Description: "Upgrade Required"},
+		},
+	}, func(ctx context.Context, input *struct {
+		RequestInfo
+		Room    string `path:"room" pattern:"^[A-Za-z0-9_-]+$" maxLength:"64"`
+		Name    string `query:"name" maxLength:"32" doc:"Name shown to others in the room. Defaults to a generated guest name."`
+		Upgrade string `header:"Upgrade"`
+	}) (*huma.StreamResponse, error) {
+		w, ok := input.ctx.BodyWriter().(http.ResponseWriter)
+		if !ok || !canHijack(w) || !strings.EqualFold(input.Upgrade, "websocket") {
+			input.ctx.SetHeader("Upgrade", "websocket")
+			input.ctx.SetHeader("Connection", "Upgrade")
+			return nil, huma.NewError(http.StatusUpgradeRequired, "a WebSocket upgrade over HTTP/1.1 is required")
+		}
+
+		name := input.Name
+		if name == "" {
+			name = fmt.Sprintf("guest-%d", roomGuests.Add(1))
+		}
+
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				u := ctx.URL()
+				r := (&http.Request{
+					Method:     ctx.Method(),
+					URL:        &u,
+					Proto:      "HTTP/1.1",
+					ProtoMajor: 1,
+					ProtoMinor: 1,
+					Host:       ctx.Host(),
+					Header:     http.Header{},
+				}).WithContext(ctx.Context())
+				ctx.EachHeader(func(name, value string) {
+					r.Header.Add(name, value)
+				})
+
+				c := &roomClient{name: name, send: make(chan []byte, roomClientBuffer)}
+				websocket.Server{Handler: func(ws *websocket.Conn) {
+					serveRoom(ws, input.Room, c)
+				}}.ServeHTTP(roomHijacker{w}, r)
+			},
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-room-message",
+		Method:      http.MethodPost,
+		Path:        "/ws/rooms/{room}/messages",
+		Description: "Send a message to everyone connected to a room via its WebSocket, which is dropped if nobody is connected.",
+		Tags:        []string{"WebSocket"},
+	}, func(ctx context.Context, input *struct {
+		Room string `path:"room" pattern:"^[A-Za-z0-9_-]+$" maxLength:"64"`
+		Body struct {
+			From string `json:"from,omitempty" maxLength:"32" default:"api" doc:"Name shown as the sender"`
+			Text string `json:"text" minLength:"1" maxLength:"4096"`
+		}
+	}) (*RoomPostResponse, error) {
+		resp := &RoomPostResponse{}
+		resp.Body.Delivered = postToRoom(input.Room, RoomMessage{Type: "message", From: input.Body.From, Text: input.Body.Text})
+		return resp, nil
+	})
+}
@@ -0,0 +1,95 @@
+package apibin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// unwrapOnlyWriter wraps a response writer without implementing any optional
+// interfaces, like some middleware does.
+type unwrapOnlyWriter struct {
+	w http.ResponseWriter
+}
+
+func (w unwrapOnlyWriter) Header() http.Header            { return w.w.Header() }
+func (w unwrapOnlyWriter) Write(data []byte) (int, error) { return w.w.Write(data) }
+func (w unwrapOnlyWriter) WriteHeader(status int)         { w.w.WriteHeader(status) }
+func (w unwrapOnlyWriter) Unwrap() http.ResponseWriter    { return w.w }
+
+// receiveRoomMessage reads messages until one of the given type arrives.
+func receiveRoomMessage(t *testing.T, ws *websocket.Conn, kind string) RoomMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg RoomMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			t.Fatalf("expected a %s message, got %v", kind, err)
+		}
+		if msg.Type == kind {
+			return msg
+		}
+	}
+}
+
+func TestRooms(t *testing.T) {
+	handler, _ := New(nil)
+	t.Cleanup(func() { handler.Close() })
+
+	for _, tc := range []struct {
+		name    string
+		handler http.Handler
+	}{
+		{"direct", handler},
+		{"unwrap only", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(unwrapOnlyWriter{w}, r)
+		})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/rooms/test-" + strings.ReplaceAll(tc.name, " ", "-")
+			first, err := websocket.Dial(url+"?name=first", "", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer first.Close()
+			receiveRoomMessage(t, first, "join")
+
+			second, err := websocket.Dial(url+"?name=second", "", server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer second.Close()
+			if msg := receiveRoomMessage(t, first, "join"); msg.From != "second" {
+				t.Fatalf("expected second to join, got %s", msg.From)
+			}
+
+			websocket.Message.Send(second, `{"text": "hello"}`)
+			msg := receiveRoomMessage(t, first, "message")
+			if msg.From != "second" || msg.Text != "hello" || msg.Clients != 2 {
+				data, _ := json.Marshal(msg)
+				t.Errorf("expected the message from second, got %s", data)
+			}
+		})
+	}
+}
+
+func TestRoomsUpgradeRequired(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	resp, err := http.Get(server.URL + "/ws/rooms/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Upgrade") != "websocket" {
+		t.Errorf("expected a 426 asking for a WebSocket upgrade, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,342 @@
+package apibin
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// echoRPCPath is the path of the `Echo` method of the `apibin.v1.EchoService`
+// service defined in `echo.proto`.
+const echoRPCPath = "/apibin.v1.EchoService/Echo"
+
+// rpcError is an RPC error with a gRPC status code, which is mapped to the
+// appropriate Connect error code or gRPC-Web trailer.
+type rpcError struct {
+	code    int
+	message string
+}
+
+func (e *rpcError) Error() string {
+	return e.message
+}
+
+// Subset of gRPC status codes used by the echo service.
+const (
+	rpcInvalidArgument   = 3
+	rpcResourceExhausted = 8
+	rpcUnimplemented     = 12
+)
+
+// connectCodes maps gRPC status codes to Connect error codes & HTTP statuses.
+var connectCodes = map[int]struct {
+	name   string
+	status int
+}{
+	rpcInvalidArgument:   {"invalid_argument", http.StatusBadRequest},
+	rpcResourceExhausted: {"resource_exhausted", http.StatusTooManyRequests},
+	rpcUnimplemented:     {"unimplemented", http.StatusNotImplemented},
+}
+
+// EchoRPCRequest is the `apibin.v1.EchoRequest` message.
+type EchoRPCRequest struct {
+	Message string `json:"message"`
+}
+
+// EchoRPCResponse is the `apibin.v1.EchoResponse` message.
+type EchoRPCResponse struct {
+	Message  string            `json:"message,omitempty"`
+	Method   string            `json:"method,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	IP       string            `json:"ip,omitempty"`
+	Protocol string            `json:"protocol,omitempty"`
+}
+
+// appendProtoString appends a length-delimited protobuf field. Empty values
+// are skipped as per proto3 semantics.
+func appendProtoString(b []byte, field uint64, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = binary.AppendUvarint(b, field<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// MarshalProto encodes the response using the protobuf wire format.
+func (r *EchoRPCResponse) MarshalProto() []byte {
+	b := appendProtoString(nil, 1, r.Message)
+	b = appendProtoString(b, 2, r.Method)
+	b = appendProtoString(b, 3, r.URL)
+
+	// Maps are encoded as repeated key/value entry messages. Sort the keys so
+	// the output is deterministic.
+	keys := make([]string, 0, len(r.Headers))
+	for k := range r.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		entry := appendProtoString(nil, 1, k)
+		entry = appendProtoString(entry, 2, r.Headers[k])
+		b = appendProtoString(b, 4, string(entry))
+	}
+
+	b = appendProtoString(b, 5, r.IP)
+	return appendProtoString(b, 6, r.Protocol)
+}
+
+// UnmarshalProto decodes the request from the protobuf wire format. Unknown
+// fields are skipped.
+func (r *EchoRPCRequest) UnmarshalProto(data []byte) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("invalid field tag")
+		}
+		data = data[n:]
+
+		var value []byte
+		switch tag & 7 {
+		case 0:
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return errors.New("invalid varint")
+			}
+		case 1:
+			n = 8
+		case 2:
+			length, ln := binary.Uvarint(data)
+			if ln <= 0 || uint64(len(data)-ln) < length {
+				return errors.New("invalid length")
+			}
+			value = data[ln : ln+int(length)]
+			n = ln + int(length)
+		case 5:
+			n = 4
+		default:
+			return fmt.Errorf("unsupported wire type %d", tag&7)
+		}
+		if len(data) < n {
+			return errors.New("unexpected end of message")
+		}
+		data = data[n:]
+
+		if tag>>3 == 1 && tag&7 == 2 {
+			r.Message = string(value)
+		}
+	}
+	return nil
+}
+
+// echoRPC runs the echo method itself, reusing the same request description
+// as the HTTP echo endpoints.
+func echoRPC(ctx huma.Context, req *EchoRPCRequest) *EchoRPCResponse {
+	model := newEchoModel(ctx)
+	resp := &EchoRPCResponse{
+		Message: req.Message,
+		Method:  model.Method,
+		URL:     model.URL,
+		Headers: model.Headers,
+		IP:      model.IP,
+	}
+	if model.Connection != nil {
+		resp.Protocol = model.Connection.Protocol
+	}
+	return resp
+}
+
+// readRPCBody reads the request body up to the configured size limit.
+func (s *APIServer) readRPCBody(ctx huma.Context) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(ctx.BodyReader(), s.opts.MaxBodySize+1))
+	if err != nil {
+		return nil, &rpcError{rpcInvalidArgument, "unable to read request body"}
+	}
+	if int64(len(body)) > s.opts.MaxBodySize {
+		return nil, &rpcError{rpcResourceExhausted, fmt.Sprintf("request body is too large limit=%d bytes", s.opts.MaxBodySize)}
+	}
+	return body, nil
+}
+
+// decodeRPC decodes a request message using either the `proto` or `json` codec.
+func decodeRPC(codec string, data []byte) (*EchoRPCRequest, error) {
+	req := &EchoRPCRequest{}
+	var err error
+	if codec == "json" {
+		err = json.Unmarshal(data, req)
+	} else {
+		err = req.UnmarshalProto(data)
+	}
+	if err != nil {
+		return nil, &rpcError{rpcInvalidArgument, "unable to decode request: " + err.Error()}
+	}
+	return req, nil
+}
+
+// encodeRPC encodes a response message using either the `proto` or `json`
+// codec.
+func encodeRPC(codec string, resp *EchoRPCResponse) []byte {
+	if codec == "json" {
+		b, _ := json.Marshal(resp)
+		return b
+	}
+	return resp.MarshalProto()
+}
+
+// serveConnect implements the Connect protocol for unary calls. See
+// https://connectrpc.com/docs/protocol/
+func (s *APIServer) serveConnect(ctx huma.Context) {
+	ct := ctx.Header("Content-Type")
+	var codec string
+	switch ct {
+	case "application/json":
+		codec = "json"
+	case "application/proto":
+		codec = "proto"
+	default:
+		ctx.SetHeader("Accept-Post", "application/json, application/proto")
+		ctx.SetStatus(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var resp *EchoRPCResponse
+	body, err := s.readRPCBody(ctx)
+	if err == nil {
+		var req *EchoRPCRequest
+		if req, err = decodeRPC(codec, body); err == nil {
+			resp = echoRPC(ctx, req)
+		}
+	}
+
+	if err != nil {
+		e := err.(*rpcError)
+		ctx.SetHeader("Content-Type", "application/json")
+		ctx.SetStatus(connectCodes[e.code].status)
+		b, _ := json.Marshal(map[string]string{
+			"code":    connectCodes[e.code].name,
+			"message": e.message,
+		})
+		ctx.BodyWriter().Write(b)
+		return
+	}
+
+	ctx.SetHeader("Content-Type", ct)
+	ctx.SetStatus(http.StatusOK)
+	ctx.BodyWriter().Write(encodeRPC(codec, resp))
+}
+
+// grpcWebFrame creates a length-prefixed gRPC message frame.
+func grpcWebFrame(flags byte, data []byte) []byte {
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = flags
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	return append(frame, data...)
+}
+
+// serveGRPCWeb implements the gRPC-Web protocol for unary calls, including
+// the base64 `grpc-web-text` variant used by some browser clients. See
+// https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+func (s *APIServer) serveGRPCWeb(ctx huma.Context) {
+	ct := ctx.Header("Content-Type")
+	mediaType, subtype, _ := strings.Cut(ct, "+")
+	text := mediaType == "application/grpc-web-text"
+	codec := "proto"
+	if subtype == "json" {
+		codec = "json"
+	}
+
+	if (mediaType != "application/grpc-web" && !text) || (subtype != "" && subtype != "proto" && subtype != "json") {
+		ctx.SetStatus(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var resp *EchoRPCResponse
+	body, err := s.readRPCBody(ctx)
+	if err == nil && text {
+		if body, err = base64.StdEncoding.DecodeString(string(body)); err != nil {
+			err = &rpcError{rpcInvalidArgument, "invalid base64 request body"}
+		}
+	}
+	if err == nil {
+		switch {
+		case len(body) < 5 || uint32(len(body)-5) < binary.BigEndian.Uint32(body[1:5]):
+			err = &rpcError{rpcInvalidArgument, "invalid message frame"}
+		case body[0]&1 != 0:
+			err = &rpcError{rpcUnimplemented, "compressed messages are not supported"}
+		default:
+			var req *EchoRPCRequest
+			if req, err = decodeRPC(codec, body[5:5+binary.BigEndian.Uint32(body[1:5])]); err == nil {
+				resp = echoRPC(ctx, req)
+			}
+		}
+	}
+
+	status := 0
+	message := ""
+	if err != nil {
+		status = err.(*rpcError).code
+		message = err.Error()
+	}
+
+	// Status is sent in a trailer frame, since browsers cannot read real HTTP
+	// trailers.
+	var out []byte
+	if resp != nil {
+		out = grpcWebFrame(0, encodeRPC(codec, resp))
+	}
+	trailers := fmt.Sprintf("grpc-status: %d\r\ngrpc-message: %s\r\n", status, url.PathEscape(message))
+	out = append(out, grpcWebFrame(0x80, []byte(trailers))...)
+
+	if text {
+		out = []byte(base64.StdEncoding.EncodeToString(out))
+	}
+
+	ctx.SetHeader("Content-Type", ct)
+	ctx.SetStatus(http.StatusOK)
+	ctx.BodyWriter().Write(out)
+}
+
+func (s *APIServer) RegisterEchoRPC(api huma.API) {
+	// These use the adapter directly since they are not regular HTTP/REST
+	// operations and should not be documented in the OpenAPI.
+	adapter := api.Adapter()
+
+	adapter.Handle(&huma.Operation{
+		OperationID: "echo-rpc",
+		Method:      http.MethodPost,
+		Path:        echoRPCPath,
+		Tags:        []string{"Echo"},
+	}, func(ctx huma.Context) {
+		// Allow browser clients on other origins.
+		ctx.SetHeader("Access-Control-Allow-Origin", "*")
+		ctx.SetHeader("Access-Control-Expose-Headers", "Grpc-Status, Grpc-Message")
+
+		if strings.HasPrefix(ctx.Header("Content-Type"), "application/grpc-web") {
+			s.serveGRPCWeb(ctx)
+			return
+		}
+		s.serveConnect(ctx)
+	})
+
+	adapter.Handle(&huma.Operation{
+		OperationID: "echo-rpc-preflight",
+		Method:      http.MethodOptions,
+		Path:        echoRPCPath,
+		Tags:        []string{"Echo"},
+	}, func(ctx huma.Context) {
+		ctx.SetHeader("Access-Control-Allow-Origin", "*")
+		ctx.SetHeader("Access-Control-Allow-Methods", "POST")
+		ctx.SetHeader("Access-Control-Allow-Headers", "Content-Type, Connect-Protocol-Version, Connect-Timeout-Ms, Grpc-Timeout, X-Grpc-Web, X-User-Agent")
+		ctx.SetHeader("Access-Control-Max-Age", "7200")
+		ctx.SetStatus(http.StatusNoContent)
+	})
+}
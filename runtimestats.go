@@ -0,0 +1,138 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/go-chi/chi/v5"
+)
+
+// routeCounters counts requests by method and route pattern.
+type routeCounters struct {
+	mu      sync.Mutex
+	started time.Time
+	total   int64
+	counts  map[string]int64
+}
+
+func newRouteCounters() *routeCounters {
+	return &routeCounters{started: time.Now(), counts: map[string]int64{}}
+}
+
+// Middleware counts each request once it has been routed. Requests which
+// don't match a route are counted under `unmatched`.
+func (c *routeCounters) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = r.Method + " " + rctx.RoutePattern()
+		}
+		c.mu.Lock()
+		c.total++
+		c.counts[route]++
+		c.mu.Unlock()
+	})
+}
+
+// RouteCount is the number of requests to a route.
+type RouteCount struct {
+	Route    string `json:"route" doc:"Method and route pattern, or unmatched"`
+	Requests int64  `json:"requests"`
+}
+
+// GCStats summarizes garbage collection since the server started.
+type GCStats struct {
+	Runs       uint32     `json:"runs"`
+	PauseTotal string     `json:"pause_total" doc:"Total time spent in stop-the-world pauses"`
+	LastRun    *time.Time `json:"last_run,omitempty"`
+}
+
+// MemoryStats describes the memory used by the server in bytes.
+type MemoryStats struct {
+	HeapAlloc   uint64 `json:"heap_alloc" doc:"Bytes of allocated heap objects"`
+	HeapObjects uint64 `json:"heap_objects" doc:"Number of allocated heap objects"`
+	Sys         uint64 `json:"sys" doc:"Total bytes obtained from the OS"`
+}
+
+// RuntimeStats describes the running server.
+type RuntimeStats struct {
+	Started    time.Time    `json:"started"`
+	Uptime     string       `json:"uptime"`
+	GoVersion  string       `json:"go_version"`
+	Goroutines int          `json:"goroutines"`
+	Memory     MemoryStats  `json:"memory"`
+	GC         GCStats      `json:"gc"`
+	Books      int          `json:"books" doc:"Number of books currently stored"`
+	Requests   int64        `json:"requests" doc:"Total requests served, excluding the current one"`
+	Routes     []RouteCount `json:"routes" doc:"Requests per route, most requested first"`
+}
+
+type RuntimeStatsResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Body         RuntimeStats
+}
+
+func (s *APIServer) RegisterRuntimeStats(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-stats",
+		Method:      http.MethodGet,
+		Path:        "/stats",
+		Description: "Get runtime statistics about the server, like goroutines, memory & garbage collection, the number of stored books, and request counts for each route since it started. Useful to observe the server during demos without a metrics stack.",
+		Tags:        []string{"Stats"},
+	}, func(ctx context.Context, input *struct{}) (*RuntimeStatsResponse, error) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		stats := RuntimeStats{
+			GoVersion:  runtime.Version(),
+			Goroutines: runtime.NumGoroutine(),
+			Memory: MemoryStats{
+				HeapAlloc:   mem.HeapAlloc,
+				HeapObjects: mem.HeapObjects,
+				Sys:         mem.Sys,
+			},
+			GC: GCStats{
+				Runs:       mem.NumGC,
+				PauseTotal: time.Duration(mem.PauseTotalNs).String(),
+			},
+			Routes: []RouteCount{},
+		}
+		if mem.LastGC > 0 {
+			last := time.Unix(0, int64(mem.LastGC)).UTC()
+			stats.GC.LastRun = &last
+		}
+
+		booksMu.RLock()
+		stats.Books = len(books)
+		booksMu.RUnlock()
+
+		if c := s.routes; c != nil {
+			c.mu.Lock()
+			stats.Started = c.started.UTC()
+			stats.Uptime = time.Since(c.started).Round(time.Second).String()
+			stats.Requests = c.total
+			for route, n := range c.counts {
+				stats.Routes = append(stats.Routes, RouteCount{Route: route, Requests: n})
+			}
+			c.mu.Unlock()
+		}
+		sort.Slice(stats.Routes, func(i, j int) bool {
+			if stats.Routes[i].Requests != stats.Routes[j].Requests {
+				return stats.Routes[i].Requests > stats.Routes[j].Requests
+			}
+			return stats.Routes[i].Route < stats.Routes[j].Route
+		})
+
+		return &RuntimeStatsResponse{
+			CacheControl: "no-store",
+			Body:         stats,
+		}, nil
+	})
+}
@@ -0,0 +1,101 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// securityPresets lists the security headers sent by each preset. The
+// report-only preset only includes headers which don't block anything.
+var securityPresets = map[string]SecurityHeaders{
+	"strict": {
+		StrictTransportSecurity: "max-age=63072000; includeSubDomains; preload",
+		ContentTypeOptions:      "nosniff",
+		FrameOptions:            "DENY",
+		ReferrerPolicy:          "no-referrer",
+		PermissionsPolicy:       "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		ContentSecurityPolicy:   "default-src 'none'; frame-ancestors 'none'",
+	},
+	"report-only": {
+		ContentTypeOptions:              "nosniff",
+		ReferrerPolicy:                  "strict-origin-when-cross-origin",
+		ContentSecurityPolicyReportOnly: "default-src 'none'; frame-ancestors 'none'",
+	},
+	"none": {},
+}
+
+// SecurityHeaders are the security-related response headers, which are also
+// echoed in the response body.
+type SecurityHeaders struct {
+	StrictTransportSecurity         string `json:"strict_transport_security,omitempty"`
+	ContentTypeOptions              string `json:"x_content_type_options,omitempty"`
+	FrameOptions                    string `json:"x_frame_options,omitempty"`
+	ReferrerPolicy                  string `json:"referrer_policy,omitempty"`
+	PermissionsPolicy               string `json:"permissions_policy,omitempty"`
+	ContentSecurityPolicy           string `json:"content_security_policy,omitempty"`
+	ContentSecurityPolicyReportOnly string `json:"content_security_policy_report_only,omitempty"`
+}
+
+type SecurityHeadersResponse struct {
+	StrictTransportSecurity         string `header:"Strict-Transport-Security"`
+	ContentTypeOptions              string `header:"X-Content-Type-Options"`
+	FrameOptions                    string `header:"X-Frame-Options"`
+	ReferrerPolicy                  string `header:"Referrer-Policy"`
+	PermissionsPolicy               string `header:"Permissions-Policy"`
+	ContentSecurityPolicy           string `header:"Content-Security-Policy"`
+	ContentSecurityPolicyReportOnly string `header:"Content-Security-Policy-Report-Only"`
+	Body                            SecurityHeaders
+}
+
+// overrideHeader replaces a preset header value. The special value `off` removes
+// the header.
+func overrideHeader(value *string, with string) {
+	switch with {
+	case "":
+	case "off":
+		*value = ""
+	default:
+		*value = with
+	}
+}
+
+func (s *APIServer) RegisterSecurityHeaders(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-security-headers",
+		Method:      http.MethodGet,
+		Path:        "/security-headers",
+		Description: "Return security headers from a preset, with any individual header overridden or removed via query params. The headers sent are echoed in the body.",
+		Tags:        []string{"Security"},
+	}, func(ctx context.Context, input *struct {
+		Preset        string `query:"preset" enum:"strict,report-only,none" default:"strict" doc:"Starting set of headers"`
+		HSTS          string `query:"hsts" doc:"Strict-Transport-Security value, or off to remove it"`
+		ContentType   string `query:"content_type_options" doc:"X-Content-Type-Options value, or off to remove it"`
+		Frame         string `query:"frame_options" doc:"X-Frame-Options value, or off to remove it"`
+		Referrer      string `query:"referrer_policy" doc:"Referrer-Policy value, or off to remove it"`
+		Permissions   string `query:"permissions_policy" doc:"Permissions-Policy value, or off to remove it"`
+		CSP           string `query:"csp" doc:"Content-Security-Policy value, or off to remove it"`
+		CSPReportOnly string `query:"csp_report_only" doc:"Content-Security-Policy-Report-Only value, or off to remove it"`
+	}) (*SecurityHeadersResponse, error) {
+		h := securityPresets[input.Preset]
+		overrideHeader(&h.StrictTransportSecurity, input.HSTS)
+		overrideHeader(&h.ContentTypeOptions, input.ContentType)
+		overrideHeader(&h.FrameOptions, input.Frame)
+		overrideHeader(&h.ReferrerPolicy, input.Referrer)
+		overrideHeader(&h.PermissionsPolicy, input.Permissions)
+		overrideHeader(&h.ContentSecurityPolicy, input.CSP)
+		overrideHeader(&h.ContentSecurityPolicyReportOnly, input.CSPReportOnly)
+
+		return &SecurityHeadersResponse{
+			StrictTransportSecurity:         h.StrictTransportSecurity,
+			ContentTypeOptions:              h.ContentTypeOptions,
+			FrameOptions:                    h.FrameOptions,
+			ReferrerPolicy:                  h.ReferrerPolicy,
+			PermissionsPolicy:               h.PermissionsPolicy,
+			ContentSecurityPolicy:           h.ContentSecurityPolicy,
+			ContentSecurityPolicyReportOnly: h.ContentSecurityPolicyReportOnly,
+			Body:                            h,
+		}, nil
+	})
+}
@@ -0,0 +1,208 @@
+package apibin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	sessionCookie = "apibin_session"
+
+	// maxSessions limits the number of active sessions. The oldest are removed
+	// first when the limit is reached.
+	maxSessions = 1000
+
+	// Demo credentials accepted by the login operation.
+	sessionUsername = "demo"
+	sessionPassword = "demo"
+)
+
+// sessionKey signs session cookies. Sessions are only kept in memory, so a new
+// key is generated on each start.
+var sessionKey = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// Session is an active login session.
+type Session struct {
+	Username string    `json:"username"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires" doc:"When the session expires on the server"`
+}
+
+// sessionsMu protects the active sessions.
+var sessionsMu = sync.Mutex{}
+var sessions = map[string]*Session{}
+var sessionsOrder = []string{}
+
+// signSession returns the cookie value for a session ID.
+func signSession(id string) string {
+	mac := hmac.New(sha256.New, sessionKey)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// findSession returns the active session for a cookie value, or nil if the
+// signature is invalid or the session has expired or been logged out.
+func findSession(value string) (string, *Session) {
+	id, _, _ := strings.Cut(value, ".")
+	if id == "" || !hmac.Equal([]byte(signSession(id)), []byte(value)) {
+		return "", nil
+	}
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	session := sessions[id]
+	if session == nil || (!session.Expires.IsZero() && time.Now().After(session.Expires)) {
+		return "", nil
+	}
+	return id, session
+}
+
+// removeSession ends a session. The caller must hold the lock.
+func removeSession(id string) {
+	delete(sessions, id)
+	for i, k := range sessionsOrder {
+		if k == id {
+			sessionsOrder = append(sessionsOrder[:i], sessionsOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// CookieParams configures the attributes of the session cookie.
+type CookieParams struct {
+	Path     string `query:"path" default:"/session" doc:"Cookie path"`
+	SameSite string `query:"samesite" enum:"lax,strict,none" default:"lax" doc:"Cookie SameSite attribute. Browsers require Secure for none."`
+	Secure   string `query:"secure" enum:"auto,true,false" default:"auto" doc:"Cookie Secure attribute. Auto sets it when the request used HTTPS."`
+	HTTPOnly bool   `query:"http_only" default:"true" doc:"Cookie HttpOnly attribute, which hides it from scripts"`
+	MaxAge   int    `query:"max_age" minimum:"0" maximum:"86400" doc:"Cookie and session lifetime in seconds. Zero creates a browser session cookie which expires after an hour on the server."`
+}
+
+// cookie creates a session cookie with the configured attributes.
+func (p CookieParams) cookie(ctx context.Context, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     p.Path,
+		HttpOnly: p.HTTPOnly,
+		MaxAge:   p.MaxAge,
+		Secure:   p.Secure == "true" || (p.Secure == "auto" && GetClientInfo(ctx).Scheme == "https"),
+		SameSite: sameSiteMode(p.SameSite),
+	}
+}
+
+// SessionLogin holds the login credentials.
+type SessionLogin struct {
+	Username string `json:"username" example:"demo" doc:"Use demo"`
+	Password string `json:"password" example:"demo" doc:"Use demo"`
+}
+
+type SessionResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	SetCookie    string `header:"Set-Cookie"`
+	Body         *Session
+}
+
+func (s *APIServer) RegisterSession(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "post-session-login",
+		Method:      http.MethodPost,
+		Path:        "/session/login",
+		Description: "Log in with the demo credentials `demo` / `demo` to start a session. The session ID is set in a signed cookie whose attributes can be configured via query params.",
+		Tags:        []string{"Session"},
+		Errors:      []int{http.StatusUnauthorized},
+	}, func(ctx context.Context, input *struct {
+		CookieParams
+		Body SessionLogin
+	}) (*SessionResponse, error) {
+		user := subtle.ConstantTimeCompare([]byte(input.Body.Username), []byte(sessionUsername))
+		pass := subtle.ConstantTimeCompare([]byte(input.Body.Password), []byte(sessionPassword))
+		if user&pass != 1 {
+			return nil, huma.Error401Unauthorized("invalid username or password")
+		}
+
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, huma.Error500InternalServerError("unable to read random bytes", err)
+		}
+		id := base64.RawURLEncoding.EncodeToString(b)
+
+		lifetime := time.Hour
+		if input.MaxAge > 0 {
+			lifetime = time.Duration(input.MaxAge) * time.Second
+		}
+		session := &Session{
+			Username: input.Body.Username,
+			Created:  time.Now(),
+			Expires:  time.Now().Add(lifetime),
+		}
+
+		sessionsMu.Lock()
+		sessions[id] = session
+		sessionsOrder = append(sessionsOrder, id)
+		for len(sessionsOrder) > maxSessions {
+			removeSession(sessionsOrder[0])
+		}
+		sessionsMu.Unlock()
+
+		return &SessionResponse{
+			CacheControl: "no-store",
+			SetCookie:    input.cookie(ctx, signSession(id)).String(),
+			Body:         session,
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-session-me",
+		Method:      http.MethodGet,
+		Path:        "/session/me",
+		Description: "Get the current session from the session cookie.",
+		Tags:        []string{"Session"},
+		Errors:      []int{http.StatusUnauthorized},
+	}, func(ctx context.Context, input *struct {
+		Cookie string `header:"Cookie"`
+	}) (*SessionResponse, error) {
+		_, session := findSession(readCookie(input.Cookie, sessionCookie))
+		if session == nil {
+			return nil, huma.Error401Unauthorized("not logged in")
+		}
+		return &SessionResponse{CacheControl: "no-store", Body: session}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-session-logout",
+		Method:      http.MethodPost,
+		Path:        "/session/logout",
+		Description: "End the current session and clear the session cookie. Use the same cookie path as when logging in.",
+		Tags:        []string{"Session"},
+	}, func(ctx context.Context, input *struct {
+		CookieParams
+		Cookie string `header:"Cookie"`
+	}) (*SessionResponse, error) {
+		if id, _ := findSession(readCookie(input.Cookie, sessionCookie)); id != "" {
+			sessionsMu.Lock()
+			removeSession(id)
+			sessionsMu.Unlock()
+		}
+
+		c := input.cookie(ctx, "")
+		c.MaxAge = -1
+		return &SessionResponse{CacheControl: "no-store", SetCookie: c.String()}, nil
+	})
+}
@@ -0,0 +1,195 @@
+package apibin
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// soapEnvelope is a SOAP 1.1 envelope. Only the body is used.
+type soapEnvelope struct {
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	Body    struct {
+		GetBook *soapGetBook `xml:"https://api.rest.sh/soap GetBook"`
+	} `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+}
+
+type soapGetBook struct {
+	ID string `xml:"id"`
+}
+
+type soapBook struct {
+	Title         string  `xml:"title"`
+	Author        string  `xml:"author,omitempty"`
+	Published     string  `xml:"published,omitempty"`
+	Ratings       int     `xml:"ratings"`
+	RatingAverage float64 `xml:"ratingAverage"`
+}
+
+type soapGetBookResponse struct {
+	XMLName xml.Name `xml:"https://api.rest.sh/soap GetBookResponse"`
+	Book    soapBook `xml:"book"`
+}
+
+type soapFault struct {
+	XMLName xml.Name `xml:"soap:Fault"`
+	Code    string   `xml:"faultcode"`
+	String  string   `xml:"faultstring"`
+}
+
+// writeSOAP writes a SOAP 1.1 envelope containing the given body. As per the
+// spec, faults must use a 500 status code.
+func writeSOAP(ctx huma.Context, status int, body any) {
+	content, _ := xml.Marshal(body)
+
+	ctx.SetHeader("Content-Type", "text/xml; charset=utf-8")
+	ctx.SetStatus(status)
+	w := ctx.BodyWriter()
+	io.WriteString(w, xml.Header)
+	io.WriteString(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>`)
+	w.Write(content)
+	io.WriteString(w, `</soap:Body></soap:Envelope>`)
+}
+
+func writeSOAPFault(ctx huma.Context, code, message string) {
+	writeSOAP(ctx, http.StatusInternalServerError, soapFault{
+		Code:   "soap:" + code,
+		String: message,
+	})
+}
+
+// soapWSDL describes the SOAP service. The `{{url}}` placeholder is replaced
+// with the service address.
+const soapWSDL = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="BookService"
+  targetNamespace="https://api.rest.sh/soap"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="https://api.rest.sh/soap"
+  xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xsd:schema targetNamespace="https://api.rest.sh/soap" elementFormDefault="qualified">
+      <xsd:element name="GetBook">
+        <xsd:complexType>
+          <xsd:sequence>
+            <xsd:element name="id" type="xsd:string"/>
+          </xsd:sequence>
+        </xsd:complexType>
+      </xsd:element>
+      <xsd:element name="GetBookResponse">
+        <xsd:complexType>
+          <xsd:sequence>
+            <xsd:element name="book" type="tns:Book"/>
+          </xsd:sequence>
+        </xsd:complexType>
+      </xsd:element>
+      <xsd:complexType name="Book">
+        <xsd:sequence>
+          <xsd:element name="title" type="xsd:string"/>
+          <xsd:element name="author" type="xsd:string" minOccurs="0"/>
+          <xsd:element name="published" type="xsd:date" minOccurs="0"/>
+          <xsd:element name="ratings" type="xsd:int"/>
+          <xsd:element name="ratingAverage" type="xsd:double"/>
+        </xsd:sequence>
+      </xsd:complexType>
+    </xsd:schema>
+  </types>
+  <message name="GetBookInput">
+    <part name="parameters" element="tns:GetBook"/>
+  </message>
+  <message name="GetBookOutput">
+    <part name="parameters" element="tns:GetBookResponse"/>
+  </message>
+  <portType name="BookPortType">
+    <operation name="GetBook">
+      <input message="tns:GetBookInput"/>
+      <output message="tns:GetBookOutput"/>
+    </operation>
+  </portType>
+  <binding name="BookBinding" type="tns:BookPortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetBook">
+      <soap:operation soapAction="https://api.rest.sh/soap/GetBook"/>
+      <input><soap:body use="literal"/></input>
+      <output><soap:body use="literal"/></output>
+    </operation>
+  </binding>
+  <service name="BookService">
+    <port name="BookPort" binding="tns:BookBinding">
+      <soap:address location="{{url}}"/>
+    </port>
+  </service>
+</definitions>
+`
+
+func (s *APIServer) RegisterSOAP(api huma.API) {
+	// SOAP is not a REST API, so these bypass Huma's request handling and are
+	// not documented in the OpenAPI.
+	adapter := api.Adapter()
+
+	adapter.Handle(&huma.Operation{
+		OperationID: "get-soap-wsdl",
+		Method:      http.MethodGet,
+		Path:        "/soap",
+		Tags:        []string{"SOAP"},
+	}, func(ctx huma.Context) {
+		u := ctx.URL()
+		if _, ok := u.Query()["wsdl"]; !ok {
+			// Without `?wsdl` this is a call to an operation with the wrong
+			// method, so respond like the router does for other resources.
+			ctx.SetHeader("Allow", "POST")
+			huma.WriteErr(api, ctx, http.StatusMethodNotAllowed, "use POST to call operations or ?wsdl for the service description")
+			return
+		}
+
+		client := GetClientInfo(ctx.Context())
+		ctx.SetHeader("Content-Type", "text/xml; charset=utf-8")
+		ctx.SetStatus(http.StatusOK)
+		io.WriteString(ctx.BodyWriter(), strings.Replace(soapWSDL, "{{url}}", client.Scheme+"://"+client.Host+"/soap", 1))
+	})
+
+	adapter.Handle(&huma.Operation{
+		OperationID: "post-soap",
+		Method:      http.MethodPost,
+		Path:        "/soap",
+		Tags:        []string{"SOAP"},
+	}, func(ctx huma.Context) {
+		var env soapEnvelope
+		if err := xml.NewDecoder(io.LimitReader(ctx.BodyReader(), s.opts.MaxBodySize)).Decode(&env); err != nil {
+			writeSOAPFault(ctx, "Client", "Invalid SOAP envelope: "+err.Error())
+			return
+		}
+
+		if env.Body.GetBook == nil {
+			writeSOAPFault(ctx, "Client", "Unknown operation, only GetBook is supported")
+			return
+		}
+
+		booksMu.RLock()
+		b := books[env.Body.GetBook.ID]
+		var book soapBook
+		if b != nil {
+			book = soapBook{
+				Title:         b.Title,
+				Author:        b.Author,
+				Ratings:       b.Ratings,
+				RatingAverage: b.RatingAverage,
+			}
+			if !b.Published.IsZero() {
+				book.Published = b.Published.Format(time.DateOnly)
+			}
+		}
+		booksMu.RUnlock()
+
+		if b == nil {
+			writeSOAPFault(ctx, "Client", "Book "+env.Body.GetBook.ID+" not found")
+			return
+		}
+
+		writeSOAP(ctx, http.StatusOK, soapGetBookResponse{Book: book})
+	})
+}
@@ -0,0 +1,111 @@
+package apibin
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+//go:embed static images
+var staticFiles embed.FS
+
+// staticModified is used as the `Last-Modified` time for all static files,
+// since embedded files have no modification time of their own.
+var staticModified = time.Now().UTC().Truncate(time.Second)
+
+// staticETags maps each static file path to a strong ETag of its contents.
+var staticETags = map[string]string{}
+
+// staticVariants maps each compressible static file path to its contents
+// precompressed at startup, so they needn't be compressed on each request.
+var staticVariants = map[string]map[string][]byte{}
+
+func init() {
+	err := fs.WalkDir(staticFiles, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := staticFiles.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		staticETags[p] = `"` + genETagBytes(data) + `"`
+		if !strings.HasPrefix(p, "images/") {
+			if staticVariants[p], err = precompress(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// staticPath maps a `/static/...` URL path to a file in the embedded
+// filesystem. Sample images are exposed as `/static/images/...` and all other
+// files come from the `static` directory.
+func staticPath(urlPath string) string {
+	p := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(urlPath, "/static")), "/")
+	if p == "" {
+		p = "index.html"
+	}
+	if !strings.HasPrefix(p, "images/") {
+		p = "static/" + p
+	}
+	return p
+}
+
+// StaticFiles serves embedded static assets. Responses use strong ETags unless
+// `?etag=weak` is passed and `http.ServeContent`, which handles conditional
+// and `Range` requests. Compressible files are sent precompressed when the
+// client accepts it.
+func StaticFiles(notFound http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := staticPath(r.URL.Path)
+		etag, ok := staticETags[name]
+		if !ok {
+			notFound(w, r)
+			return
+		}
+
+		data, err := staticFiles.ReadFile(name)
+		if err != nil {
+			notFound(w, r)
+			return
+		}
+
+		if variants := staticVariants[name]; variants != nil {
+			data, etag = selectPrecompressed(w, r, variants, etag)
+		}
+
+		if r.URL.Query().Get("etag") == etagWeak {
+			// Weak validators make `http.ServeContent` ignore `If-Range` and
+			// send the full content, as required by RFC 9110.
+			etag = "W/" + etag
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		http.ServeContent(w, r, name, staticModified, bytes.NewReader(data))
+	}
+}
+
+// newServeContentRequest creates a request with the method and headers from
+// an operation's context which are used by `http.ServeContent` for `Range`
+// and conditional requests.
+func newServeContentRequest(ctx huma.Context) *http.Request {
+	r := &http.Request{Method: ctx.Method(), Header: http.Header{}}
+	for _, name := range []string{"Range", "If-Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"} {
+		if v := ctx.Header(name); v != "" {
+			r.Header.Set(name, v)
+		}
+	}
+	return r
+}
@@ -0,0 +1,109 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// RatingBucket counts books whose average rating falls in `[min, max)`. The
+// last bucket also includes its maximum.
+type RatingBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// BookStats aggregates information about all the stored books.
+type BookStats struct {
+	Books           int            `json:"books"`
+	Authors         int            `json:"authors"`
+	Ratings         int            `json:"ratings" doc:"Total number of ratings across all books"`
+	RatingAverage   float64        `json:"rating_average" doc:"Average rating weighted by number of ratings"`
+	RatingHistogram []RatingBucket `json:"rating_histogram" doc:"Distribution of average book ratings"`
+	OldestPublished *time.Time     `json:"oldest_published,omitempty"`
+	NewestPublished *time.Time     `json:"newest_published,omitempty"`
+	RecentlyUpdated []BookSummary  `json:"recently_updated" doc:"Most recently modified books, newest first"`
+}
+
+// maxRecentlyUpdated limits the number of books in the stats recent list.
+const maxRecentlyUpdated = 5
+
+func (s *APIServer) RegisterBookStats(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-book-stats",
+		Method:      http.MethodGet,
+		Path:        "/books/stats",
+		Description: "Get aggregate statistics about the books, computed on demand.",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		FilterParams
+	}) (*struct{ Body BookStats }, error) {
+		if err := input.CheckFilter(); err != nil {
+			return nil, err
+		}
+
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		order, _, _ := listAuthors()
+		stats := BookStats{
+			Books:           len(books),
+			Authors:         len(order),
+			RatingHistogram: make([]RatingBucket, 5),
+			RecentlyUpdated: []BookSummary{},
+		}
+		for i := range stats.RatingHistogram {
+			stats.RatingHistogram[i].Min = float64(i)
+			stats.RatingHistogram[i].Max = float64(i + 1)
+		}
+
+		weighted := 0.0
+		for _, k := range booksOrder {
+			b := books[k]
+
+			stats.Ratings += b.Ratings
+			weighted += b.RatingAverage * float64(b.Ratings)
+
+			bucket := int(b.RatingAverage)
+			if bucket >= len(stats.RatingHistogram) {
+				bucket = len(stats.RatingHistogram) - 1
+			}
+			if bucket < 0 {
+				bucket = 0
+			}
+			stats.RatingHistogram[bucket].Count++
+
+			if !b.Published.IsZero() {
+				published := b.Published
+				if stats.OldestPublished == nil || published.Before(*stats.OldestPublished) {
+					stats.OldestPublished = &published
+				}
+				if stats.NewestPublished == nil || published.After(*stats.NewestPublished) {
+					stats.NewestPublished = &published
+				}
+			}
+
+			stats.RecentlyUpdated = append(stats.RecentlyUpdated, BookSummary{
+				URL:      "/books/" + k,
+				Version:  b.Version(),
+				Modified: b.modified,
+			})
+		}
+		if stats.Ratings > 0 {
+			stats.RatingAverage = weighted / float64(stats.Ratings)
+		}
+
+		sort.SliceStable(stats.RecentlyUpdated, func(i, j int) bool {
+			return stats.RecentlyUpdated[i].Modified.After(stats.RecentlyUpdated[j].Modified)
+		})
+		if len(stats.RecentlyUpdated) > maxRecentlyUpdated {
+			stats.RecentlyUpdated = stats.RecentlyUpdated[:maxRecentlyUpdated]
+		}
+
+		return &struct{ Body BookStats }{Body: stats}, nil
+	})
+}
@@ -0,0 +1,162 @@
+package apibin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// maxTemplateSize limits the size of a rendered template in bytes, so a
+// template can't be used to generate huge responses.
+const maxTemplateSize = 1 << 20
+
+// TemplateParams render a response body from a template using request info.
+type TemplateParams struct {
+	Template    string `query:"template" maxLength:"8192" doc:"Template for the response body, e.g. {\"id\": {{rand 100}}, \"method\": \"{{method}}\"}. See the operation description for placeholders."`
+	ContentType string `query:"content_type" default:"text/plain" enum:"text/plain,text/csv,application/json,application/xml" doc:"Content type of the rendered body. Active types like HTML aren't allowed since the body is controlled by the client."`
+}
+
+// templateContentType returns the Content-Type header for a rendered body.
+func (p TemplateParams) templateContentType() string {
+	if strings.HasPrefix(p.ContentType, "text/") {
+		return p.ContentType + "; charset=utf-8"
+	}
+	return p.ContentType
+}
+
+// setTemplateSecurityHeaders stops browsers from sniffing or running a
+// rendered body, which could otherwise be used for XSS on the API's origin.
+// XML can still contain XHTML, so it's also sandboxed.
+func setTemplateSecurityHeaders(ctx huma.Context) {
+	ctx.SetHeader("X-Content-Type-Options", "nosniff")
+	ctx.SetHeader("Content-Security-Policy", "sandbox")
+}
+
+// templateDoc describes the placeholders available in body templates.
+const templateDoc = "Templates use Go [text/template](https://pkg.go.dev/text/template) syntax with the placeholders `{{method}}`, `{{path}}`, `{{url}}`, `{{query \"name\"}}`, `{{header \"X-Foo\"}}`, `{{body}}`, `{{now}}` as RFC 3339, `{{unix}}` seconds, `{{rand 100}}` for a number from 0 up to but not including 100, and `{{uuid}}`."
+
+// templateUUID returns a random version 4 UUID.
+func templateUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// limitedBuffer errors on writes past its limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("rendered body is larger than %d bytes", b.limit)
+	}
+	return b.Buffer.Write(p)
+}
+
+// renderTemplate renders a body template for the request, with the request
+// body available via `{{body}}`. Parse & execution errors are returned as
+// validation errors on the given location.
+func renderTemplate(ctx huma.Context, tmpl, location string, body []byte) ([]byte, error) {
+	u := ctx.URL()
+	client := GetClientInfo(ctx.Context())
+	t, err := template.New("body").Option("missingkey=zero").Funcs(template.FuncMap{
+		"method": ctx.Method,
+		"path":   func() string { return u.Path },
+		"url":    func() string { return client.Scheme + "://" + client.Host + u.RequestURI() },
+		"query":  func(name string) string { return u.Query().Get(name) },
+		"header": ctx.Header,
+		"body":   func() string { return string(body) },
+		"now":    func() string { return time.Now().UTC().Format(time.RFC3339) },
+		"unix":   func() int64 { return time.Now().Unix() },
+		"rand": func(n int) (int, error) {
+			if n <= 0 {
+				return 0, fmt.Errorf("rand needs a positive number, not %d", n)
+			}
+			return rand.Intn(n), nil
+		},
+		"uuid": templateUUID,
+	}).Parse(tmpl)
+	if err == nil {
+		buf := &limitedBuffer{limit: maxTemplateSize}
+		if err = t.Execute(buf, nil); err == nil {
+			return buf.Bytes(), nil
+		}
+	}
+	return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+		Location: location,
+		Message:  strings.TrimPrefix(err.Error(), "template: "),
+		Value:    tmpl,
+	})
+}
+
+type TemplateResponse struct {
+	Status       int
+	ContentType  string `header:"Content-Type"`
+	CacheControl string `header:"Cache-Control"`
+	Body         []byte
+}
+
+func (s *APIServer) RegisterTemplateEcho(api huma.API) {
+	for _, method := range []string{
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	} {
+		huma.Register(api, huma.Operation{
+			OperationID: strings.ToLower(method) + "-template-echo",
+			Method:      method,
+			Path:        "/template-echo",
+			Description: "Render a response body from a template, to mock arbitrary responses without writing code. The template is taken from the `template` query param, or otherwise the request body. " + templateDoc,
+			Tags:        []string{"Echo"},
+			RequestBody: &huma.RequestBody{
+				Description: "Template for the response body when not given via the query, or otherwise available as {{body}}",
+				Content: map[string]*huma.MediaType{
+					"text/plain": {Schema: &huma.Schema{Type: huma.TypeString}},
+				},
+			},
+			Responses: map[string]*huma.Response{
+				"200": {
+					Description: "OK",
+					Content: map[string]*huma.MediaType{
+						"text/plain": {Schema: &huma.Schema{Type: huma.TypeString}},
+					},
+				},
+			},
+		}, func(ctx context.Context, input *struct {
+			RequestInfo
+			TemplateParams
+			Status  int `query:"status" default:"200" minimum:"200" maximum:"599" doc:"Status code to return"`
+			RawBody []byte
+		}) (*TemplateResponse, error) {
+			tmpl, location, body := input.Template, "query.template", input.RawBody
+			if tmpl == "" {
+				// The request body is the template, so it isn't also its body.
+				tmpl, location, body = string(input.RawBody), "body", nil
+			}
+			rendered, err := renderTemplate(input.ctx, tmpl, location, body)
+			if err != nil {
+				return nil, err
+			}
+			setTemplateSecurityHeaders(input.ctx)
+			return &TemplateResponse{
+				Status:       input.Status,
+				ContentType:  input.templateContentType(),
+				CacheControl: "no-store",
+				Body:         rendered,
+			}, nil
+		})
+	}
+}
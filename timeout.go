@@ -0,0 +1,100 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// maxTimeoutAfter limits how long `/timeout` waits before timing out.
+const maxTimeoutAfter = 5 * time.Minute
+
+// timeoutTitles names the timeout statuses which aren't in the standard
+// library, all of which are used by CDNs & proxies.
+var timeoutTitles = map[int]string{
+	522: "Connection Timed Out",
+	524: "A Timeout Occurred",
+	598: "Network Read Timeout Error",
+	599: "Network Connect Timeout Error",
+}
+
+// writeTimeoutError writes a problem body for a timeout status, like
+// `huma.WriteErr` but with a title for non-standard statuses.
+func writeTimeoutError(api huma.API, ctx huma.Context, status int, detail string) {
+	title := http.StatusText(status)
+	if t, ok := timeoutTitles[status]; ok {
+		title = t
+	}
+	var err any = &huma.ErrorModel{Status: status, Title: title, Detail: detail}
+
+	ct, negotiateErr := api.Negotiate(ctx.Header("Accept"))
+	if negotiateErr != nil {
+		ct = "application/json"
+	}
+	if ctf, ok := err.(huma.ContentTypeFilter); ok {
+		ct = ctf.ContentType(ct)
+	}
+	ctx.SetHeader("Content-Type", ct)
+	ctx.SetHeader("Cache-Control", "no-store")
+	ctx.SetStatus(status)
+	if tval, terr := api.Transform(ctx, strconv.Itoa(status), err); terr == nil {
+		api.Marshal(ctx.BodyWriter(), ct, tval)
+	}
+}
+
+func (s *APIServer) RegisterTimeout(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-timeout",
+		Method:      http.MethodGet,
+		Path:        "/timeout",
+		Description: "Simulate a gateway which times out waiting for its upstream. By default nothing is sent until the wait is over, when the timeout status is returned with a problem body, like a header timeout. With `stage=body` a `200 OK` & its headers are sent right away and the connection is dropped once the wait is over without sending a body, like a body timeout. Non-standard statuses like Cloudflare's `524` get their usual titles.",
+		Tags:        []string{"Timeout"},
+		Metadata:    map[string]any{streamingKey: true},
+		Responses: map[string]*huma.Response{
+			"504": {Description: "Gateway Timeout"},
+		},
+	}, func(ctx context.Context, input *struct {
+		After  string `query:"after" default:"5s" doc:"How long to wait before timing out, as a duration like 500ms or 5s, up to 5m"`
+		Status int    `query:"status" default:"504" minimum:"400" maximum:"599" doc:"Status to time out with when stage is headers, e.g. 504, 408, or 524"`
+		Stage  string `query:"stage" default:"headers" enum:"headers,body" doc:"Time out before sending the headers, or after sending them but before the body"`
+	}) (*huma.StreamResponse, error) {
+		after, err := time.ParseDuration(input.After)
+		if err != nil || after < 0 || after > maxTimeoutAfter {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Location: "query.after",
+				Message:  "expected a duration from 0s to " + maxTimeoutAfter.String(),
+				Value:    input.After,
+			})
+		}
+
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				if input.Stage == "body" {
+					ctx.SetHeader("Content-Type", "application/json")
+					ctx.SetHeader("Cache-Control", "no-store")
+					ctx.SetStatus(http.StatusOK)
+					if f, ok := ctx.BodyWriter().(http.Flusher); ok {
+						f.Flush()
+					}
+				}
+
+				select {
+				case <-ctx.Context().Done():
+					return
+				case <-time.After(after):
+				}
+
+				if input.Stage == "body" {
+					// Abort the response so the client sees the connection close
+					// or stream reset rather than an empty but complete body.
+					panic(http.ErrAbortHandler)
+				}
+				writeTimeoutError(api, ctx, input.Status, fmt.Sprintf("The upstream server did not respond within %s", after))
+			},
+		}, nil
+	})
+}
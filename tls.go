@@ -0,0 +1,44 @@
+package apibin
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// RedirectHTTPS sends a permanent redirect to the same resource on the given
+// HTTPS port for any request made over plaintext HTTP.
+func RedirectHTTPS(port int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if port != 443 {
+				host = net.JoinHostPort(host, strconv.Itoa(port))
+			}
+
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		})
+	}
+}
+
+// StrictTransportSecurity adds an HSTS header with the given max age to all
+// responses sent over HTTPS. Browsers ignore the header over plaintext HTTP.
+func StrictTransportSecurity(maxAge int) func(http.Handler) http.Handler {
+	value := "max-age=" + strconv.Itoa(maxAge)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil {
+				w.Header().Set("Strict-Transport-Security", value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
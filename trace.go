@@ -0,0 +1,115 @@
+package apibin
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// traceRedacted replaces the value of headers which aren't reflected.
+const traceRedacted = "[redacted]"
+
+// traceHopByHop headers only apply to a single connection, see RFC 9110
+// section 7.6.1. Any headers listed in `Connection` are also hop-by-hop.
+var traceHopByHop = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+}
+
+// traceSensitive headers may contain credentials, so are never reflected.
+var traceSensitive = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Api-Key":     true,
+	"X-Auth-Token":  true,
+	"X-Csrf-Token":  true,
+}
+
+// traceMessage formats a request line and headers as an HTTP/1.1 message
+// without a body, redacting hop-by-hop and sensitive header values.
+func traceMessage(ctx huma.Context) string {
+	hopByHop := map[string]bool{}
+	headers := [][2]string{}
+	ctx.EachHeader(func(name, value string) {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if name == "Connection" {
+			for _, token := range strings.Split(value, ",") {
+				hopByHop[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(token))] = true
+			}
+		}
+		headers = append(headers, [2]string{name, value})
+	})
+	sort.SliceStable(headers, func(i, j int) bool {
+		return headers[i][0] < headers[j][0]
+	})
+
+	proto := "HTTP/1.1"
+	if conn := GetConnection(ctx.Context()); conn != nil {
+		proto = conn.Protocol
+	}
+
+	u := ctx.URL()
+	b := strings.Builder{}
+	b.WriteString(ctx.Method() + " " + u.RequestURI() + " " + proto + "\r\n")
+	b.WriteString("Host: " + ctx.Host() + "\r\n")
+	for _, h := range headers {
+		value := h[1]
+		if traceHopByHop[h[0]] || hopByHop[h[0]] || traceSensitive[h[0]] {
+			value = traceRedacted
+		}
+		b.WriteString(h[0] + ": " + value + "\r\n")
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+type TraceEchoResponse struct {
+	ContentType  string `header:"Content-Type"`
+	CacheControl string `header:"Cache-Control"`
+	Body         []byte
+}
+
+func (s *APIServer) RegisterTraceEcho(api huma.API) {
+	for _, method := range []string{
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	} {
+		huma.Register(api, huma.Operation{
+			OperationID: strings.ToLower(method) + "-trace-echo",
+			Method:      method,
+			Path:        "/trace-echo",
+			Description: "Reflect the request line and headers as received, like `TRACE` but without enabling it. Useful to see what intermediaries add or strip. Hop-by-hop headers and those which may contain credentials, like `Authorization` & `Cookie`, are redacted. Any request body is ignored.",
+			Tags:        []string{"Echo"},
+			Responses: map[string]*huma.Response{
+				"200": {
+					Description: "OK",
+					Content: map[string]*huma.MediaType{
+						"message/http": {Schema: &huma.Schema{Type: huma.TypeString}},
+					},
+				},
+			},
+		}, func(ctx context.Context, input *struct {
+			RequestInfo
+		}) (*TraceEchoResponse, error) {
+			return &TraceEchoResponse{
+				ContentType:  "message/http",
+				CacheControl: "no-store",
+				Body:         []byte(traceMessage(input.ctx)),
+			}, nil
+		})
+	}
+}
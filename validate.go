@@ -0,0 +1,82 @@
+package apibin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// DataFile is a seed data file and the Go type it's loaded into.
+type DataFile struct {
+	Name string
+	Data []byte
+	Type reflect.Type
+}
+
+// EmbeddedDataFiles returns the embedded books & example data files, which
+// can be replaced with custom ones to validate before embedding them.
+func EmbeddedDataFiles() []DataFile {
+	return []DataFile{
+		{Name: "embedded books.json", Data: booksBytes, Type: reflect.TypeOf(map[string]Book{})},
+		{Name: "embedded example.json", Data: exampleBytes, Type: reflect.TypeOf(Resume{})},
+	}
+}
+
+// jsonPosition returns the 1-based line & column of a byte offset.
+func jsonPosition(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// Validate checks the file against the JSON Schema generated from its type
+// and that it loads, returning a description of each problem found.
+func (f DataFile) Validate() []string {
+	var value any
+	if err := json.Unmarshal(f.Data, &value); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := jsonPosition(f.Data, syntaxErr.Offset)
+			return []string{fmt.Sprintf("%s:%d:%d: %s", f.Name, line, col, err)}
+		}
+		return []string{fmt.Sprintf("%s: %s", f.Name, err)}
+	}
+
+	registry := huma.NewMapRegistry("#/components/schemas/", huma.DefaultSchemaNamer)
+	schema := registry.Schema(f.Type, false, "")
+	res := &huma.ValidateResult{}
+	huma.Validate(registry, schema, huma.NewPathBuffer([]byte{}, 0), huma.ModeWriteToServer, value, res)
+
+	problems := []string{}
+	for _, err := range res.Errors {
+		detail := err.(*huma.ErrorDetail)
+		location := detail.Location
+		if location == "" {
+			location = "(root)"
+		}
+		problem := fmt.Sprintf("%s: %s: %s", f.Name, location, detail.Message)
+		switch detail.Value.(type) {
+		case map[string]any, []any, nil:
+			// Objects & arrays are too long to be useful.
+		default:
+			problem += fmt.Sprintf(" (got %v)", detail.Value)
+		}
+		problems = append(problems, problem)
+	}
+
+	if len(problems) == 0 {
+		// The schema doesn't cover everything, e.g. numbers too big for an int.
+		if err := json.Unmarshal(f.Data, reflect.New(f.Type).Interface()); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", f.Name, err))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,290 @@
+package apibin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humachi"
+	"github.com/danielgtaylor/huma/v2/autopatch"
+	"github.com/danielgtaylor/huma/v2/conditional"
+	"github.com/go-chi/chi/v5"
+)
+
+// apiVersions lists the versioned APIs mounted under `/{version}`. Each one
+// is a separate API with its own OpenAPI document, docs, and schemas.
+var apiVersions = []string{"v1", "v2"}
+
+// versionDocs describes each versioned API for its OpenAPI document.
+var versionDocs = map[string]string{
+	"v1": "Version 1 of the books API. This is identical to the unversioned `/books` API.",
+	"v2": "Version 2 of the books API, which introduces breaking changes:\n\n- `title` is renamed to `name`\n- `published` is renamed to `published_at`\n- `ratings` is renamed to `rating_count`\n- The book list is paginated with `page` & `page_size` and returns an envelope object with the items and a link to the next page",
+}
+
+// mountVersion creates a new API for the given version on the router and
+// registers its operations. Books link to their authors if the unversioned
+// authors group is mounted.
+func mountVersion(router chi.Router, opts *Options, version string, authors bool) huma.API {
+	prefix := "/" + version
+
+	config := huma.DefaultConfig("Example API "+version, version[1:]+".0.0")
+	config.Info.Description = versionDocs[version]
+	if opts.ReadOnly {
+		config.Info.Description += readOnlyDocs
+	}
+	if opts.APIKeys != "" {
+		documentAPIKeys(&config)
+	}
+	config.Servers = []*huma.Server{
+		{URL: "https://api.rest.sh"},
+	}
+	config.OpenAPIPath = prefix + "/openapi"
+	config.DocsPath = prefix + "/docs"
+	config.SchemasPath = prefix + "/schemas"
+	config.Formats["application/yaml"] = yamlFormat
+	config.Formats["yaml"] = yamlFormat
+
+	// The default link transformer points at `/schemas`, so replace it with
+	// one using this version's schemas path.
+	links := huma.NewSchemaLinkTransformer("#/components/schemas/", config.SchemasPath)
+	config.OpenAPI.OnAddOperation = []huma.AddOpFunc{links.OnAddOperation}
+	config.Transformers = []huma.Transformer{links.Transform, SparseFields, FilterResponse}
+
+	api := humachi.New(router, config)
+	api.UseMiddleware(MaxBodySize(api, opts.MaxBodySize))
+	api.UseMiddleware(ExemptWriteTimeout)
+	api.UseMiddleware(NotModifiedWithoutBody)
+
+	server := &APIServer{opts: opts, prefix: prefix, authors: authors}
+	switch version {
+	case "v1":
+		server.RegisterListBooks(api)
+		server.RegisterGetBook(api)
+		server.RegisterPutBook(api)
+		server.RegisterDeleteBook(api)
+		server.RegisterBatchGetBooks(api)
+		server.RegisterPostRating(api)
+		server.RegisterGetRatings(api)
+	case "v2":
+		server.registerBooksV2(api)
+	}
+
+	autopatch.AutoPatch(api)
+	setMaxBodyBytes(api, opts.MaxBodySize)
+	addHeadOperations(api)
+
+	return api
+}
+
+// BookV2 is the v2 representation of a book, with renamed fields.
+type BookV2 struct {
+	Name          string    `json:"name"`
+	Author        string    `json:"author,omitempty"`
+	PublishedAt   time.Time `json:"published_at,omitempty"`
+	RatingCount   int       `json:"rating_count,omitempty"`
+	RatingAverage float64   `json:"rating_average,omitempty"`
+	RecentRatings []Rating  `json:"recent_ratings,omitempty"`
+}
+
+func newBookV2(b *Book) *BookV2 {
+	return &BookV2{
+		Name:          b.Title,
+		Author:        b.Author,
+		PublishedAt:   b.Published,
+		RatingCount:   b.Ratings,
+		RatingAverage: b.RatingAverage,
+		RecentRatings: b.RecentRatings,
+	}
+}
+
+// Book converts back to the stored book representation.
+func (b BookV2) Book() *Book {
+	return &Book{
+		Title:         b.Name,
+		Author:        b.Author,
+		Published:     b.PublishedAt,
+		Ratings:       b.RatingCount,
+		RatingAverage: b.RatingAverage,
+		RecentRatings: b.RecentRatings,
+	}
+}
+
+// BookSummaryV2 provides a link and version for the v2 books list response.
+type BookSummaryV2 struct {
+	Href       string    `json:"href"`
+	ETag       string    `json:"etag"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// BookPageV2 is a single page of books.
+type BookPageV2 struct {
+	Items    []BookSummaryV2 `json:"items"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+	Total    int             `json:"total"`
+	Next     string          `json:"next,omitempty" doc:"Link to the next page, if any"`
+}
+
+type GetBookV2Response struct {
+	CacheControl string    `header:"Cache-Control"`
+	ETag         string    `header:"Etag"`
+	LastModified time.Time `header:"Last-Modified"`
+	Link         string    `header:"Link"`
+	Vary         string    `header:"Vary"`
+
+	Body *BookV2
+}
+
+func (s *APIServer) registerBooksV2(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-books",
+		Method:      http.MethodGet,
+		Path:        s.prefix + "/books",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		Page     int    `query:"page" default:"1" minimum:"1" doc:"Page number, starting at 1"`
+		PageSize int    `query:"page_size" default:"10" minimum:"1" maximum:"100" doc:"Number of books per page"`
+		Author   string `query:"author" doc:"Only include books by this author ID"`
+	}) (*struct{ Body BookPageV2 }, error) {
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		ids := make([]string, 0, len(booksOrder))
+		for _, k := range booksOrder {
+			if input.Author == "" || authorID(books[k].Author) == input.Author {
+				ids = append(ids, k)
+			}
+		}
+
+		resp := &struct{ Body BookPageV2 }{}
+		resp.Body.Page = input.Page
+		resp.Body.PageSize = input.PageSize
+		resp.Body.Total = len(ids)
+		resp.Body.Items = []BookSummaryV2{}
+
+		// Pages past the end are empty. The page is checked before multiplying
+		// so huge page numbers can't overflow.
+		start, end := len(ids), len(ids)
+		if input.Page-1 < len(ids)/input.PageSize+1 {
+			start = (input.Page - 1) * input.PageSize
+			if start > len(ids) {
+				start = len(ids)
+			}
+			end = start + input.PageSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+		}
+		for i := start; i < end; i++ {
+			k := ids[i]
+			b := books[k]
+			resp.Body.Items = append(resp.Body.Items, BookSummaryV2{
+				Href:       s.prefix + "/books/" + k,
+				ETag:       b.Version(),
+				ModifiedAt: b.modified,
+			})
+		}
+		if end < len(ids) {
+			resp.Body.Next = fmt.Sprintf("%s/books?page=%d&page_size=%d", s.prefix, input.Page+1, input.PageSize)
+			if input.Author != "" {
+				resp.Body.Next += "&author=" + url.QueryEscape(input.Author)
+			}
+		}
+
+		return resp, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-book",
+		Method:      http.MethodGet,
+		Path:        s.prefix + "/books/{book-id}",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		conditional.Params
+		ETagParams
+		ID string `path:"book-id"`
+	}) (*GetBookV2Response, error) {
+		booksMu.RLock()
+		defer booksMu.RUnlock()
+
+		b := books[input.ID]
+		if b == nil {
+			return nil, huma.Error404NotFound(input.ID + " not found")
+		}
+
+		if err := input.PreconditionFailed(b.Version(), b.modified); err != nil {
+			return nil, err
+		}
+
+		return &GetBookV2Response{
+			CacheControl: "max-age:0",
+			ETag:         formatETag(b.Version(), input.ETag),
+			LastModified: b.modified,
+			Link:         s.bookLinks(input.ID, b, false),
+			Vary:         "Accept, Accept-Encoding, Origin",
+			Body:         newBookV2(b),
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-book",
+		Method:      http.MethodPut,
+		Path:        s.prefix + "/books/{book-id}",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		conditional.Params
+		ID   string `path:"book-id"`
+		Body BookV2
+	}) (*struct{}, error) {
+		booksMu.Lock()
+		defer booksMu.Unlock()
+
+		if input.HasConditionalParams() {
+			if err := rejectWeakIfMatch(input.IfMatch); err != nil {
+				return nil, err
+			}
+			existing := books[input.ID]
+			if existing != nil {
+				if err := input.PreconditionFailed(existing.Version(), existing.modified); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		storeBook(input.ID, input.Body.Book())
+
+		return nil, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-book",
+		Method:      http.MethodDelete,
+		Path:        s.prefix + "/books/{book-id}",
+		Tags:        []string{"Books"},
+	}, func(ctx context.Context, input *struct {
+		conditional.Params
+		ID string `path:"book-id"`
+	}) (*struct{}, error) {
+		booksMu.Lock()
+		defer booksMu.Unlock()
+
+		if input.HasConditionalParams() {
+			if err := rejectWeakIfMatch(input.IfMatch); err != nil {
+				return nil, err
+			}
+			existing := books[input.ID]
+			if existing != nil {
+				if err := input.PreconditionFailed(existing.Version(), existing.modified); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		removeBook(input.ID)
+
+		return nil, nil
+	})
+}
@@ -0,0 +1,386 @@
+package apibin
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	// maxWebhooks limits the number of registered webhooks. The oldest are
+	// removed first when the limit is reached.
+	maxWebhooks = 20
+
+	// maxDeliveries limits how many deliveries are logged per webhook.
+	maxDeliveries = 50
+
+	// webhookAttempts is the number of delivery attempts before giving up.
+	webhookAttempts = 3
+)
+
+// webhookBackoff is the delay before the first retry, doubled after each
+// failed attempt.
+var webhookBackoff = 2 * time.Second
+
+// WebhookInput is used to register a new webhook.
+type WebhookInput struct {
+	URL    string   `json:"url" format:"uri" doc:"Callback URL which will receive a POST for each event"`
+	Secret string   `json:"secret,omitempty" maxLength:"256" doc:"Secret used to sign deliveries via the X-Apibin-Signature header"`
+	Events []string `json:"events,omitempty" enum:"created,updated,deleted" doc:"Events to deliver, defaults to all"`
+}
+
+// Webhook is a registered callback for book changes.
+type Webhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Events     []string  `json:"events"`
+	Created    time.Time `json:"created"`
+	Deliveries string    `json:"deliveries" doc:"Link to the delivery log"`
+	secret     string
+}
+
+// WebhookDelivery logs a single delivery attempt.
+type WebhookDelivery struct {
+	ID       int       `json:"id" doc:"Event ID being delivered"`
+	Event    string    `json:"event"`
+	Attempt  int       `json:"attempt"`
+	Time     time.Time `json:"time"`
+	Duration string    `json:"duration"`
+	Status   int       `json:"status,omitempty" doc:"Response status code, if a response was received"`
+	Error    string    `json:"error,omitempty"`
+	Success  bool      `json:"success"`
+}
+
+// WebhookPayload is the body sent to webhook URLs.
+type WebhookPayload struct {
+	ID    int       `json:"id" doc:"Event ID, matching the SSE event stream"`
+	Event string    `json:"event"`
+	Data  BookEvent `json:"data"`
+}
+
+// webhooksMu protects the registered webhooks and their delivery logs.
+var webhooksMu = sync.Mutex{}
+var webhooks = map[string]*Webhook{}
+var webhooksOrder = []string{}
+var webhookDeliveries = map[string][]WebhookDelivery{}
+
+// bookEventName returns the SSE event name for book event data.
+func bookEventName(data any) (string, BookEvent) {
+	switch e := data.(type) {
+	case BookCreatedEvent:
+		return "created", BookEvent(e)
+	case BookUpdatedEvent:
+		return "updated", BookEvent(e)
+	case BookDeletedEvent:
+		return "deleted", BookEvent(e)
+	}
+	return "", BookEvent{}
+}
+
+// signWebhook computes the signature header value for a payload.
+func signWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var errPrivateAddress = errors.New("connections to private addresses are not allowed")
+
+// newWebhookClient creates the HTTP client used for deliveries. Unless
+// allowed, connections to loopback and private addresses are refused so a
+// public instance can't be used to probe its own network.
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// recordDelivery appends to a webhook's delivery log.
+func recordDelivery(hookID string, d WebhookDelivery) {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	if webhooks[hookID] == nil {
+		// Deleted while delivering.
+		return
+	}
+	log := append(webhookDeliveries[hookID], d)
+	if len(log) > maxDeliveries {
+		log = log[len(log)-maxDeliveries:]
+	}
+	webhookDeliveries[hookID] = log
+}
+
+// deliverWebhook sends a payload to a webhook, retrying failures with
+// exponential backoff until the context is done.
+func deliverWebhook(ctx context.Context, client *http.Client, hook Webhook, payload WebhookPayload) {
+	body, _ := json.Marshal(payload)
+	backoff := webhookBackoff
+
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		d := WebhookDelivery{
+			ID:      payload.ID,
+			Event:   payload.Event,
+			Attempt: attempt,
+			Time:    time.Now(),
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "apibin-webhooks")
+			req.Header.Set("X-Apibin-Event", payload.Event)
+			req.Header.Set("X-Apibin-Delivery", strconv.Itoa(payload.ID))
+			if hook.secret != "" {
+				req.Header.Set("X-Apibin-Signature", signWebhook(hook.secret, body))
+			}
+
+			var resp *http.Response
+			resp, err = client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				d.Status = resp.StatusCode
+				if resp.StatusCode >= 300 {
+					err = fmt.Errorf("unexpected response status %d", resp.StatusCode)
+				}
+			}
+		}
+		d.Duration = time.Since(d.Time).String()
+		d.Success = err == nil
+		if err != nil {
+			d.Error = err.Error()
+		}
+		recordDelivery(hook.ID, d)
+
+		if d.Success {
+			return
+		}
+		if attempt < webhookAttempts {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+}
+
+// dispatchWebhooks delivers book events to all matching webhooks until the
+// context is done. Events dropped because the subscription was full are
+// caught up on from the stored events, so they're only missed if more than
+// the stored number of events happen while the dispatcher is busy.
+func dispatchWebhooks(ctx context.Context, client *http.Client) {
+	_, events, unsubscribe := subscribeBookEvents(0)
+	defer unsubscribe()
+
+	lastID := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-events:
+			if lastID == 0 {
+				lastID = msg.ID - 1
+			}
+			for _, msg := range bookEventsSince(lastID) {
+				if missed := msg.ID - lastID - 1; missed > 0 {
+					fmt.Fprintf(os.Stderr, "Webhooks missed %d book events\n", missed)
+				}
+				lastID = msg.ID
+
+				name, data := bookEventName(msg.Data)
+				payload := WebhookPayload{ID: msg.ID, Event: name, Data: data}
+
+				webhooksMu.Lock()
+				for _, id := range webhooksOrder {
+					hook := webhooks[id]
+					for _, e := range hook.Events {
+						if e == name {
+							go deliverWebhook(ctx, client, *hook, payload)
+							break
+						}
+					}
+				}
+				webhooksMu.Unlock()
+			}
+		}
+	}
+}
+
+type WebhookResponse struct {
+	Location string `header:"Location"`
+	Body     *Webhook
+}
+
+func (s *APIServer) RegisterWebhooks(api huma.API) {
+	client := newWebhookClient(s.opts.WebhooksAllowPrivate)
+	go dispatchWebhooks(s.ctx, client)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "create-webhook",
+		Method:        http.MethodPost,
+		Path:          "/books/webhooks",
+		Description:   "Register a webhook to receive a signed POST for every book change. Failed deliveries are retried with exponential backoff.",
+		Tags:          []string{"Webhooks"},
+		DefaultStatus: http.StatusCreated,
+	}, func(ctx context.Context, input *struct {
+		Body WebhookInput
+	}) (*WebhookResponse, error) {
+		u, err := url.Parse(input.Body.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, huma.Error422UnprocessableEntity("validation failed", &huma.ErrorDetail{
+				Message:  "expected an absolute http or https URL",
+				Location: "body.url",
+				Value:    input.Body.URL,
+			})
+		}
+
+		idBytes := make([]byte, 8)
+		rand.Read(idBytes)
+		id := hex.EncodeToString(idBytes)
+
+		events := input.Body.Events
+		if len(events) == 0 {
+			events = []string{"created", "updated", "deleted"}
+		}
+
+		hook := &Webhook{
+			ID:         id,
+			URL:        input.Body.URL,
+			Events:     events,
+			Created:    time.Now(),
+			Deliveries: "/books/webhooks/" + id + "/deliveries",
+			secret:     input.Body.Secret,
+		}
+
+		webhooksMu.Lock()
+		webhooks[id] = hook
+		webhooksOrder = append(webhooksOrder, id)
+		for len(webhooksOrder) > maxWebhooks {
+			delete(webhooks, webhooksOrder[0])
+			delete(webhookDeliveries, webhooksOrder[0])
+			webhooksOrder = webhooksOrder[1:]
+		}
+		webhooksMu.Unlock()
+
+		return &WebhookResponse{
+			Location: "/books/webhooks/" + id,
+			Body:     hook,
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-webhooks",
+		Method:      http.MethodGet,
+		Path:        "/books/webhooks",
+		Tags:        []string{"Webhooks"},
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body []*Webhook }, error) {
+		webhooksMu.Lock()
+		defer webhooksMu.Unlock()
+
+		l := make([]*Webhook, 0, len(webhooksOrder))
+		for _, id := range webhooksOrder {
+			l = append(l, webhooks[id])
+		}
+		return &struct{ Body []*Webhook }{Body: l}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-webhook",
+		Method:      http.MethodGet,
+		Path:        "/books/webhooks/{webhook-id}",
+		Tags:        []string{"Webhooks"},
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"webhook-id"`
+	}) (*struct{ Body *Webhook }, error) {
+		webhooksMu.Lock()
+		defer webhooksMu.Unlock()
+
+		hook := webhooks[input.ID]
+		if hook == nil {
+			return nil, huma.Error404NotFound(input.ID + " not found")
+		}
+		return &struct{ Body *Webhook }{Body: hook}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-webhook",
+		Method:      http.MethodDelete,
+		Path:        "/books/webhooks/{webhook-id}",
+		Tags:        []string{"Webhooks"},
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"webhook-id"`
+	}) (*struct{}, error) {
+		webhooksMu.Lock()
+		defer webhooksMu.Unlock()
+
+		delete(webhooks, input.ID)
+		delete(webhookDeliveries, input.ID)
+		for i, id := range webhooksOrder {
+			if id == input.ID {
+				webhooksOrder = append(webhooksOrder[:i], webhooksOrder[i+1:]...)
+				break
+			}
+		}
+		return nil, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-webhook-deliveries",
+		Method:      http.MethodGet,
+		Path:        "/books/webhooks/{webhook-id}/deliveries",
+		Description: "List recent delivery attempts for a webhook, newest first.",
+		Tags:        []string{"Webhooks"},
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"webhook-id"`
+	}) (*struct{ Body []WebhookDelivery }, error) {
+		webhooksMu.Lock()
+		defer webhooksMu.Unlock()
+
+		if webhooks[input.ID] == nil {
+			return nil, huma.Error404NotFound(input.ID + " not found")
+		}
+
+		log := webhookDeliveries[input.ID]
+		l := make([]WebhookDelivery, 0, len(log))
+		for i := len(log) - 1; i >= 0; i-- {
+			l = append(l, log[i])
+		}
+		return &struct{ Body []WebhookDelivery }{Body: l}, nil
+	})
+}